@@ -0,0 +1,253 @@
+// Paging, filtering, sorting and preloading of list endpoints
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DefaultLimit and MaxLimit bound the pages of list endpoints.
+const (
+	DefaultLimit = 100
+	MaxLimit     = 1000
+)
+
+// Filter narrows a list by the value of a query parameter.
+type Filter struct {
+	// Type is the OpenAPI type of the parameter.
+	Type        string
+	Description string
+	Apply       func(db *gorm.DB, value string) (*gorm.DB, error)
+}
+
+// List is how a list endpoint is queried: ?limit= and ?offset= page it,
+// the Filters narrow it, ?sort= orders it and ?include= preloads.
+type List struct {
+	Filters map[string]Filter
+	// Sorts maps the keys ?sort= takes to columns, a key prefixed with "-"
+	// sorts descending.
+	Sorts map[string]string
+	// Sort is the order when ?sort= is not given.
+	Sort string
+	// Key is the primary key, appended to every order so pages are stable.
+	Key string
+	// Includes maps the names ?include= takes to associations to preload.
+	Includes map[string][]string
+	// Include is what is preloaded when ?include= is not given.
+	Include string
+	// Preloads are preloaded whatever ?include= asks for.
+	Preloads []string
+}
+
+// Page is the window of a list that was served.
+type Page struct {
+	Total  int64
+	Limit  int
+	Offset int
+}
+
+// Find loads into dest, a pointer to a slice, the page of the list the
+// request asks for. Bad parameters are 400s.
+func (l List) Find(db *gorm.DB, r *http.Request, dest interface{}) (Page, error) {
+	query := r.URL.Query()
+	page := Page{Limit: DefaultLimit}
+	var err error
+	if value := query.Get("limit"); value != "" {
+		if page.Limit, err = strconv.Atoi(value); err != nil || page.Limit < 1 || page.Limit > MaxLimit {
+			return page, Errorf(http.StatusBadRequest, "limit must be between 1 and %d", MaxLimit)
+		}
+	}
+	if value := query.Get("offset"); value != "" {
+		if page.Offset, err = strconv.Atoi(value); err != nil || page.Offset < 0 {
+			return page, Errorf(http.StatusBadRequest, "offset must be 0 or more")
+		}
+	}
+
+	tx := db
+	for _, name := range sortedKeys(l.Filters) {
+		value := query.Get(name)
+		if value == "" {
+			continue
+		}
+		if tx, err = l.Filters[name].Apply(tx, value); err != nil {
+			return page, Errorf(http.StatusBadRequest, "%s: %v", name, err)
+		}
+	}
+	order, err := l.order(query)
+	if err != nil {
+		return page, err
+	}
+	preloads, err := l.preloads(query)
+	if err != nil {
+		return page, err
+	}
+
+	// The session lets the filtered query be counted and found.
+	tx = tx.Session(&gorm.Session{})
+	if err = tx.Model(dest).Count(&page.Total).Error; err != nil {
+		return page, err
+	}
+	for _, preload := range preloads {
+		tx = tx.Preload(preload)
+	}
+	err = tx.Order(order).Limit(page.Limit).Offset(page.Offset).Find(dest).Error
+	return page, err
+}
+
+// order returns the ORDER BY of the request's ?sort=.
+func (l List) order(query url.Values) (string, error) {
+	value := l.Sort
+	if query.Has("sort") {
+		value = query.Get("sort")
+	}
+	var columns []string
+	for _, key := range splitList(value) {
+		direction := " ASC"
+		if strings.HasPrefix(key, "-") {
+			key, direction = key[1:], " DESC"
+		}
+		column, ok := l.Sorts[key]
+		if !ok {
+			return "", Errorf(http.StatusBadRequest, "sort: %q is not one of %s", key, strings.Join(sortedKeys(l.Sorts), ", "))
+		}
+		columns = append(columns, column+direction)
+	}
+	return strings.Join(append(columns, l.Key), ", "), nil
+}
+
+// preloads returns the associations of the request's ?include=.
+func (l List) preloads(query url.Values) ([]string, error) {
+	value := l.Include
+	if query.Has("include") {
+		value = query.Get("include")
+	}
+	preloads := append([]string(nil), l.Preloads...)
+	for _, name := range splitList(value) {
+		associations, ok := l.Includes[name]
+		if !ok {
+			return nil, Errorf(http.StatusBadRequest, "include: %q is not one of %s", name, strings.Join(sortedKeys(l.Includes), ", "))
+		}
+		preloads = append(preloads, associations...)
+	}
+	return preloads, nil
+}
+
+// Params documents the query parameters of the list.
+func (l List) Params() []Param {
+	params := []Param{
+		{Name: "limit", Type: "integer", Description: fmt.Sprintf("Page size, default %d, at most %d", DefaultLimit, MaxLimit)},
+		{Name: "offset", Type: "integer", Description: "Records skipped, default 0"},
+		{Name: "sort", Type: "string", Description: fmt.Sprintf("Comma separated keys of %s, - prefixed for descending, default %q", strings.Join(sortedKeys(l.Sorts), ", "), l.Sort)},
+	}
+	if len(l.Includes) > 0 {
+		params = append(params, Param{Name: "include", Type: "string", Description: fmt.Sprintf("Comma separated associations of %s, default %q", strings.Join(sortedKeys(l.Includes), ", "), l.Include)})
+	}
+	for _, name := range sortedKeys(l.Filters) {
+		filter := l.Filters[name]
+		params = append(params, Param{Name: name, Type: filter.Type, Description: filter.Description})
+	}
+	return params
+}
+
+// Paged writes a page of a list with status 200. X-Total-Count is the size
+// of the whole list and Link has the next and previous pages.
+func Paged(w http.ResponseWriter, r *http.Request, page Page, data interface{}) {
+	w.Header().Set("X-Total-Count", strconv.FormatInt(page.Total, 10))
+	var links []string
+	if next := page.Offset + page.Limit; int64(next) < page.Total {
+		links = append(links, fmt.Sprintf("<%s>; rel=\"next\"", pageURL(r, page.Limit, next)))
+	}
+	if page.Offset > 0 {
+		links = append(links, fmt.Sprintf("<%s>; rel=\"prev\"", pageURL(r, page.Limit, max(page.Offset-page.Limit, 0))))
+	}
+	if len(links) > 0 {
+		w.Header().Set("Link", strings.Join(links, ", "))
+	}
+	JSON(w, data)
+}
+
+// pageURL returns the request's URL at another offset.
+func pageURL(r *http.Request, limit, offset int) string {
+	query := r.URL.Query()
+	query.Set("limit", strconv.Itoa(limit))
+	query.Set("offset", strconv.Itoa(offset))
+	return r.URL.Path + "?" + query.Encode()
+}
+
+// Equals filters column by an ID.
+func Equals(column, description string) Filter {
+	return ByID(column+" = ?", description)
+}
+
+// ByID filters by a condition whose every ? is an ID.
+func ByID(condition, description string) Filter {
+	return Filter{Type: "integer", Description: description, Apply: func(db *gorm.DB, value string) (*gorm.DB, error) {
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an ID", value)
+		}
+		args := make([]interface{}, strings.Count(condition, "?"))
+		for i := range args {
+			args[i] = id
+		}
+		return db.Where(condition, args...), nil
+	}}
+}
+
+// After filters column to be after an RFC 3339 time.
+func After(column, description string) Filter {
+	return timeFilter(column+" > ?", description)
+}
+
+// Before filters column to be before an RFC 3339 time.
+func Before(column, description string) Filter {
+	return timeFilter(column+" < ?", description)
+}
+
+// timeFilter filters by an RFC 3339 time in UTC, the times are stored in
+// UTC and SQLite compares them as text.
+func timeFilter(condition, description string) Filter {
+	return Filter{Type: "string", Description: description, Apply: func(db *gorm.DB, value string) (*gorm.DB, error) {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an RFC 3339 time", value)
+		}
+		return db.Where(condition, t.UTC()), nil
+	}}
+}
+
+// Contains filters column by a case-insensitive substring.
+func Contains(column, description string) Filter {
+	escape := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+	return Filter{Type: "string", Description: description, Apply: func(db *gorm.DB, value string) (*gorm.DB, error) {
+		return db.Where(column+` LIKE ? ESCAPE '\'`, "%"+escape.Replace(value)+"%"), nil
+	}}
+}
+
+// splitList splits a comma separated parameter, dropping empty items.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// sortedKeys returns the keys of a map in order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,135 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type listItem struct {
+	ItemID uint `gorm:"primaryKey"`
+	Name   string
+	Kind   string
+	Notes  []listNote `gorm:"foreignKey:ItemID"`
+}
+
+type listNote struct {
+	NoteID uint `gorm:"primaryKey"`
+	ItemID uint
+	Text   string
+}
+
+var testList = List{
+	Filters: map[string]Filter{
+		"kind": {Type: "string", Apply: func(db *gorm.DB, value string) (*gorm.DB, error) {
+			return db.Where("kind = ?", value), nil
+		}},
+		"item": Equals("item_id", "Only this item"),
+	},
+	Sorts:    map[string]string{"name": "name", "kind": "kind", "id": "item_id"},
+	Sort:     "name",
+	Key:      "item_id",
+	Includes: map[string][]string{"notes": {"Notes"}},
+}
+
+// openListDB returns an in-memory database of items named a to e, of kinds
+// x and y.
+func openListDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = db.AutoMigrate(&listItem{}, &listNote{}); err != nil {
+		t.Fatal(err)
+	}
+	items := []listItem{
+		{ItemID: 1, Name: "c", Kind: "x", Notes: []listNote{{Text: "note"}}},
+		{ItemID: 2, Name: "a", Kind: "y"},
+		{ItemID: 3, Name: "e", Kind: "x"},
+		{ItemID: 4, Name: "b", Kind: "y"},
+		{ItemID: 5, Name: "b", Kind: "x"},
+	}
+	if err = db.Create(&items).Error; err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestListFind(t *testing.T) {
+	db := openListDB(t)
+	tests := []struct {
+		query string
+		want  []uint
+		total int64
+		notes bool
+	}{
+		{"", []uint{2, 4, 5, 1, 3}, 5, false},
+		{"sort=-name", []uint{3, 1, 4, 5, 2}, 5, false},
+		{"sort=kind,-name", []uint{3, 1, 5, 4, 2}, 5, false},
+		{"sort=", []uint{1, 2, 3, 4, 5}, 5, false},
+		{"kind=x", []uint{5, 1, 3}, 3, false},
+		{"kind=x&item=3", []uint{3}, 1, false},
+		{"limit=2&offset=1", []uint{4, 5}, 5, false},
+		{"offset=5", []uint{}, 5, false},
+		{"item=1&include=notes", []uint{1}, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			items := []listItem{}
+			page, err := testList.Find(db, httptest.NewRequest(http.MethodGet, "/items?"+tt.query, nil), &items)
+			if err != nil {
+				t.Fatalf("Find() error = %v", err)
+			}
+			got := []uint{}
+			for _, item := range items {
+				got = append(got, item.ItemID)
+				if len(item.Notes) > 0 && !tt.notes {
+					t.Errorf("item %d notes are preloaded", item.ItemID)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Find() items = %v, want %v", got, tt.want)
+			}
+			if page.Total != tt.total {
+				t.Errorf("Find() total = %d, want %d", page.Total, tt.total)
+			}
+			if tt.notes && len(items[0].Notes) != 1 {
+				t.Errorf("item %d has %d notes preloaded, want 1", items[0].ItemID, len(items[0].Notes))
+			}
+		})
+	}
+}
+
+func TestListFindRejects(t *testing.T) {
+	db := openListDB(t)
+	for _, query := range []string{
+		"limit=0",
+		fmt.Sprintf("limit=%d", MaxLimit+1),
+		"limit=ten",
+		"offset=-1",
+		"sort=colour",
+		"sort=name,-colour",
+		"sort=name%3BDROP%20TABLE%20list_items",
+		"item=one",
+		"item=-1",
+		"include=tags",
+		"include=notes,tags",
+	} {
+		items := []listItem{}
+		_, err := testList.Find(db, httptest.NewRequest(http.MethodGet, "/items?"+query, nil), &items)
+		if err == nil {
+			t.Errorf("Find(%q) succeeded, want an error", query)
+			continue
+		}
+		if status := StatusOf(err); status != http.StatusBadRequest {
+			t.Errorf("Find(%q) error %v has status %d, want %d", query, err, status, http.StatusBadRequest)
+		}
+	}
+}
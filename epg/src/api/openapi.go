@@ -0,0 +1,305 @@
+// OpenAPI 3 document generated from the route table
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+// OpenAPIVersion is the version of the OpenAPI specification documents follow.
+const OpenAPIVersion = "3.0.3"
+
+// Info describes the API.
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// Document is an OpenAPI document.
+type Document struct {
+	OpenAPI    string                          `json:"openapi"`
+	Info       Info                            `json:"info"`
+	Servers    []Server                        `json:"servers"`
+	Paths      map[string]map[string]Operation `json:"paths"`
+	Components Components                      `json:"components"`
+}
+
+// Server is where the API is served.
+type Server struct {
+	URL string `json:"url"`
+}
+
+// Components holds the schemas the operations refer to.
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas"`
+}
+
+// Operation is a method on a path.
+type Operation struct {
+	OperationID string              `json:"operationId"`
+	Summary     string              `json:"summary,omitempty"`
+	Tags        []string            `json:"tags,omitempty"`
+	Parameters  []Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]Response `json:"responses"`
+}
+
+// Parameter is a path or query parameter.
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody is the body an operation takes.
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response is a response of an operation.
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType is the schema of a body in a media type.
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+// Schema is the subset of JSON schema the generated documents use.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+// errorDoc documents ErrorBody with the Violation type its Details return,
+// which ErrorBody leaves open.
+type errorDoc struct {
+	Status     bool        `json:"status"`
+	Message    string      `json:"message"`
+	Violations []Violation `json:"violations,omitempty"`
+}
+
+// pathParam matches the {name} and {name:pattern} parameters of a path.
+var pathParam = regexp.MustCompile(`\{([^}:]+)(:[^}]*)?\}`)
+
+// NewDocument returns the OpenAPI document of routes served at root.
+func NewDocument(info Info, root string, routes []Route) *Document {
+	doc := &Document{
+		OpenAPI:    OpenAPIVersion,
+		Info:       info,
+		Servers:    []Server{{URL: root}},
+		Paths:      map[string]map[string]Operation{},
+		Components: Components{Schemas: map[string]*Schema{}},
+	}
+	gen := &schemaGenerator{components: doc.Components.Schemas, names: map[reflect.Type]string{}}
+	doc.Components.Schemas["Error"] = gen.object(reflect.TypeOf(errorDoc{}))
+	errorRef := &Schema{Ref: "#/components/schemas/Error"}
+
+	operationIDs := map[string]int{}
+	for _, route := range routes {
+		op := Operation{
+			OperationID: operationID(route, operationIDs),
+			Summary:     route.Summary,
+			Responses:   map[string]Response{},
+		}
+		if route.Tag != "" {
+			op.Tags = []string{route.Tag}
+		}
+		for _, m := range pathParam.FindAllStringSubmatch(route.Path, -1) {
+			schema := &Schema{Type: "string"}
+			if strings.HasSuffix(m[1], "Id") {
+				schema = &Schema{Type: "integer"}
+			}
+			op.Parameters = append(op.Parameters, Parameter{Name: m[1], In: "path", Required: true, Schema: schema})
+		}
+		for _, p := range route.Query {
+			op.Parameters = append(op.Parameters, Parameter{Name: p.Name, In: "query", Description: p.Description, Required: p.Required, Schema: &Schema{Type: p.Type}})
+		}
+
+		errors := []int{http.StatusBadRequest, http.StatusNotFound, http.StatusNotAcceptable, http.StatusInternalServerError}
+		if consumes := route.consumes(); len(consumes) > 0 {
+			body := &RequestBody{Required: true, Content: map[string]MediaType{}}
+			for _, mediaType := range consumes {
+				body.Content[mediaType] = MediaType{Schema: gen.bodySchema(route.Request, mediaType)}
+			}
+			op.RequestBody = body
+			errors = append(errors, http.StatusConflict, http.StatusUnsupportedMediaType)
+		}
+
+		success := Response{Description: http.StatusText(route.status())}
+		if route.Response != nil || route.produces() != MediaTypeJSON {
+			success.Content = map[string]MediaType{route.produces(): {Schema: gen.bodySchema(route.Response, route.produces())}}
+		}
+		op.Responses[strconv.Itoa(route.status())] = success
+		for _, status := range errors {
+			op.Responses[strconv.Itoa(status)] = Response{
+				Description: http.StatusText(status),
+				Content:     map[string]MediaType{MediaTypeJSON: {Schema: errorRef}},
+			}
+		}
+
+		path := root + pathParam.ReplaceAllString(route.Path, "{$1}")
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = map[string]Operation{}
+		}
+		doc.Paths[path][strings.ToLower(route.Method)] = op
+	}
+	return doc
+}
+
+// operationID returns the name of the route's handler method, numbered when
+// a handler serves several routes.
+func operationID(route Route, seen map[string]int) string {
+	name := runtime.FuncForPC(reflect.ValueOf(route.Handler).Pointer()).Name()
+	name = strings.TrimSuffix(name[strings.LastIndex(name, ".")+1:], "-fm")
+	if strings.HasPrefix(name, "func") {
+		// Closures are named after the method and path, getOpenapiJson.
+		name = strings.ToLower(route.Method)
+		words := strings.FieldsFunc(pathParam.ReplaceAllString(route.Path, ""), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		for _, word := range words {
+			name += strings.ToUpper(word[:1]) + word[1:]
+		}
+	}
+	seen[name]++
+	if seen[name] > 1 {
+		name += strconv.Itoa(seen[name])
+	}
+	return name
+}
+
+// schemaGenerator builds schemas from Go types as encoding/json encodes
+// them, named structs going to the components.
+type schemaGenerator struct {
+	components map[string]*Schema
+	names      map[reflect.Type]string
+}
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	deletedAtType = reflect.TypeOf(gorm.DeletedAt{})
+	rawType       = reflect.TypeOf(json.RawMessage{})
+)
+
+// bodySchema returns the schema of a body of value in mediaType, a plain
+// string for bodies other than JSON.
+func (g *schemaGenerator) bodySchema(value interface{}, mediaType string) *Schema {
+	if mediaType != MediaTypeJSON {
+		return &Schema{Type: "string"}
+	}
+	if value == nil {
+		return &Schema{}
+	}
+	return g.schema(reflect.TypeOf(value))
+}
+
+// schema returns the schema of t.
+func (g *schemaGenerator) schema(t reflect.Type) *Schema {
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case deletedAtType:
+		return &Schema{Type: "string", Format: "date-time", Nullable: true}
+	case rawType:
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		s := g.schema(t.Elem())
+		if s.Ref != "" {
+			// A $ref takes no siblings in OpenAPI 3.0.
+			return s
+		}
+		s.Nullable = true
+		return s
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t)
+		}
+		name, ok := g.names[t]
+		if !ok {
+			name = g.name(t)
+			g.names[t] = name
+			g.components[name] = &Schema{}
+			*g.components[name] = *g.object(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	}
+	// Interfaces and anything else take any value.
+	return &Schema{}
+}
+
+// name returns the component name of a named type, prefixed with its
+// package when another package has the same name.
+func (g *schemaGenerator) name(t reflect.Type) string {
+	name := t.Name()
+	for other, taken := range g.names {
+		if taken == name && other != t {
+			pkg := t.PkgPath()[strings.LastIndex(t.PkgPath(), "/")+1:]
+			return strings.ToUpper(pkg[:1]) + pkg[1:] + name
+		}
+	}
+	return name
+}
+
+// object returns the schema of a struct's JSON fields.
+func (g *schemaGenerator) object(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" || !field.IsExported() && !field.Anonymous {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			for key, value := range g.object(field.Type).Properties {
+				s.Properties[key] = value
+			}
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		s.Properties[name] = g.schema(field.Type)
+	}
+	return s
+}
@@ -0,0 +1,136 @@
+// JSON responses and the error envelope shared by the handlers
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrorBody is the envelope of every error response.
+type ErrorBody struct {
+	Status  bool   `json:"status"`
+	Message string `json:"message"`
+	// Violations lists what a refused request body breaks.
+	Violations interface{} `json:"violations,omitempty"`
+}
+
+// StatusError is an error answered with a given HTTP status.
+type StatusError struct {
+	Status int
+	Err    error
+}
+
+// Error ...
+func (e *StatusError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap ...
+func (e *StatusError) Unwrap() error {
+	return e.Err
+}
+
+// WithStatus returns err answered with status.
+func WithStatus(status int, err error) error {
+	return &StatusError{Status: status, Err: err}
+}
+
+// Errorf returns an error answered with status.
+func Errorf(status int, format string, args ...interface{}) error {
+	return &StatusError{Status: status, Err: fmt.Errorf(format, args...)}
+}
+
+// Invalid is implemented by errors refusing a request body for the
+// constraints it breaks, such as *ValidationError.
+type Invalid interface {
+	error
+	// Subject names what the body is, e.g. "channel".
+	Subject() string
+	// Details returns the violations reported in the envelope.
+	Details() interface{}
+	// Conflict reports whether the body only clashes with other records.
+	Conflict() bool
+}
+
+// StatusOf returns the HTTP status an error is answered with: 413 for a body
+// over the limit, the status of a *StatusError, 400 or 409 for an Invalid
+// body, 404 for a missing record, 400 for malformed JSON, numbers and times,
+// 409 for a unique constraint and 500 for anything else.
+func StatusOf(err error) int {
+	// A body over the limit is too large whatever the handler made of it.
+	var maxBytes *http.MaxBytesError
+	if errors.As(err, &maxBytes) {
+		return http.StatusRequestEntityTooLarge
+	}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Status
+	}
+	var invalid Invalid
+	if errors.As(err, &invalid) {
+		if invalid.Conflict() {
+			return http.StatusConflict
+		}
+		return http.StatusBadRequest
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return http.StatusNotFound
+	}
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var numErr *strconv.NumError
+	var timeErr *time.ParseError
+	if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) || errors.As(err, &numErr) || errors.As(err, &timeErr) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return http.StatusBadRequest
+	}
+	// SQLite reports unique constraints only in the message.
+	if errors.Is(err, gorm.ErrDuplicatedKey) || strings.Contains(err.Error(), "UNIQUE constraint failed") {
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+// Error writes err in the error envelope with the status of StatusOf.
+// Server errors are logged.
+func Error(w http.ResponseWriter, err error) {
+	status := StatusOf(err)
+	body := ErrorBody{Status: false, Message: err.Error()}
+	var invalid Invalid
+	if errors.As(err, &invalid) {
+		body.Message = "invalid " + invalid.Subject()
+		body.Violations = invalid.Details()
+	}
+	if status >= http.StatusInternalServerError {
+		log.Println(err)
+	}
+	Write(w, status, body)
+}
+
+// JSON writes data with status 200.
+func JSON(w http.ResponseWriter, data interface{}) {
+	Write(w, http.StatusOK, data)
+}
+
+// Created writes a created record with status 201.
+func Created(w http.ResponseWriter, data interface{}) {
+	Write(w, http.StatusCreated, data)
+}
+
+// Write writes data as JSON with status.
+func Write(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		log.Println(err)
+	}
+}
@@ -0,0 +1,171 @@
+// Versioned API routes with content negotiation
+package api
+
+import (
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// MediaTypeJSON is what routes produce and consume unless they say otherwise.
+const MediaTypeJSON = "application/json"
+
+// Route is an entry of the route table, served and documented by Mount.
+type Route struct {
+	Method string
+	// Path is relative to the API root, with {name} path parameters.
+	Path    string
+	Summary string
+	Tag     string
+	Handler http.HandlerFunc
+	// Query documents the query parameters.
+	Query []Param
+	// Request and Response are values of the body types, nil for none.
+	Request  interface{}
+	Response interface{}
+	// Status is the success status, default 200.
+	Status int
+	// Produces and Consumes default to MediaTypeJSON.
+	Produces string
+	Consumes []string
+}
+
+// Param is a documented query parameter, Type is an OpenAPI type.
+type Param struct {
+	Name        string
+	Type        string
+	Description string
+	Required    bool
+}
+
+// status returns the success status of the route.
+func (route Route) status() int {
+	if route.Status == 0 {
+		return http.StatusOK
+	}
+	return route.Status
+}
+
+// produces returns the media type of the route's responses.
+func (route Route) produces() string {
+	if route.Produces == "" {
+		return MediaTypeJSON
+	}
+	return route.Produces
+}
+
+// consumes returns the media types the route's request body may have, none
+// when it takes no body.
+func (route Route) consumes() []string {
+	if len(route.Consumes) > 0 {
+		return route.Consumes
+	}
+	if route.Request != nil {
+		return []string{MediaTypeJSON}
+	}
+	return nil
+}
+
+// Mount serves routes on router, a subrouter at root, and their OpenAPI
+// document at /openapi.json. Requests must accept what a route produces
+// (406) and send a body it consumes (415). Unknown paths and methods get
+// the error envelope.
+func Mount(router *mux.Router, root string, info Info, routes []Route) {
+	var doc *Document
+	routes = append(routes, Route{
+		Method:  http.MethodGet,
+		Path:    "/openapi.json",
+		Summary: "This OpenAPI document",
+		Tag:     "api",
+		Handler: func(w http.ResponseWriter, r *http.Request) {
+			JSON(w, doc)
+		},
+		Response: map[string]interface{}{},
+	})
+	doc = NewDocument(info, root, routes)
+
+	// Routes are grouped by path to answer other methods with 405 here,
+	// gorilla/mux loses the method mismatch in a subrouter of several routes.
+	var paths []string
+	methods := map[string]map[string]http.Handler{}
+	for _, route := range routes {
+		if methods[route.Path] == nil {
+			paths = append(paths, route.Path)
+			methods[route.Path] = map[string]http.Handler{}
+		}
+		methods[route.Path][route.Method] = negotiate(route)
+	}
+	for _, path := range paths {
+		router.Handle(path, dispatch(methods[path]))
+	}
+	router.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Error(w, Errorf(http.StatusNotFound, "no such path %s", r.URL.Path))
+	})
+}
+
+// dispatch hands a request to the handler of its method, answering other
+// methods with 405 and the allowed ones.
+func dispatch(handlers map[string]http.Handler) http.Handler {
+	allowed := make([]string, 0, len(handlers))
+	for method := range handlers {
+		allowed = append(allowed, method)
+	}
+	slices.Sort(allowed)
+	allow := strings.Join(allowed, ", ")
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler, ok := handlers[r.Method]
+		if !ok {
+			w.Header().Set("Allow", allow)
+			Error(w, Errorf(http.StatusMethodNotAllowed, "method %s is not allowed on %s", r.Method, r.URL.Path))
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// negotiate checks the Accept and Content-Type of a request before handing
+// it to the route.
+func negotiate(route Route) http.Handler {
+	produces := route.produces()
+	consumes := route.consumes()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !Accepts(r.Header.Get("Accept"), produces) {
+			Error(w, Errorf(http.StatusNotAcceptable, "%s is only served as %s", r.URL.Path, produces))
+			return
+		}
+		if r.ContentLength != 0 && len(consumes) > 0 {
+			mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			if err != nil || !slices.Contains(consumes, mediaType) {
+				Error(w, Errorf(http.StatusUnsupportedMediaType, "%s takes %s", r.URL.Path, strings.Join(consumes, " or ")))
+				return
+			}
+		}
+		route.Handler(w, r)
+	})
+}
+
+// Accepts reports whether an Accept header allows mediaType. An empty header
+// accepts anything.
+func Accepts(accept, mediaType string) bool {
+	if strings.TrimSpace(accept) == "" {
+		return true
+	}
+	major, _, _ := strings.Cut(mediaType, "/")
+	for _, part := range strings.Split(accept, ",") {
+		accepted, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		if q, err := strconv.ParseFloat(params["q"], 64); err == nil && q == 0 {
+			continue
+		}
+		if accepted == "*/*" || accepted == major+"/*" || accepted == mediaType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+// Violations of request bodies
+package api
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Violation codes of a request body.
+const (
+	ViolationRequired = "required"
+	ViolationInvalid  = "invalid"
+	ViolationUnknown  = "unknown"
+	// ViolationOffAir and ViolationTooLong refuse events outside the broadcast
+	// window and with texts the EIT cannot carry.
+	ViolationOffAir  = "off_air"
+	ViolationTooLong = "too_long"
+	// ViolationDuplicate, ViolationPIDConflict and ViolationOverlap clash with
+	// other records.
+	ViolationDuplicate   = "duplicate"
+	ViolationPIDConflict = "pid_conflict"
+	ViolationOverlap     = "overlap"
+)
+
+// Violation is one constraint a request body breaks, Field names its JSON field.
+type Violation struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// EventID is the event overlapped by an overlap violation.
+	EventID uint `json:"eventID,omitempty"`
+}
+
+// ValidationError lists every constraint a request body for Resource breaks.
+type ValidationError struct {
+	Resource   string
+	Violations []Violation
+}
+
+// Add appends a violation.
+func (e *ValidationError) Add(field, code, format string, args ...interface{}) {
+	e.Violations = append(e.Violations, Violation{Field: field, Code: code, Message: fmt.Sprintf(format, args...)})
+}
+
+// Err returns e when it has violations, nil otherwise.
+func (e *ValidationError) Err() error {
+	if len(e.Violations) == 0 {
+		return nil
+	}
+	return e
+}
+
+// Error ...
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		messages[i] = v.Field + ": " + v.Message
+	}
+	return "invalid " + e.Resource + ": " + strings.Join(messages, "; ")
+}
+
+// Subject ...
+func (e *ValidationError) Subject() string {
+	return e.Resource
+}
+
+// Details ...
+func (e *ValidationError) Details() interface{} {
+	return e.Violations
+}
+
+// Conflict reports whether the body is only refused for clashing with other
+// records, it is otherwise well formed.
+func (e *ValidationError) Conflict() bool {
+	for _, v := range e.Violations {
+		if v.Code != ViolationDuplicate && v.Code != ViolationPIDConflict && v.Code != ViolationOverlap {
+			return false
+		}
+	}
+	return len(e.Violations) > 0
+}
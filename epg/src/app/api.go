@@ -0,0 +1,152 @@
+package main
+
+import (
+	"net/http"
+
+	"epg/src/api"
+	"epg/src/controller"
+	"epg/src/model"
+	"epg/src/nownext"
+	"epg/src/schedule"
+	"epg/src/xmltv"
+)
+
+// apiRoot is where the versioned API is served.
+const apiRoot = "/api/v1"
+
+// apiInfo describes the versioned API in its OpenAPI document.
+var apiInfo = api.Info{
+	Title:       "EPG",
+	Version:     "1",
+	Description: "Networks, channels and the events of their DVB EIT schedules.",
+}
+
+// message is the body of the responses that only report what was done.
+type message map[string]interface{}
+
+// apiRoutes returns the route table of the versioned API.
+func (s *Server) apiRoutes() []api.Route {
+	countryHandler := controller.NewCountryHandler(s.db)
+	timezoneHandler := controller.NewTimezoneHandler(s.db)
+	genreHandler := controller.NewGenreHandler(s.db)
+	categoryHandler := controller.NewCategoryHandler(s.db)
+	ratingHandler := controller.NewRatingHandler(s.db)
+	ratingValueHandler := controller.NewRatingValueHandler(s.db)
+	networkHandler := controller.NewNetworkHandler(s.db)
+	channelHandler := controller.NewChannelHandler(s.db)
+	elementaryStreamHandler := controller.NewElementaryStreamHandler(s.db)
+	eventHandler := controller.NewEventHandler(s.db)
+	eventRatingHandler := controller.NewEventRatingHandler(s.db)
+	eventGenreHandler := controller.NewEventGenreHandler(s.db)
+	scheduleRuleHandler := controller.NewScheduleRuleHandler(s.db)
+	scheduleHandler := controller.NewScheduleHandler(s.db)
+	nowNextHandler := controller.NewNowNextHandler(s.db, s.broker)
+	importHandler := controller.NewImportHandler(s.db)
+	authHandler := controller.NewAuthHandler(s.db, s.sessions)
+	userHandler := controller.NewUserHandler(s.db)
+
+	window := []api.Param{
+		{Name: "channel", Type: "integer", Description: "Channel ID, default all"},
+		{Name: "from", Type: "string", Description: "RFC 3339 start, default now"},
+		{Name: "hours", Type: "integer", Description: "Hours checked, default a week"},
+	}
+
+	return []api.Route{
+		// Session
+		{Method: http.MethodGet, Path: "/session", Summary: "Logged in user and CSRF token", Tag: "users", Handler: authHandler.GetSession, Response: controller.SessionResponse{}},
+		{Method: http.MethodPost, Path: "/session", Summary: "Log in", Tag: "users", Handler: authHandler.CreateSession, Request: controller.LoginRequest{}, Response: controller.SessionResponse{}, Status: http.StatusCreated},
+		{Method: http.MethodDelete, Path: "/session", Summary: "Log out", Tag: "users", Handler: authHandler.DeleteSession, Response: message{}},
+
+		// Users
+		{Method: http.MethodGet, Path: "/users", Summary: "List users", Tag: "users", Handler: userHandler.GetAllUsers, Response: []model.User{}, Query: controller.UserList.Params()},
+		{Method: http.MethodGet, Path: "/users/{userId}", Summary: "Get a user", Tag: "users", Handler: userHandler.GetUserById, Response: model.User{}},
+		{Method: http.MethodPost, Path: "/users", Summary: "Create a user", Tag: "users", Handler: userHandler.CreateUser, Request: controller.UserRequest{}, Response: model.User{}, Status: http.StatusCreated},
+		{Method: http.MethodPut, Path: "/users/{userId}", Summary: "Update a user", Tag: "users", Handler: userHandler.UpdateUser, Request: controller.UserRequest{}, Response: model.User{}},
+		{Method: http.MethodDelete, Path: "/users/{userId}", Summary: "Delete a user", Tag: "users", Handler: userHandler.DeleteUser, Response: message{}},
+
+		// Countries
+		{Method: http.MethodGet, Path: "/countries", Summary: "List countries", Tag: "countries", Handler: countryHandler.GetAllCountries, Response: []model.Country{}},
+		{Method: http.MethodGet, Path: "/countries/code/{countryCode}", Summary: "Find countries by code", Tag: "countries", Handler: countryHandler.GetCountryByCode, Response: []model.Country{}},
+		{Method: http.MethodGet, Path: "/countries/{countryId}", Summary: "Get a country", Tag: "countries", Handler: countryHandler.GetCountryById, Response: model.Country{}},
+		{Method: http.MethodPost, Path: "/countries", Summary: "Create a country", Tag: "countries", Handler: countryHandler.CreateCountry, Request: model.Country{}, Response: model.Country{}, Status: http.StatusCreated},
+		{Method: http.MethodPut, Path: "/countries/{countryId}", Summary: "Update a country", Tag: "countries", Handler: countryHandler.UpdateCountry, Request: model.Country{}, Response: model.Country{}},
+		{Method: http.MethodDelete, Path: "/countries/{countryId}", Summary: "Delete a country", Tag: "countries", Handler: countryHandler.DeleteCountry, Response: message{}},
+
+		// Timezones
+		{Method: http.MethodGet, Path: "/timezones", Summary: "List timezones", Tag: "timezones", Handler: timezoneHandler.GetAllTimezones, Response: []model.Timezone{}},
+		{Method: http.MethodGet, Path: "/timezones/{timezoneId}", Summary: "Get a timezone", Tag: "timezones", Handler: timezoneHandler.GetTimezoneById, Response: model.Timezone{}},
+
+		// Genres and categories
+		{Method: http.MethodGet, Path: "/genres", Summary: "List genres", Tag: "genres", Handler: genreHandler.GetAllGenres, Response: []model.Genre{}, Query: controller.GenreList.Params()},
+		{Method: http.MethodGet, Path: "/genres/{genreId}", Summary: "Get a genre", Tag: "genres", Handler: genreHandler.GetGenreById, Response: model.Genre{}},
+		{Method: http.MethodGet, Path: "/categories", Summary: "List categories", Tag: "categories", Handler: categoryHandler.GetAllCategories, Response: []model.Category{}, Query: controller.CategoryList.Params()},
+		{Method: http.MethodGet, Path: "/categories/{categoryId}", Summary: "Get a category", Tag: "categories", Handler: categoryHandler.GetCategoryById, Response: model.Category{}},
+
+		// Ratings
+		{Method: http.MethodGet, Path: "/ratingsystems", Summary: "List rating systems", Tag: "ratings", Handler: ratingHandler.GetAllRatingSystems, Response: []model.RatingSystem{}, Query: controller.RatingSystemList.Params()},
+		{Method: http.MethodGet, Path: "/ratingsystems/{ratingSystemId}", Summary: "Get a rating system", Tag: "ratings", Handler: ratingHandler.GetRatingSystemById, Response: model.RatingSystem{}},
+		{Method: http.MethodGet, Path: "/ratingvalues", Summary: "List rating values", Tag: "ratings", Handler: ratingValueHandler.GetAllRatingValues, Response: []model.RatingValue{}, Query: controller.RatingValueList.Params()},
+		{Method: http.MethodGet, Path: "/ratingvalues/{ratingValueId}", Summary: "Get a rating value", Tag: "ratings", Handler: ratingValueHandler.GetRatingValueById, Response: model.RatingValue{}},
+
+		// Networks
+		{Method: http.MethodGet, Path: "/networks", Summary: "List networks", Tag: "networks", Handler: networkHandler.GetAllNetworks, Response: []model.Network{}},
+		{Method: http.MethodGet, Path: "/networks/{networkId}", Summary: "Get a network", Tag: "networks", Handler: networkHandler.GetNetworkById, Response: model.Network{}},
+		{Method: http.MethodPost, Path: "/networks", Summary: "Create a network", Tag: "networks", Handler: networkHandler.CreateNetwork, Request: model.Network{}, Response: model.Network{}, Status: http.StatusCreated},
+		{Method: http.MethodPut, Path: "/networks/{networkId}", Summary: "Update a network", Tag: "networks", Handler: networkHandler.UpdateNetwork, Request: model.Network{}, Response: model.Network{}},
+		{Method: http.MethodDelete, Path: "/networks/{networkId}", Summary: "Delete a network with its channels and events", Tag: "networks", Handler: networkHandler.DeleteNetwork, Response: message{}},
+
+		// Channels
+		{Method: http.MethodGet, Path: "/channels", Summary: "List channels", Tag: "channels", Handler: channelHandler.GetAllChannels, Response: []model.Channel{}, Query: controller.ChannelList.Params()},
+		{Method: http.MethodGet, Path: "/channels/{channelId}", Summary: "Get a channel", Tag: "channels", Handler: channelHandler.GetChannelById, Response: model.Channel{}},
+		{Method: http.MethodPost, Path: "/channels", Summary: "Create a channel", Tag: "channels", Handler: channelHandler.CreateChannel, Request: model.Channel{}, Response: model.Channel{}, Status: http.StatusCreated},
+		{Method: http.MethodPut, Path: "/channels/{channelId}", Summary: "Update a channel", Tag: "channels", Handler: channelHandler.UpdateChannel, Request: model.Channel{}, Response: model.Channel{}},
+		{Method: http.MethodDelete, Path: "/channels/{channelId}", Summary: "Delete a channel with its events", Tag: "channels", Handler: channelHandler.DeleteChannel, Response: message{}},
+		{Method: http.MethodGet, Path: "/channels/{channelId}/now-next", Summary: "Present and following event of a channel", Tag: "now/next", Handler: nowNextHandler.GetChannelNowNext, Response: nownext.Channel{}},
+
+		// Elementary streams
+		{Method: http.MethodGet, Path: "/elementarystreams", Summary: "List elementary streams", Tag: "channels", Handler: elementaryStreamHandler.GetAllElementaryStreams, Response: []model.ElementaryStream{}},
+		{Method: http.MethodGet, Path: "/elementarystreams/{elementaryStreamId}", Summary: "Get an elementary stream", Tag: "channels", Handler: elementaryStreamHandler.GetElementaryStreamById, Response: model.ElementaryStream{}},
+		{Method: http.MethodPost, Path: "/elementarystreams", Summary: "Create an elementary stream", Tag: "channels", Handler: elementaryStreamHandler.CreateElementaryStream, Request: model.ElementaryStream{}, Response: model.ElementaryStream{}, Status: http.StatusCreated},
+		{Method: http.MethodDelete, Path: "/elementarystreams/{elementaryStreamId}", Summary: "Delete an elementary stream", Tag: "channels", Handler: elementaryStreamHandler.DeleteElementaryStream, Response: message{}},
+
+		// Events
+		{Method: http.MethodGet, Path: "/events", Summary: "List events", Tag: "events", Handler: eventHandler.GetAllEvents, Response: []model.Event{}, Query: controller.EventList.Params()},
+		{Method: http.MethodGet, Path: "/events/stream", Summary: "Stream now/next changes as server-sent events", Tag: "now/next", Handler: nowNextHandler.StreamNowNext, Produces: "text/event-stream"},
+		{Method: http.MethodGet, Path: "/events/{eventId}", Summary: "Get an event", Tag: "events", Handler: eventHandler.GetEventById, Response: model.Event{}},
+		{Method: http.MethodPost, Path: "/events", Summary: "Create an event", Tag: "events", Handler: eventHandler.CreateEvent, Request: model.Event{}, Response: model.Event{}, Status: http.StatusCreated},
+		{Method: http.MethodPut, Path: "/events/{eventId}", Summary: "Update an event", Tag: "events", Handler: eventHandler.UpdateEvent, Request: model.Event{}, Response: model.Event{}},
+		{Method: http.MethodDelete, Path: "/events/{eventId}", Summary: "Delete an event", Tag: "events", Handler: eventHandler.DeleteEvent, Response: message{}},
+
+		// Event ratings and genres
+		{Method: http.MethodGet, Path: "/eventratings", Summary: "List event ratings", Tag: "events", Handler: eventRatingHandler.GetAllEventRatings, Response: []model.EventRating{}},
+		{Method: http.MethodGet, Path: "/eventratings/{eventId}/{ratingValueId}", Summary: "Get an event rating", Tag: "events", Handler: eventRatingHandler.GetEventRatingById, Response: model.EventRating{}},
+		{Method: http.MethodPost, Path: "/eventratings", Summary: "Rate an event", Tag: "events", Handler: eventRatingHandler.CreateEventRating, Request: model.EventRating{}, Response: model.EventRating{}, Status: http.StatusCreated},
+		{Method: http.MethodPut, Path: "/eventratings/{eventId}/{ratingValueId}", Summary: "Update an event rating", Tag: "events", Handler: eventRatingHandler.UpdateEventRating, Request: model.EventRating{}, Response: model.EventRating{}},
+		{Method: http.MethodDelete, Path: "/eventratings/{eventId}/{ratingValueId}", Summary: "Delete an event rating", Tag: "events", Handler: eventRatingHandler.DeleteEventRating, Response: message{}},
+		{Method: http.MethodGet, Path: "/eventgenres", Summary: "List event genres", Tag: "events", Handler: eventGenreHandler.GetAllEventGenres, Response: []model.EventGenre{}},
+		{Method: http.MethodGet, Path: "/eventgenres/{eventId}/{genreId}", Summary: "Get an event genre", Tag: "events", Handler: eventGenreHandler.GetEventGenreById, Response: model.EventGenre{}},
+		{Method: http.MethodPost, Path: "/eventgenres", Summary: "Add a genre to an event", Tag: "events", Handler: eventGenreHandler.CreateEventGenre, Request: model.EventGenre{}, Response: model.EventGenre{}, Status: http.StatusCreated},
+		{Method: http.MethodDelete, Path: "/eventgenres/{eventId}/{genreId}", Summary: "Remove a genre from an event", Tag: "events", Handler: eventGenreHandler.DeleteEventGenre, Response: message{}},
+
+		// Schedule rules
+		{Method: http.MethodGet, Path: "/schedulerules", Summary: "List schedule rules", Tag: "schedule", Handler: scheduleRuleHandler.GetAllScheduleRules, Response: []model.ScheduleRule{}},
+		{Method: http.MethodPost, Path: "/schedulerules", Summary: "Create a schedule rule", Tag: "schedule", Handler: scheduleRuleHandler.CreateScheduleRule, Request: model.ScheduleRule{}, Response: model.ScheduleRule{}, Status: http.StatusCreated},
+		{Method: http.MethodPost, Path: "/schedulerules/materialize", Summary: "Materialise the events of the enabled rules", Tag: "schedule", Handler: scheduleRuleHandler.MaterializeScheduleRules, Response: schedule.Result{},
+			Query: []api.Param{{Name: "days", Type: "integer", Description: "Days from now, default 7"}}},
+		{Method: http.MethodGet, Path: "/schedulerules/{scheduleRuleId}", Summary: "Get a schedule rule", Tag: "schedule", Handler: scheduleRuleHandler.GetScheduleRuleById, Response: model.ScheduleRule{}},
+		{Method: http.MethodPut, Path: "/schedulerules/{scheduleRuleId}", Summary: "Update a schedule rule", Tag: "schedule", Handler: scheduleRuleHandler.UpdateScheduleRule, Request: model.ScheduleRule{}, Response: model.ScheduleRule{}},
+		{Method: http.MethodDelete, Path: "/schedulerules/{scheduleRuleId}", Summary: "Delete a schedule rule and its future events", Tag: "schedule", Handler: scheduleRuleHandler.DeleteScheduleRule, Response: message{}},
+
+		// Schedule checks
+		{Method: http.MethodGet, Path: "/schedule/report", Summary: "Report overlaps and gaps", Tag: "schedule", Handler: scheduleHandler.GetScheduleReport, Response: []schedule.ChannelReport{}, Query: window},
+		{Method: http.MethodPost, Path: "/schedule/fill", Summary: "Fill gaps with the filler event", Tag: "schedule", Handler: scheduleHandler.FillScheduleGaps, Response: []schedule.ChannelReport{}, Query: window},
+
+		// Now/next
+		{Method: http.MethodGet, Path: "/now", Summary: "Present and following events of every channel", Tag: "now/next", Handler: nowNextHandler.GetNow, Response: nownext.Snapshot{}},
+
+		// Import
+		{Method: http.MethodPost, Path: "/import/xmltv", Summary: "Import an XMLTV schedule", Tag: "import", Handler: importHandler.ImportXMLTV, Response: xmltv.Result{},
+			Consumes: []string{"application/xml", "text/xml", "multipart/form-data"},
+			Query:    []api.Param{{Name: "replace", Type: "boolean", Description: "Replace overlapping events instead of reporting them"}}},
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"time"
+
+	"epg/src/controller"
+	"epg/src/dvb/eit"
+)
+
+// runEITXML implements "epg eit-xml": it writes the EIT as TSDuck SI XML for
+// "tsp -P eitinject --files", once or rewriting it every interval.
+func runEITXML(args []string) error {
+	flags := flag.NewFlagSet("eit-xml", flag.ExitOnError)
+	output := flags.String("o", "eit.xml", "output file")
+	days := flags.Int("days", 7, "number of days of EIT schedule")
+	interval := flags.Duration("interval", 0, "rewrite the file every interval, 0 to write it once")
+	flags.Parse(args)
+
+	generator := eit.NewGenerator(controller.GetDB(), *days)
+	if *interval <= 0 {
+		return writeEITXML(generator, *output)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	for {
+		if err := writeEITXML(generator, *output); err != nil {
+			log.Println(err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// writeEITXML replaces path atomically so a watching eitinject never reads a partial file.
+func writeEITXML(generator *eit.Generator, path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".eit-*.xml")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	err = generator.WriteTSDuckXML(tmp, time.Now())
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
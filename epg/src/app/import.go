@@ -0,0 +1,144 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	config "epg/src/config"
+	"epg/src/controller"
+	"epg/src/dvb"
+	"epg/src/dvb/eit"
+	"epg/src/dvb/ts"
+	"epg/src/xmltv"
+)
+
+// runImport implements "epg import <source>".
+func runImport(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: import ts|xmltv [flags]")
+	}
+	switch args[0] {
+	case "ts":
+		return runImportTS(args[1:])
+	case "xmltv":
+		return runImportXMLTV(args[1:])
+	}
+	return fmt.Errorf("unknown import source %q", args[0])
+}
+
+// runImportTS implements "epg import ts": it decodes the EIT of a captured
+// transport stream and stores its events on the channels with matching service IDs.
+func runImportTS(args []string) error {
+	flags := flag.NewFlagSet("import ts", flag.ExitOnError)
+	input := flags.String("i", "-", `input: "-" for stdin, udp://[group]:port or a file`)
+	pid := flags.Uint("pid", eit.PID, "PID carrying the EIT")
+	duration := flags.Duration("duration", 0, "stop reading after this long, needed for udp:// input, 0 to read to the end")
+	flags.Parse(args)
+
+	in, err := ts.OpenInput(*input)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	if *duration > 0 {
+		// Closing the input ends a blocked read.
+		timer := time.AfterFunc(*duration, func() { in.Close() })
+		defer timer.Stop()
+	}
+
+	importer, err := eit.NewImporter(controller.GetDB())
+	if err != nil {
+		return err
+	}
+
+	reader := ts.NewReader(in)
+	demux := ts.NewDemux(uint16(*pid))
+	var pkt ts.Packet
+	var sections, invalid int
+	started := time.Now()
+	for {
+		err := reader.ReadPacket(&pkt)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && (*duration == 0 || time.Since(started) < *duration) {
+				return err
+			}
+			break
+		}
+		for _, b := range demux.Push(&pkt) {
+			section, err := dvb.ParseSection(b)
+			if err != nil {
+				invalid++
+				continue
+			}
+			if !eit.IsEIT(section.TableID) || !section.CurrentNext {
+				continue
+			}
+			table, err := eit.Decode(section)
+			if err != nil {
+				invalid++
+				log.Println(err)
+				continue
+			}
+			sections++
+			importer.Add(table)
+		}
+	}
+
+	if err := importer.Commit(); err != nil {
+		return err
+	}
+	log.Printf("Decoded %d EIT sections, %d invalid, %d continuity errors", sections, invalid, demux.Discontinuities)
+	log.Printf("Created %d events, updated %d, skipped %d of unknown services", importer.Created, importer.Updated, importer.Skipped)
+	return nil
+}
+
+// runImportXMLTV implements "epg import xmltv <file>": it adds the programmes
+// of an XMLTV file as events, mapping channels with the config's xmltv_channels.
+func runImportXMLTV(args []string) error {
+	flags := flag.NewFlagSet("import xmltv", flag.ExitOnError)
+	replace := flags.Bool("replace", false, "replace overlapping events instead of reporting conflicts")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		return errors.New(`usage: import xmltv [-replace] <file or "-">`)
+	}
+
+	in := os.Stdin
+	if name := flags.Arg(0); name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	tv, err := xmltv.Parse(in)
+	if err != nil {
+		return err
+	}
+
+	importer := xmltv.NewImporter(controller.GetDB(), config.Config.XMLTVChannels)
+	importer.Replace = *replace
+	result, err := importer.Import(tv)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range result.Unmapped {
+		log.Printf("No channel for XMLTV channel %q", id)
+	}
+	for _, invalid := range result.Invalid {
+		log.Printf("Skipped %s", invalid)
+	}
+	for _, c := range result.Conflicts {
+		log.Printf("Conflict on channel %d: %q %s-%s overlaps event %d %q",
+			c.ChannelID, c.Title, c.Start.Format(time.RFC3339), c.Stop.Format(time.RFC3339), c.EventID, c.EventTitle)
+	}
+	log.Printf("Created %d events, replaced %d, %d duplicates, %d conflicts",
+		result.Created, result.Replaced, result.Duplicates, len(result.Conflicts))
+	return nil
+}
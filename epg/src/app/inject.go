@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"time"
+
+	"epg/src/controller"
+	"epg/src/dvb/eit"
+	"epg/src/dvb/nit"
+	"epg/src/dvb/psi"
+	"epg/src/dvb/sdt"
+	"epg/src/dvb/tdt"
+	"epg/src/dvb/ts"
+)
+
+// runInject implements "epg inject": it replaces the EIT PID of a transport
+// stream with sections generated from the database, in place of TSDuck's eitinject.
+func runInject(args []string) error {
+	flags := flag.NewFlagSet("inject", flag.ExitOnError)
+	input := flags.String("i", "-", `input: "-" for stdin, udp://[group]:port or a file`)
+	output := flags.String("o", "-", `output: "-" for stdout, udp://host:port or a file`)
+	pfInterval := flags.Duration("pf-interval", 2*time.Second, "repetition interval of EIT present/following")
+	scheduleInterval := flags.Duration("schedule-interval", 10*time.Second, "repetition interval of EIT schedule")
+	sdtInterval := flags.Duration("sdt-interval", 0, "repetition interval of the SDT, 0 to leave the input SDT alone")
+	psiInterval := flags.Duration("psi-interval", 0, "repetition interval of the PAT and PMTs, 0 to leave the input ones alone")
+	nitInterval := flags.Duration("nit-interval", 0, "repetition interval of the NIT, 0 to leave the input NIT alone")
+	tdtInterval := flags.Duration("tdt-interval", 0, "repetition interval of the TDT and TOT, 0 to leave the input ones alone")
+	days := flags.Int("days", 7, "number of days of EIT schedule")
+	maxBitrate := flags.Int("max-bitrate", 0, "maximum bitrate of injected packets in bits/s, 0 for no limit")
+	tsBitrate := flags.Int("ts-bitrate", 0, "input bitrate in bits/s to clock file input, 0 to follow the wall clock")
+	flags.Parse(args)
+
+	in, err := ts.OpenInput(*input)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := ts.OpenOutput(*output)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	generator := eit.NewGenerator(controller.GetDB(), *days)
+	injector := &ts.Injector{MaxBitrate: *maxBitrate, TSBitrate: *tsBitrate}
+	injector.AddSource(&ts.Source{Name: "EIT p/f", PID: eit.PID, Interval: *pfInterval, Sections: generator.PresentFollowing})
+	injector.AddSource(&ts.Source{Name: "EIT schedule", PID: eit.PID, Interval: *scheduleInterval, Sections: generator.Schedule})
+	if *sdtInterval > 0 {
+		sdtGenerator := sdt.NewGenerator(controller.GetDB(), *days)
+		injector.AddSource(&ts.Source{Name: "SDT", PID: sdt.PID, Interval: *sdtInterval, Sections: sdtGenerator.Sections})
+	}
+	if *psiInterval > 0 {
+		psiGenerator := psi.NewGenerator(controller.GetDB())
+		injector.AddSource(&ts.Source{Name: "PAT", PID: psi.PIDPAT, Interval: *psiInterval, Sections: psiGenerator.PAT})
+		// PMT PIDs are read once, channels added later need a restart.
+		pids, err := psiGenerator.PMTPIDs()
+		if err != nil {
+			return err
+		}
+		for _, pid := range pids {
+			injector.AddSource(&ts.Source{Name: fmt.Sprintf("PMT 0x%04X", pid), PID: pid, Interval: *psiInterval, Sections: psiGenerator.PMT(pid)})
+		}
+	}
+	if *nitInterval > 0 {
+		nitGenerator := nit.NewGenerator(controller.GetDB())
+		injector.AddSource(&ts.Source{Name: "NIT", PID: nit.PID, Interval: *nitInterval, Sections: nitGenerator.Sections})
+	}
+	if *tdtInterval > 0 {
+		tdtGenerator := tdt.NewGenerator(controller.GetDB())
+		injector.AddSource(&ts.Source{Name: "TDT/TOT", PID: tdt.PID, Interval: *tdtInterval, Sections: tdtGenerator.Sections})
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	log.Printf("Injecting EIT from %s to %s", *input, *output)
+	err = injector.Run(ctx, in, out)
+	log.Printf("Injected %d packets, removed %d", injector.Injected, injector.Removed)
+	return err
+}
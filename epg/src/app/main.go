@@ -30,18 +30,29 @@ Make sure to replace the path/to/cert.pem and path/to/key.pem placeholders with
 package main
 
 import (
+	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"net/smtp"
+	"os"
+	"os/signal"
 	"strconv"
+	"sync"
+	"syscall"
 	"time"
 
 	"gorm.io/gorm"
 
+	"epg/src/api"
+	"epg/src/auth"
 	config "epg/src/config"
 	"epg/src/controller"
+	"epg/src/model"
+	"epg/src/nownext"
+	"epg/src/schedule"
 
 	"github.com/gorilla/mux"
 )
@@ -50,6 +61,8 @@ type PageData struct {
 	Title   string
 	Heading string
 	Content string
+	// CSRFToken is sent back by the page's forms.
+	CSRFToken string
 }
 
 type Server struct {
@@ -57,31 +70,98 @@ type Server struct {
 	port int
 	db   *gorm.DB
 	srv  *http.Server
+	// broker pushes now/next changes to the event stream
+	broker *nownext.Broker
+	// sessions logs users in and authorizes the routes
+	sessions *auth.Manager
 }
 
 func NewServer(port int, db *gorm.DB) *Server {
-	return &Server{
+	s := &Server{
 		mux:  mux.NewRouter(),
 		port: port,
 		db:   db,
 	}
+	// Create server with timeouts
+	s.srv = &http.Server{
+		Handler:      s.mux,
+		Addr:         fmt.Sprintf(":%d", s.port),
+		WriteTimeout: 15 * time.Second,
+		ReadTimeout:  15 * time.Second,
+		TLSConfig: &tls.Config{
+			MinVersion: tls.VersionTLS12,
+		},
+	}
+	return s
+}
+
+// authPolicy is who may use which routes. Reading is public, maintaining
+// the schedule needs a scheduler and everything else that changes data, the
+// users included, needs an admin.
+var authPolicy = auth.Policy{
+	Rules: []auth.Rule{
+		{Prefix: "/login"},
+		{Prefix: "/logout"},
+		{Prefix: "/contact"},
+		{Prefix: apiRoot + "/session"},
+		{Prefix: apiRoot + "/users", Role: model.RoleAdmin, All: true},
+		{Prefix: "/event", Role: model.RoleScheduler},
+		{Prefix: "/eventrating", Role: model.RoleScheduler},
+		{Prefix: "/eventgenre", Role: model.RoleScheduler},
+		{Prefix: "/schedulerule", Role: model.RoleScheduler},
+		{Prefix: "/schedule", Role: model.RoleScheduler},
+		{Prefix: "/import", Role: model.RoleScheduler},
+		{Prefix: apiRoot + "/events", Role: model.RoleScheduler},
+		{Prefix: apiRoot + "/eventratings", Role: model.RoleScheduler},
+		{Prefix: apiRoot + "/eventgenres", Role: model.RoleScheduler},
+		{Prefix: apiRoot + "/schedulerules", Role: model.RoleScheduler},
+		{Prefix: apiRoot + "/schedule", Role: model.RoleScheduler},
+		{Prefix: apiRoot + "/import", Role: model.RoleScheduler},
+	},
+	Default: model.RoleAdmin,
 }
 
 func (s *Server) setupRoutes() {
 
+	// Every route is authorized, see authPolicy
+	s.mux.Use(s.sessions.Middleware(authPolicy))
+
 	// HTML Routes index
 	s.mux.HandleFunc("/", indexHandler)
 	s.mux.HandleFunc("/contact", contactHandler)
 
+	// Login routes
+	authHandler := controller.NewAuthHandler(s.db, s.sessions)
+	s.mux.HandleFunc("/login", authHandler.GetLoginHTML).Methods("GET")
+	s.mux.HandleFunc("/login", authHandler.PostLoginHTML).Methods("POST")
+	s.mux.HandleFunc("/logout", authHandler.Logout).Methods("POST")
+
+	// Versioned API routes, before the unversioned /api routes
+	api.Mount(s.mux.PathPrefix(apiRoot).Subrouter(), apiRoot, apiInfo, s.apiRoutes())
+
+	// EPG routes
+	epgHandler := controller.NewEPGHandler(s.db)
+	s.mux.HandleFunc("/epg", epgHandler.GetEPGHTML).Methods("GET")
+
 	// Network routes
 	networkHandler := controller.NewNetworkHandler(s.db)
 	s.mux.HandleFunc("/network", networkHandler.GetAllNetworksHTML).Methods("GET")
 	s.mux.HandleFunc("/network/{networkId}", networkHandler.GetNetworkByIdHTML).Methods("GET")
+	s.mux.HandleFunc("/api/networks", networkHandler.GetAllNetworks).Methods("GET")
+	s.mux.HandleFunc("/api/networks/{networkId}", networkHandler.GetNetworkById).Methods("GET")
+	s.mux.HandleFunc("/api/networks", networkHandler.CreateNetwork).Methods("POST")
+	s.mux.HandleFunc("/api/networks/{networkId}", networkHandler.UpdateNetwork).Methods("PUT")
+	s.mux.HandleFunc("/api/networks/{networkId}", networkHandler.DeleteNetwork).Methods("DELETE")
 
 	// Channels routes
 	channelHandler := controller.NewChannelHandler(s.db)
 	s.mux.HandleFunc("/channel", channelHandler.GetAllChannelsHTML).Methods("GET")
 	s.mux.HandleFunc("/channel/{channelId}", channelHandler.GetChannelByIdHTML).Methods("GET")
+	s.mux.HandleFunc("/api/channels", channelHandler.GetAllChannels).Methods("GET")
+	s.mux.HandleFunc("/api/channels/{channelId}", channelHandler.GetChannelById).Methods("GET")
+	s.mux.HandleFunc("/api/channels", channelHandler.CreateChannel).Methods("POST")
+	s.mux.HandleFunc("/api/channels/{channelId}", channelHandler.UpdateChannel).Methods("PUT")
+	s.mux.HandleFunc("/api/channels/{channelId}", channelHandler.DeleteChannel).Methods("DELETE")
 
 	// Genre routes
 	genreHandler := controller.NewGenreHandler(s.db)
@@ -111,7 +191,7 @@ func (s *Server) setupRoutes() {
 	ratingHandler := controller.NewRatingHandler(s.db)
 	s.mux.HandleFunc("/ratingsystem", ratingHandler.GetAllRatingSystems).Methods("GET")
 	s.mux.HandleFunc("/rating", ratingHandler.GetAllRatingsHTML).Methods("GET")
-	s.mux.HandleFunc("/rating/{ratingId}", ratingHandler.GetRatingSystemById).Methods("GET")
+	s.mux.HandleFunc("/rating/{ratingSystemId}", ratingHandler.GetRatingSystemById).Methods("GET")
 
 	// Rating value routes
 	ratingValueHandler := controller.NewRatingValueHandler(s.db)
@@ -135,6 +215,52 @@ func (s *Server) setupRoutes() {
 	s.mux.HandleFunc("/eventrating/{eventId}/{ratingValueId}", eventRatingHandler.UpdateEventRating).Methods("PUT")
 	s.mux.HandleFunc("/eventrating/{eventId}/{ratingValueId}", eventRatingHandler.DeleteEventRating).Methods("DELETE")
 
+	// Event genre routes
+	eventGenreHandler := controller.NewEventGenreHandler(s.db)
+	s.mux.HandleFunc("/eventgenre", eventGenreHandler.GetAllEventGenres).Methods("GET")
+	s.mux.HandleFunc("/eventgenre/{eventId}/{genreId}", eventGenreHandler.GetEventGenreById).Methods("GET")
+	s.mux.HandleFunc("/eventgenre", eventGenreHandler.CreateEventGenre).Methods("POST")
+	s.mux.HandleFunc("/eventgenre/{eventId}/{genreId}", eventGenreHandler.DeleteEventGenre).Methods("DELETE")
+
+	// Elementary stream routes
+	elementaryStreamHandler := controller.NewElementaryStreamHandler(s.db)
+	s.mux.HandleFunc("/elementarystream", elementaryStreamHandler.GetAllElementaryStreams).Methods("GET")
+	s.mux.HandleFunc("/elementarystream/{elementaryStreamId}", elementaryStreamHandler.GetElementaryStreamById).Methods("GET")
+	s.mux.HandleFunc("/elementarystream", elementaryStreamHandler.CreateElementaryStream).Methods("POST")
+	s.mux.HandleFunc("/elementarystream/{elementaryStreamId}", elementaryStreamHandler.DeleteElementaryStream).Methods("DELETE")
+
+	// Schedule rule routes
+	scheduleRuleHandler := controller.NewScheduleRuleHandler(s.db)
+	s.mux.HandleFunc("/schedulerule", scheduleRuleHandler.GetAllScheduleRules).Methods("GET")
+	s.mux.HandleFunc("/schedulerule", scheduleRuleHandler.CreateScheduleRule).Methods("POST")
+	s.mux.HandleFunc("/schedulerule/materialize", scheduleRuleHandler.MaterializeScheduleRules).Methods("POST")
+	s.mux.HandleFunc("/schedulerule/{scheduleRuleId}", scheduleRuleHandler.GetScheduleRuleById).Methods("GET")
+	s.mux.HandleFunc("/schedulerule/{scheduleRuleId}", scheduleRuleHandler.UpdateScheduleRule).Methods("PUT")
+	s.mux.HandleFunc("/schedulerule/{scheduleRuleId}", scheduleRuleHandler.DeleteScheduleRule).Methods("DELETE")
+
+	// Schedule check routes
+	scheduleHandler := controller.NewScheduleHandler(s.db)
+	s.mux.HandleFunc("/schedule/report", scheduleHandler.GetScheduleReport).Methods("GET")
+	s.mux.HandleFunc("/schedule/fill", scheduleHandler.FillScheduleGaps).Methods("POST")
+
+	// Import routes
+	importHandler := controller.NewImportHandler(s.db)
+	s.mux.HandleFunc("/import/xmltv", importHandler.ImportXMLTV).Methods("POST")
+
+	// EIT routes
+	eitHandler := controller.NewEITHandler(s.db)
+	s.mux.HandleFunc("/eit.xml", eitHandler.GetEITXML).Methods("GET")
+
+	// XMLTV routes
+	xmltvHandler := controller.NewXMLTVHandler(s.db)
+	s.mux.HandleFunc("/xmltv.xml", xmltvHandler.GetXMLTV).Methods("GET")
+
+	// Now/next routes
+	nowNextHandler := controller.NewNowNextHandler(s.db, s.broker)
+	s.mux.HandleFunc("/api/now", nowNextHandler.GetNow).Methods("GET")
+	s.mux.HandleFunc("/api/channel/{channelId}/now-next", nowNextHandler.GetChannelNowNext).Methods("GET")
+	s.mux.HandleFunc("/events/stream", nowNextHandler.StreamNowNext).Methods("GET")
+
 	// Serve static files
 	staticDir := http.Dir("./static")
 	s.mux.PathPrefix("/static/").Handler(http.StripPrefix("/static/", http.FileServer(staticDir)))
@@ -200,31 +326,42 @@ func contactHandler(w http.ResponseWriter, r *http.Request) {
 	} else {
 		// Render the contact form
 		data := PageData{
-			Title:   "Contact",
-			Heading: "Contact Us",
+			Title:     "Contact",
+			Heading:   "Contact Us",
+			CSRFToken: auth.CSRFToken(r),
 		}
 		controller.RenderTemplate(w, "static/html/contact.html", data)
 	}
 }
 
 func (s *Server) start() error {
-	// Create server with timeouts
-	s.srv = &http.Server{
-		Handler:      s.mux,
-		Addr:         fmt.Sprintf(":%d", s.port),
-		WriteTimeout: 15 * time.Second,
-		ReadTimeout:  15 * time.Second,
-		TLSConfig: &tls.Config{
-			MinVersion: tls.VersionTLS12,
-		},
-	}
-
 	// Start server with HTTPS
 	//return s.srv.ListenAndServeTLS("./cert/cert.pem", "./cert/key.pem")
 	return s.srv.ListenAndServe()
 }
 
 func main() {
+	// Subcommands run instead of the web server
+	if len(os.Args) > 1 {
+		var err error
+		switch os.Args[1] {
+		case "inject":
+			err = runInject(os.Args[2:])
+		case "eit-xml":
+			err = runEITXML(os.Args[2:])
+		case "import":
+			err = runImport(os.Args[2:])
+		case "user":
+			err = runUser(os.Args[2:])
+		default:
+			log.Fatalf("Unknown command %q", os.Args[1])
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	// Connect to the database
 
 	db := controller.GetDB()
@@ -232,29 +369,55 @@ func main() {
 	// Create a new server
 	server := NewServer(config.Config.BindPort, db)
 
+	// Watch for now/next changes
+	broker, err := nownext.NewBroker(db)
+	if err != nil {
+		log.Fatal(err)
+	}
+	server.broker = broker
+
+	// Log users in for the routes that need a role
+	server.sessions = auth.NewManager(db, time.Duration(config.Config.Auth.SessionHours)*time.Hour, config.Config.Auth.SecureCookies)
+
 	// Setup routes
 	server.setupRoutes()
-	/*
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Keep the events materialised out to the horizon
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		broker.Run(ctx)
+	}()
+	if cfg := config.Config.Scheduler; !cfg.Disabled {
+		scheduler := schedule.NewScheduler(db, cfg.HorizonDays, time.Duration(cfg.IntervalMinutes)*time.Minute, cfg.RetentionDays)
+		wg.Add(1)
 		go func() {
-			for {
-				// Wait until 0:00 the next day
-				now := time.Now()
-				nextDay := now.Add(24 * time.Hour)
-				nextDay = time.Date(nextDay.Year(), nextDay.Month(), nextDay.Day(), 0, 0, 0, 0, nextDay.Location())
-				time.Sleep(nextDay.Sub(now))
-
-				// Populate the database with initial events
-				err := (&Event{}).PopulateInitialEvents(dbConn, []uint{1, 2}, nextDay)
-				if err != nil {
-					log.Println(err)
-				}
-			}
+			defer wg.Done()
+			scheduler.Run(ctx)
 		}()
-	*/
+	}
+
+	// Stop the server on interrupt, letting requests in flight finish
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := server.srv.Shutdown(shutdownCtx); err != nil {
+			log.Println(err)
+		}
+	}()
+
 	// Start the server
 	log.Println("Listening: port = " + strconv.Itoa(server.port))
-	log.Fatal(server.start())
+	if err := server.start(); !errors.Is(err, http.ErrServerClosed) {
+		log.Fatal(err)
+	}
 	log.Println("Shutting down...")
+	wg.Wait()
 }
 
 // Send an email using a mail server
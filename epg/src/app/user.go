@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"epg/src/auth"
+	"epg/src/controller"
+	"epg/src/model"
+)
+
+// runUser implements "epg user <command>", which manages the users from the
+// shell, needed to create the first admin.
+func runUser(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: user add|passwd|list [flags]")
+	}
+	switch args[0] {
+	case "add":
+		return runUserAdd(args[1:])
+	case "passwd":
+		return runUserPasswd(args[1:])
+	case "list":
+		return runUserList(args[1:])
+	}
+	return fmt.Errorf("unknown user command %q", args[0])
+}
+
+// runUserAdd implements "epg user add [-role role] <username>".
+func runUserAdd(args []string) error {
+	flags := flag.NewFlagSet("user add", flag.ExitOnError)
+	role := flags.String("role", model.RoleViewer, "role: viewer, scheduler or admin")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		return errors.New("usage: user add [-role role] <username>")
+	}
+	if !model.ValidRole(*role) {
+		return fmt.Errorf("unknown role %q", *role)
+	}
+
+	password, err := readPassword()
+	if err != nil {
+		return err
+	}
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	user := model.User{Username: flags.Arg(0), PasswordHash: hash, Role: *role}
+	if err := controller.GetDB().Create(&user).Error; err != nil {
+		return err
+	}
+	fmt.Printf("Added %s user %s\n", user.Role, user.Username)
+	return nil
+}
+
+// runUserPasswd implements "epg user passwd <username>", which also logs the
+// user out everywhere.
+func runUserPasswd(args []string) error {
+	flags := flag.NewFlagSet("user passwd", flag.ExitOnError)
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		return errors.New("usage: user passwd <username>")
+	}
+
+	db := controller.GetDB()
+	var user model.User
+	if err := db.Where("username = ?", flags.Arg(0)).First(&user).Error; err != nil {
+		return fmt.Errorf("user %s: %w", flags.Arg(0), err)
+	}
+	password, err := readPassword()
+	if err != nil {
+		return err
+	}
+	if user.PasswordHash, err = auth.HashPassword(password); err != nil {
+		return err
+	}
+	if err := db.Save(&user).Error; err != nil {
+		return err
+	}
+	if err := auth.EndSessions(db, user.UserID); err != nil {
+		return err
+	}
+	fmt.Printf("Changed the password of %s\n", user.Username)
+	return nil
+}
+
+// runUserList implements "epg user list".
+func runUserList(args []string) error {
+	flags := flag.NewFlagSet("user list", flag.ExitOnError)
+	flags.Parse(args)
+
+	var users []model.User
+	if err := controller.GetDB().Order("username").Find(&users).Error; err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tUSERNAME\tROLE\tDISABLED")
+	for _, user := range users {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%t\n", user.UserID, user.Username, user.Role, user.Disabled)
+	}
+	return tw.Flush()
+}
+
+// readPassword returns the password in $EPG_PASSWORD, or the first line of
+// stdin, so scripts need not put it on the command line.
+func readPassword() (string, error) {
+	if password := os.Getenv("EPG_PASSWORD"); password != "" {
+		return password, nil
+	}
+	fmt.Fprint(os.Stderr, "Password: ")
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("reading password: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
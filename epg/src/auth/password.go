@@ -0,0 +1,58 @@
+// Password hashing of the users
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// MinPasswordLength is the shortest password accepted. bcrypt only reads
+// the first MaxPasswordLength bytes, longer ones are refused rather than cut.
+const (
+	MinPasswordLength = 8
+	MaxPasswordLength = 72
+)
+
+// HashPassword returns the bcrypt hash of password.
+func HashPassword(password string) (string, error) {
+	if err := CheckPasswordLength(password); err != nil {
+		return "", err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return string(hash), err
+}
+
+// CheckPasswordLength reports a password bcrypt would not take whole, or too
+// short to be safe.
+func CheckPasswordLength(password string) error {
+	if len(password) < MinPasswordLength || len(password) > MaxPasswordLength {
+		return fmt.Errorf("password must be %d to %d bytes", MinPasswordLength, MaxPasswordLength)
+	}
+	return nil
+}
+
+// CheckPassword reports whether password is the one of hash.
+func CheckPassword(hash, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+var (
+	dummyOnce sync.Once
+	dummyHash string
+)
+
+// checkNoPassword takes as long as CheckPassword, so failed logins do not
+// tell unknown users from wrong passwords.
+func checkNoPassword(password string) {
+	dummyOnce.Do(func() {
+		hash, err := bcrypt.GenerateFromPassword([]byte("no such user"), bcrypt.DefaultCost)
+		if err != nil {
+			panic(errors.Join(errors.New("auth: dummy hash"), err))
+		}
+		dummyHash = string(hash)
+	})
+	CheckPassword(dummyHash, password)
+}
@@ -0,0 +1,114 @@
+// Authorization of the routes by role
+package auth
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"epg/src/api"
+)
+
+// Rule sets the role needed on the paths under Prefix.
+type Rule struct {
+	// Prefix matches a path and the paths below it, "/event" matches
+	// "/event/1" but not "/eventrating".
+	Prefix string
+	// Role is the least role needed, "" for anyone.
+	Role string
+	// All applies the rule to reading methods too, otherwise it only covers
+	// the mutating ones.
+	All bool
+}
+
+// Policy says who may do what. The first rule matching a request decides;
+// mutating requests no rule matches need Default, reading ones are public.
+type Policy struct {
+	Rules   []Rule
+	Default string
+}
+
+// role returns the role a request needs, "" for anyone.
+func (p Policy) role(r *http.Request) string {
+	unsafe := !safeMethod(r.Method)
+	for _, rule := range p.Rules {
+		if (rule.All || unsafe) && underPrefix(r.URL.Path, rule.Prefix) {
+			return rule.Role
+		}
+	}
+	if unsafe {
+		return p.Default
+	}
+	return ""
+}
+
+// Middleware finds the user of each request and refuses it with 401 or 403
+// when the policy needs a role the user lacks, or with 403 when a request
+// that could be forged across sites does not send back its CSRF token.
+func (m *Manager) Middleware(policy Policy) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, err := m.authenticate(r)
+			if err != nil {
+				api.Error(w, err)
+				return
+			}
+			token, err := m.csrfToken(w, r)
+			if err != nil {
+				api.Error(w, err)
+				return
+			}
+			r = withRequestAuth(r, user, token)
+
+			if role := policy.role(r); role != "" {
+				if user == nil {
+					api.Error(w, api.Errorf(http.StatusUnauthorized, "login required"))
+					return
+				}
+				if !user.Can(role) {
+					api.Error(w, api.Errorf(http.StatusForbidden, "%s role required", role))
+					return
+				}
+			}
+			if !safeMethod(r.Method) && forgeable(r, user != nil) && !checkCSRF(r, token) {
+				api.Error(w, api.Errorf(http.StatusForbidden, "missing or wrong CSRF token"))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// forgeable reports whether another site could make a browser send the
+// request: any request riding on a login cookie, and the posts of HTML forms
+// even without one. JSON and other methods need a CORS preflight first.
+func forgeable(r *http.Request, loggedIn bool) bool {
+	if loggedIn {
+		return true
+	}
+	if r.Method != http.MethodPost {
+		return false
+	}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "", "application/x-www-form-urlencoded", "multipart/form-data", "text/plain":
+		return true
+	}
+	return false
+}
+
+// safeMethod reports whether method only reads.
+func safeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// underPrefix reports whether path is prefix or below it.
+func underPrefix(path, prefix string) bool {
+	return path == prefix || strings.HasPrefix(path, strings.TrimSuffix(prefix, "/")+"/")
+}
@@ -0,0 +1,218 @@
+// Login sessions and CSRF tokens carried in cookies
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"mime"
+	"net/http"
+	"time"
+
+	"gorm.io/gorm"
+
+	"epg/src/api"
+	"epg/src/model"
+)
+
+// Cookie and field names of the session and the CSRF token.
+const (
+	SessionCookie = "epg_session"
+	CSRFCookie    = "epg_csrf"
+	// CSRFField is the form field HTML forms send the token in.
+	CSRFField = "csrf_token"
+	// CSRFHeader is the header other clients send the token in.
+	CSRFHeader = "X-CSRF-Token"
+)
+
+// DefaultSessionHours is how long a login lasts when the configuration
+// does not say.
+const DefaultSessionHours = 12
+
+// ErrInvalidLogin refuses a login without telling whether the user or the
+// password was wrong.
+var ErrInvalidLogin = api.Errorf(http.StatusUnauthorized, "invalid username or password")
+
+// Manager logs users in and out and finds the user of a request.
+type Manager struct {
+	db       *gorm.DB
+	lifetime time.Duration
+	// secure marks the cookies Secure even on plain HTTP, for servers behind
+	// a TLS terminating proxy.
+	secure bool
+}
+
+// NewManager returns a Manager whose logins last lifetime.
+func NewManager(db *gorm.DB, lifetime time.Duration, secure bool) *Manager {
+	if lifetime <= 0 {
+		lifetime = DefaultSessionHours * time.Hour
+	}
+	return &Manager{db: db, lifetime: lifetime, secure: secure}
+}
+
+// Login checks the password of username and starts a session, setting its
+// cookie. It returns the user and the CSRF token, renewed with the login.
+func (m *Manager) Login(w http.ResponseWriter, r *http.Request, username, password string) (*model.User, string, error) {
+	var user model.User
+	if err := m.db.Where("username = ?", username).First(&user).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, "", err
+		}
+		checkNoPassword(password)
+		return nil, "", ErrInvalidLogin
+	}
+	if !CheckPassword(user.PasswordHash, password) || user.Disabled {
+		return nil, "", ErrInvalidLogin
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return nil, "", err
+	}
+	now := time.Now().UTC()
+	session := model.Session{SessionID: hashToken(token), UserID: user.UserID, ExpiresAt: now.Add(m.lifetime)}
+	if err := m.db.Create(&session).Error; err != nil {
+		return nil, "", err
+	}
+	// Logins are rare enough to clear out the expired sessions.
+	if err := m.db.Where("expires_at <= ?", now).Delete(&model.Session{}).Error; err != nil {
+		return nil, "", err
+	}
+
+	http.SetCookie(w, m.cookie(r, SessionCookie, token, int(m.lifetime/time.Second), true))
+	csrf, err := m.renewCSRF(w, r)
+	if err != nil {
+		return nil, "", err
+	}
+	return &user, csrf, nil
+}
+
+// Logout ends the session of the request and clears its cookie.
+func (m *Manager) Logout(w http.ResponseWriter, r *http.Request) error {
+	if cookie, err := r.Cookie(SessionCookie); err == nil {
+		if err := m.db.Where("session_id = ?", hashToken(cookie.Value)).Delete(&model.Session{}).Error; err != nil {
+			return err
+		}
+	}
+	http.SetCookie(w, m.cookie(r, SessionCookie, "", -1, true))
+	_, err := m.renewCSRF(w, r)
+	return err
+}
+
+// EndSessions logs a user out everywhere, after the password changed or the
+// user was disabled or deleted.
+func EndSessions(db *gorm.DB, userID uint) error {
+	return db.Where("user_id = ?", userID).Delete(&model.Session{}).Error
+}
+
+// authenticate returns the user of the request's session cookie, nil when
+// there is no live session.
+func (m *Manager) authenticate(r *http.Request) (*model.User, error) {
+	cookie, err := r.Cookie(SessionCookie)
+	if err != nil {
+		return nil, nil
+	}
+	var session model.Session
+	err = m.db.Preload("User").
+		Where("session_id = ? AND expires_at > ?", hashToken(cookie.Value), time.Now().UTC()).
+		Limit(1).Find(&session).Error
+	if err != nil {
+		return nil, err
+	}
+	if session.SessionID == "" || session.User.Disabled {
+		return nil, nil
+	}
+	return &session.User, nil
+}
+
+// csrfToken returns the CSRF token of the request's cookie, setting a new
+// cookie when there is none.
+func (m *Manager) csrfToken(w http.ResponseWriter, r *http.Request) (string, error) {
+	if cookie, err := r.Cookie(CSRFCookie); err == nil && cookie.Value != "" {
+		return cookie.Value, nil
+	}
+	return m.renewCSRF(w, r)
+}
+
+// renewCSRF sets a new CSRF token cookie and returns the token.
+func (m *Manager) renewCSRF(w http.ResponseWriter, r *http.Request) (string, error) {
+	token, err := newToken()
+	if err != nil {
+		return "", err
+	}
+	// Scripts may read the token to send it in the header.
+	http.SetCookie(w, m.cookie(r, CSRFCookie, token, 0, false))
+	return token, nil
+}
+
+// cookie returns a cookie of the whole site, Secure when configured or
+// served over TLS.
+func (m *Manager) cookie(r *http.Request, name, value string, maxAge int, httpOnly bool) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: httpOnly,
+		Secure:   m.secure || r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// checkCSRF reports whether the request sends back the token of its cookie,
+// in the header or the field of a URL encoded form. Multipart bodies are left
+// for the handlers to read within their limits, they send the header.
+func checkCSRF(r *http.Request, token string) bool {
+	sent := r.Header.Get(CSRFHeader)
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); sent == "" && mediaType == "application/x-www-form-urlencoded" {
+		sent = r.PostFormValue(CSRFField)
+	}
+	return sent != "" && subtle.ConstantTimeCompare([]byte(sent), []byte(token)) == 1
+}
+
+// newToken returns a random token for a cookie.
+func newToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken returns the session ID of a session token, so the database
+// does not hold tokens that could be replayed.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+type contextKey int
+
+const (
+	userKey contextKey = iota
+	csrfKey
+)
+
+// UserOf returns the logged in user of a request, nil for anonymous ones.
+func UserOf(r *http.Request) *model.User {
+	user, _ := r.Context().Value(userKey).(*model.User)
+	return user
+}
+
+// CSRFToken returns the CSRF token HTML forms of the request's page embed
+// in the csrf_token field.
+func CSRFToken(r *http.Request) string {
+	token, _ := r.Context().Value(csrfKey).(string)
+	return token
+}
+
+// withRequestAuth returns the request carrying its user and CSRF token.
+func withRequestAuth(r *http.Request, user *model.User, csrf string) *http.Request {
+	ctx := context.WithValue(r.Context(), userKey, user)
+	ctx = context.WithValue(ctx, csrfKey, csrf)
+	return r.WithContext(ctx)
+}
@@ -3,6 +3,7 @@ package epg
 import (
 	"encoding/json"
 	"os"
+	"testing"
 	"time"
 )
 
@@ -16,16 +17,55 @@ type NetworkConfig struct {
 	CridDescription string    `json:"crid_description"`
 }
 
+type ElementaryStreamConfig struct {
+	Pid        uint   `json:"pid"`
+	StreamType uint8  `json:"stream_type"`
+	Kind       string `json:"kind"`
+	Language   string `json:"language"`
+}
+
 type ChannelConfig struct {
-	Description         string    `json:"description"`
-	BroadcastStartTime  time.Time `json:"broadcast_start_time"`
-	BroadcastFinishTime time.Time `json:"broadcast_finish_time"`
-	ServiceID           uint      `json:"service_id"`
-	ServiceVPid         uint      `json:"service_vpid"`
-	ServiceAPid         uint      `json:"service_apid"`
-	AuthorityMeta       string    `json:"authority_meta"`
-	LogoName            string    `json:"logo_name"`
-	NetworkID           uint      `json:"network_id"`
+	Description          string                   `json:"description"`
+	BroadcastStartTime   time.Time                `json:"broadcast_start_time"`
+	BroadcastFinishTime  time.Time                `json:"broadcast_finish_time"`
+	ServiceID            uint                     `json:"service_id"`
+	LogicalChannelNumber uint                     `json:"logical_channel_number"`
+	ServiceVPid          uint                     `json:"service_vpid"`
+	ServiceAPid          uint                     `json:"service_apid"`
+	PmtPid               uint                     `json:"pmt_pid"`
+	PcrPid               uint                     `json:"pcr_pid"`
+	VideoStreamType      uint8                    `json:"video_stream_type"`
+	AudioStreamType      uint8                    `json:"audio_stream_type"`
+	ElementaryStreams    []ElementaryStreamConfig `json:"elementary_streams"`
+	AuthorityMeta        string                   `json:"authority_meta"`
+	LogoName             string                   `json:"logo_name"`
+	NetworkID            uint                     `json:"network_id"`
+}
+
+// SchedulerConfig sets up the background upkeep of the events table, zero
+// values take the defaults of the schedule package.
+type SchedulerConfig struct {
+	Disabled        bool `json:"disabled"`
+	HorizonDays     int  `json:"horizon_days"`
+	IntervalMinutes int  `json:"interval_minutes"`
+	RetentionDays   int  `json:"retention_days"`
+}
+
+// FillerConfig is the event put in the gaps of a channel's schedule.
+type FillerConfig struct {
+	Title         string `json:"title"`
+	Description   string `json:"description"`
+	GenreID       uint   `json:"genre_id"`
+	CategoryID    uint   `json:"category_id"`
+	MinGapMinutes int    `json:"min_gap_minutes"`
+}
+
+// AuthConfig sets up the logins of the users, zero values take the defaults
+// of the auth package. SecureCookies marks the cookies Secure when the
+// server is behind a TLS proxy, they always are when it serves TLS itself.
+type AuthConfig struct {
+	SessionHours  int  `json:"session_hours"`
+	SecureCookies bool `json:"secure_cookies"`
 }
 
 var Config struct {
@@ -35,11 +75,20 @@ var Config struct {
 	LoadBalance bool            `json:"load_balance"`
 	Network     []NetworkConfig `json:"network"`
 	Channels    []ChannelConfig `json:"channels"`
+	// XMLTVChannels maps XMLTV channel ids to channel IDs for imports.
+	XMLTVChannels map[string]uint `json:"xmltv_channels"`
+	Scheduler     SchedulerConfig `json:"scheduler"`
+	Filler        FillerConfig    `json:"filler"`
+	Auth          AuthConfig      `json:"auth"`
 }
 
 func init() {
 	cfgJson, err := os.ReadFile("src/config/config.json")
 	if err != nil {
+		// Tests run in their package directory and go without a configuration.
+		if testing.Testing() {
+			return
+		}
 		panic(err)
 	}
 
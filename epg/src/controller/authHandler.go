@@ -0,0 +1,134 @@
+// authHandler.go
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"epg/src/api"
+	"epg/src/auth"
+	"epg/src/model"
+
+	"gorm.io/gorm"
+)
+
+// AuthHandler logs users in and out, from the login page or the API.
+type AuthHandler struct {
+	db      *gorm.DB
+	manager *auth.Manager
+}
+
+// NewAuthHandler ...
+func NewAuthHandler(db *gorm.DB, manager *auth.Manager) *AuthHandler {
+	return &AuthHandler{db: db, manager: manager}
+}
+
+// LoginRequest is the body of POST /api/v1/session.
+type LoginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// SessionResponse is the user of a session and the CSRF token its mutating
+// requests send in the X-CSRF-Token header.
+type SessionResponse struct {
+	User      *model.User `json:"user"`
+	CSRFToken string      `json:"csrfToken"`
+}
+
+// loginPage is the data of the login template.
+type loginPage struct {
+	Title     string
+	Heading   string
+	User      *model.User
+	CSRFToken string
+	Message   string
+	// Next is where to go once logged in.
+	Next string
+}
+
+// GetLoginHTML handler function for GET method, the login form or, when
+// logged in, who is.
+func (ah *AuthHandler) GetLoginHTML(w http.ResponseWriter, r *http.Request) {
+	ah.renderLogin(w, r, http.StatusOK, "")
+}
+
+// PostLoginHTML handler function for POST method, logs in from the form and
+// goes on to its next page.
+func (ah *AuthHandler) PostLoginHTML(w http.ResponseWriter, r *http.Request) {
+	_, _, err := ah.manager.Login(w, r, r.PostFormValue("username"), r.PostFormValue("password"))
+	if err != nil {
+		if !errors.Is(err, auth.ErrInvalidLogin) {
+			HandleHtmlError(w, err)
+			return
+		}
+		ah.renderLogin(w, r, http.StatusUnauthorized, "Invalid username or password.")
+		return
+	}
+	http.Redirect(w, r, localPath(r.PostFormValue("next")), http.StatusSeeOther)
+}
+
+// Logout handler function for POST method, logs out from the form.
+func (ah *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	if err := ah.manager.Logout(w, r); err != nil {
+		HandleHtmlError(w, err)
+		return
+	}
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
+
+// renderLogin renders the login page with status.
+func (ah *AuthHandler) renderLogin(w http.ResponseWriter, r *http.Request, status int, message string) {
+	data := loginPage{
+		Title:     "Login",
+		Heading:   "Login",
+		User:      auth.UserOf(r),
+		CSRFToken: auth.CSRFToken(r),
+		Message:   message,
+		Next:      localPath(r.FormValue("next")),
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	RenderTemplate(w, "static/html/login.html", data)
+}
+
+// GetSession handler function for GET method, the logged in user, null for
+// anonymous requests, and the CSRF token.
+func (ah *AuthHandler) GetSession(w http.ResponseWriter, r *http.Request) {
+	api.JSON(w, SessionResponse{User: auth.UserOf(r), CSRFToken: auth.CSRFToken(r)})
+}
+
+// CreateSession handler function for POST method, logs in.
+func (ah *AuthHandler) CreateSession(w http.ResponseWriter, r *http.Request) {
+	login := &LoginRequest{}
+	if err := json.NewDecoder(r.Body).Decode(login); err != nil {
+		api.Error(w, err)
+		return
+	}
+	user, csrf, err := ah.manager.Login(w, r, login.Username, login.Password)
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	api.Created(w, SessionResponse{User: user, CSRFToken: csrf})
+}
+
+// DeleteSession handler function for DELETE method, logs out.
+func (ah *AuthHandler) DeleteSession(w http.ResponseWriter, r *http.Request) {
+	if err := ah.manager.Logout(w, r); err != nil {
+		api.Error(w, err)
+		return
+	}
+	api.JSON(w, map[string]string{"message": "Logged out successfully"})
+}
+
+// localPath returns next when it is a path of this site, "/" otherwise, so
+// the login form does not redirect to other sites.
+func localPath(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, `/\`) {
+		return "/"
+	}
+	return next
+}
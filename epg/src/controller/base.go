@@ -8,12 +8,15 @@ When you call controller.GetDB() in your main function, it returns the already e
 package controller
 
 import (
+	"errors"
 	"fmt"
 	"html/template"
 	"log"
 	"net/http"
 	"os"
+	"time"
 
+	"epg/src/api"
 	config "epg/src/config"
 	"epg/src/model"
 
@@ -48,108 +51,223 @@ func checkDatabaseExists(dbPath string) bool {
 
 /*
 Each time init() is run we check we have a existing database (assuming nothing has been changed)
-If happens the database is deleted, init() creates a new database and Automigrate the models and populate
-each table with the relavent values for model csv files.
+If happens the database is deleted, init() creates a new database and populate each table with the
+relavent values for model csv files. The models are Automigrated on every start so tables and columns
+added since an existing database was created are picked up.
 */
 func init() {
 	dbPath := fmt.Sprintf("./bin/%s", config.Config.Dbname)
 	var err error
 	switch config.Config.DbType {
 	case "sqlite3":
-		if !checkDatabaseExists(dbPath) {
+		newDatabase := !checkDatabaseExists(dbPath)
+		if newDatabase {
 			log.Println("Database file does not exist. Creating...")
 			// Create the database file
 			f, err := os.Create(dbPath)
 			if err != nil {
 				log.Fatal(err)
 			}
-			defer f.Close()
-			// Open the database and run migration
-			dbConn, err = gorm.Open(sqlite.Open(dbPath), &gorm.Config{})
-			if err != nil {
-				log.Fatal(err)
-			}
-			// Run migration
-			err = dbConn.AutoMigrate(
-				&model.Country{},
-				&model.Timezone{},
-				&model.GenreColor{},
-				&model.Genre{},
-				&model.Category{},
-				&model.RatingSystem{},
-				&model.RatingValue{},
-				&model.Network{},
-				&model.Channel{},
-				&model.Event{},
-				&model.EventRating{},
-			)
-			if err != nil {
+			f.Close()
+		}
+		// Open the database and run migration
+		dbConn, err = gorm.Open(sqlite.Open(dbPath), &gorm.Config{})
+		if err != nil {
+			log.Fatal(err)
+		}
+		// Channels of databases from before logical channel numbers were
+		// added are numbered once the column is there.
+		numbered := newDatabase || dbConn.Migrator().HasColumn(&model.Channel{}, "logical_channel_number")
+		err = dbConn.AutoMigrate(
+			&model.Country{},
+			&model.Timezone{},
+			&model.GenreColor{},
+			&model.Genre{},
+			&model.Category{},
+			&model.RatingSystem{},
+			&model.RatingValue{},
+			&model.Network{},
+			&model.Channel{},
+			&model.Event{},
+			&model.EventRating{},
+			&model.EventGenre{},
+			&model.ElementaryStream{},
+			&model.ScheduleRule{},
+			&model.User{},
+			&model.Session{},
+		)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err = migrateEventTimes(dbConn); err != nil {
+			log.Fatal(err)
+		}
+		if err = migrateDVBEventIDs(dbConn); err != nil {
+			log.Fatal(err)
+		}
+		if !numbered {
+			if err = migrateLogicalChannelNumbers(dbConn); err != nil {
 				log.Fatal(err)
 			}
+		}
+		if newDatabase {
+			populateDatabase(dbConn)
+		}
+	default:
+		log.Fatal("Unsupported database type")
+	}
+}
 
-			// Populate the countries table
-			country := &model.Country{}
-			err = country.LoadFromCSV(dbConn, "countries.csv")
+// migrateEventTimes rewrites in UTC the event times stored with another
+// offset before model.Event.BeforeSave normalised them, so they compare
+// as text with the UTC bounds of the queries.
+func migrateEventTimes(db *gorm.DB) error {
+	var rows []struct {
+		EventID   uint
+		StartTime time.Time
+		EndTime   time.Time
+	}
+	err := db.Model(&model.Event{}).Unscoped().Select("event_id, start_time, end_time").
+		Where("start_time NOT LIKE '____-__-__ __:__:__%+00:00' OR end_time NOT LIKE '____-__-__ __:__:__%+00:00'").
+		Find(&rows).Error
+	if err != nil || len(rows) == 0 {
+		return err
+	}
+	log.Printf("Converting the times of %d events to UTC", len(rows))
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, row := range rows {
+			err := tx.Model(&model.Event{}).Unscoped().Where("event_id = ?", row.EventID).
+				UpdateColumns(map[string]interface{}{"start_time": row.StartTime.UTC(), "end_time": row.EndTime.UTC()}).Error
 			if err != nil {
-				log.Fatal(err)
+				return err
 			}
-			// Populate the timezone table
-			timezone := &model.Timezone{}
-			err = timezone.LoadFromCSV(dbConn, "timezones.csv")
-			if err != nil {
-				log.Fatal(err)
+		}
+		return nil
+	})
+}
+
+// migrateDVBEventIDs gives the events stored before they carried a DVB
+// event_id one, in start time order on each channel.
+func migrateDVBEventIDs(db *gorm.DB) error {
+	var events []model.Event
+	err := db.Select("event_id, channel_id, start_time, end_time").Where("dvb_event_id = 0").
+		Order("channel_id, start_time").Find(&events).Error
+	if err != nil || len(events) == 0 {
+		return err
+	}
+	log.Printf("Allocating DVB event_ids to %d events", len(events))
+	now := time.Now()
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, event := range events {
+			if err := event.AssignDVBEventID(tx, now); err != nil {
+				return err
 			}
-			// Populate the genre colors table
-			genrecolor := &model.GenreColor{}
-			err = genrecolor.LoadFromCSV(dbConn, "color.csv")
+			err := tx.Model(&model.Event{}).Where("event_id = ?", event.EventID).
+				UpdateColumn("dvb_event_id", event.DVBEventID).Error
 			if err != nil {
-				log.Fatal(err)
+				return err
 			}
+		}
+		return nil
+	})
+}
 
-			genre := &model.Genre{}
-			err = genre.LoadFromCSV(dbConn, "genre.csv")
-			if err != nil {
-				log.Fatal(err)
-			}
+// migrateLogicalChannelNumbers numbers the channels of a database the
+// logical_channel_number column was just added to. A channel takes the number
+// config.json gives its service_id in its network or, when that has none or
+// another channel has it, the first free number from its order in the network.
+func migrateLogicalChannelNumbers(db *gorm.DB) error {
+	var channels []model.Channel
+	if err := db.Order("network_id, channel_id").Find(&channels).Error; err != nil {
+		return err
+	}
+	configured := map[[2]uint]uint{}
+	for _, c := range config.Config.Channels {
+		configured[[2]uint{c.NetworkID, c.ServiceID}] = c.LogicalChannelNumber
+	}
+	taken := map[[2]uint]bool{}
+	for _, channel := range channels {
+		taken[[2]uint{channel.NetworkID, channel.LogicalChannelNumber}] = channel.LogicalChannelNumber != 0
+	}
 
-			category := &model.Category{}
-			err = category.LoadFromCSV(dbConn, "categories.csv")
-			if err != nil {
-				log.Fatal(err)
+	log.Printf("Numbering %d channels", len(channels))
+	return db.Transaction(func(tx *gorm.DB) error {
+		order := map[uint]uint{}
+		for _, channel := range channels {
+			order[channel.NetworkID]++
+			if channel.LogicalChannelNumber != 0 {
+				continue
 			}
-
-			ratingvalue := &model.RatingValue{}
-			err = ratingvalue.LoadFromCSV(dbConn, "ratings.csv")
-			if err != nil {
-				log.Fatal(err)
+			lcn := configured[[2]uint{channel.NetworkID, channel.ServiceID}]
+			if lcn == 0 || taken[[2]uint{channel.NetworkID, lcn}] {
+				for lcn = order[channel.NetworkID]; taken[[2]uint{channel.NetworkID, lcn}]; lcn++ {
+				}
 			}
-			ratingsystem := &model.RatingSystem{}
-			err = ratingsystem.LoadFromCSV(dbConn, "ratingsystems.csv")
+			taken[[2]uint{channel.NetworkID, lcn}] = true
+			err := tx.Model(&model.Channel{}).Where("channel_id = ?", channel.ChannelID).
+				UpdateColumn("logical_channel_number", lcn).Error
 			if err != nil {
-				log.Fatal(err)
+				return err
 			}
+		}
+		return nil
+	})
+}
 
-			// Add our network from config
-			err = model.PopulateInitialNetworkValues(dbConn)
-			if err != nil {
-				log.Fatal(err)
-			}
+// populateDatabase fills a newly created database from the csv files and config.
+func populateDatabase(dbConn *gorm.DB) {
+	// Populate the countries table
+	country := &model.Country{}
+	err := country.LoadFromCSV(dbConn, "countries.csv")
+	if err != nil {
+		log.Fatal(err)
+	}
+	// Populate the timezone table
+	timezone := &model.Timezone{}
+	err = timezone.LoadFromCSV(dbConn, "timezones.csv")
+	if err != nil {
+		log.Fatal(err)
+	}
+	// Populate the genre colors table
+	genrecolor := &model.GenreColor{}
+	err = genrecolor.LoadFromCSV(dbConn, "color.csv")
+	if err != nil {
+		log.Fatal(err)
+	}
 
-			// Add our channels from config
-			err = model.PopulateInitialChannelValues(dbConn)
-			if err != nil {
-				log.Fatal(err)
-			}
+	genre := &model.Genre{}
+	err = genre.LoadFromCSV(dbConn, "genre.csv")
+	if err != nil {
+		log.Fatal(err)
+	}
 
-		} else {
-			// Open the existing database
-			dbConn, err = gorm.Open(sqlite.Open(dbPath), &gorm.Config{})
-			if err != nil {
-				log.Fatal(err)
-			}
-		}
-	default:
-		log.Fatal("Unsupported database type")
+	category := &model.Category{}
+	err = category.LoadFromCSV(dbConn, "categories.csv")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ratingvalue := &model.RatingValue{}
+	err = ratingvalue.LoadFromCSV(dbConn, "ratings.csv")
+	if err != nil {
+		log.Fatal(err)
+	}
+	ratingsystem := &model.RatingSystem{}
+	err = ratingsystem.LoadFromCSV(dbConn, "ratingsystems.csv")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Add our network from config
+	err = model.PopulateInitialNetworkValues(dbConn)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Add our channels from config
+	err = model.PopulateInitialChannelValues(dbConn)
+	if err != nil {
+		log.Fatal(err)
 	}
 }
 
@@ -178,3 +296,12 @@ func RenderTemplate(w http.ResponseWriter, tmpl string, data interface{}) {
 func HandleHtmlError(w http.ResponseWriter, err error) {
 	http.Error(w, err.Error(), http.StatusInternalServerError)
 }
+
+// badReference answers a missing record that a request body refers to with
+// a 400, it is the body that is wrong rather than the path.
+func badReference(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return api.WithStatus(http.StatusBadRequest, err)
+	}
+	return err
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"strconv"
 
+	"epg/src/api"
 	"epg/src/model"
 
 	"github.com/gorilla/mux"
@@ -27,6 +28,46 @@ func NewCategoryHandler(db *gorm.DB) *CategoryHandler {
 	return &CategoryHandler{db: db}
 }
 
+// CategoryList is how GetAllCategories is paged, filtered and sorted.
+var CategoryList = api.List{
+	Filters: map[string]api.Filter{
+		"q": api.Contains("description", "Only the categories whose description contains this"),
+	},
+	Sorts: map[string]string{
+		"description": "description",
+		"id":          "category_id",
+	},
+	Sort: "id",
+	Key:  "category_id",
+}
+
+// GetAllCategories handler function for GET method, see CategoryList for
+// the parameters.
+func (ch *CategoryHandler) GetAllCategories(w http.ResponseWriter, r *http.Request) {
+	categories := []model.Category{}
+	page, err := CategoryList.Find(ch.db, r, &categories)
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	api.Paged(w, r, page, categories)
+}
+
+// GetCategoryById handler function for GET method
+func (ch *CategoryHandler) GetCategoryById(w http.ResponseWriter, r *http.Request) {
+	categoryId, err := strconv.ParseInt(mux.Vars(r)["categoryId"], 10, 64)
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	category := &model.Category{}
+	if err = ch.db.Where("category_id = ?", categoryId).First(category).Error; err != nil {
+		api.Error(w, err)
+		return
+	}
+	api.JSON(w, category)
+}
+
 // GetAllCategoriesHTML fetches category from the /catergory endpoint and renders the HTML page.
 func (ch *CategoryHandler) GetAllCategoriesHTML(w http.ResponseWriter, r *http.Request) {
 
@@ -2,14 +2,18 @@
 package controller
 
 import (
+	"encoding/json"
 	"errors"
 	"net/http"
 	"strconv"
 
+	"epg/src/api"
+	"epg/src/dvb/psi"
 	"epg/src/model"
 
 	"github.com/gorilla/mux"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // ChannelHandler ...
@@ -51,19 +55,20 @@ func (ch *ChannelHandler) GetAllChannelsHTML(w http.ResponseWriter, r *http.Requ
 
 		// Create a new Channel struct with the NetworkName
 		newChannel := model.Channel{
-			ChannelID:           c.ChannelID,
-			Description:         c.Description,
-			BroadcastStartTime:  c.BroadcastStartTime,
-			BroadcastFinishTime: c.BroadcastFinishTime,
-			ServiceID:           c.ServiceID,
-			ServiceVPid:         c.ServiceVPid,
-			ServiceAPid:         c.ServiceAPid,
-			AuthorityMeta:       c.AuthorityMeta,
-			LogoName:            c.LogoName,
-			NetworkID:           c.NetworkID,
-			Network:             c.Network,
-			NetworkName:         networkName,
-			Events:              c.Events,
+			ChannelID:            c.ChannelID,
+			Description:          c.Description,
+			BroadcastStartTime:   c.BroadcastStartTime,
+			BroadcastFinishTime:  c.BroadcastFinishTime,
+			ServiceID:            c.ServiceID,
+			LogicalChannelNumber: c.LogicalChannelNumber,
+			ServiceVPid:          c.ServiceVPid,
+			ServiceAPid:          c.ServiceAPid,
+			AuthorityMeta:        c.AuthorityMeta,
+			LogoName:             c.LogoName,
+			NetworkID:            c.NetworkID,
+			Network:              c.Network,
+			NetworkName:          networkName,
+			Events:               c.Events,
 		}
 
 		responses = append(responses, newChannel)
@@ -118,3 +123,247 @@ func (ch *ChannelHandler) GetChannelByIdHTML(w http.ResponseWriter, r *http.Requ
 
 	RenderTemplate(w, "static/html/channel.html", data)
 }
+
+// ChannelList is how GetAllChannels is paged, filtered, sorted and
+// preloaded. The network is always preloaded for the network name.
+var ChannelList = api.List{
+	Filters: map[string]api.Filter{
+		"network": api.Equals("network_id", "Only the channels of this network"),
+		"q":       api.Contains("description", "Only the channels whose description contains this"),
+	},
+	Sorts: map[string]string{
+		"network":     "network_id",
+		"lcn":         "logical_channel_number",
+		"description": "description",
+		"service":     "service_id",
+		"id":          "channel_id",
+	},
+	Sort: "network,lcn",
+	Key:  "channel_id",
+	Includes: map[string][]string{
+		"streams": {"ElementaryStreams"},
+	},
+	Include:  "streams",
+	Preloads: []string{"Network"},
+}
+
+// GetAllChannels handler function for GET method, see ChannelList for the
+// parameters.
+func (ch *ChannelHandler) GetAllChannels(w http.ResponseWriter, r *http.Request) {
+	channels := []model.Channel{}
+	page, err := ChannelList.Find(ch.db, r, &channels)
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	for i := range channels {
+		channels[i].NetworkName = channels[i].Network.Description
+	}
+	api.Paged(w, r, page, channels)
+}
+
+// GetChannelById handler function for GET method
+func (ch *ChannelHandler) GetChannelById(w http.ResponseWriter, r *http.Request) {
+	channel, err := ch.findChannel(r)
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	api.JSON(w, channel)
+}
+
+// CreateChannel handler function for POST method, elementaryStreams in the
+// body are created with the channel.
+func (ch *ChannelHandler) CreateChannel(w http.ResponseWriter, r *http.Request) {
+	channel := &model.Channel{}
+	err := json.NewDecoder(r.Body).Decode(channel)
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	channel.ChannelID = 0
+	for i := range channel.ElementaryStreams {
+		channel.ElementaryStreams[i].ElementaryStreamID = 0
+	}
+	if err = ch.validate(channel); err != nil {
+		api.Error(w, err)
+		return
+	}
+	err = ch.db.Omit("Network", "Events").Create(channel).Error
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	api.Created(w, channel)
+}
+
+// UpdateChannel handler function for PUT method, the channel's elementary
+// streams are kept and edited at /elementarystream.
+func (ch *ChannelHandler) UpdateChannel(w http.ResponseWriter, r *http.Request) {
+	existing, err := ch.findChannel(r)
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	channel := &model.Channel{}
+	err = json.NewDecoder(r.Body).Decode(channel)
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	channel.ChannelID = existing.ChannelID
+	channel.ElementaryStreams = existing.ElementaryStreams
+	if err = ch.validate(channel); err != nil {
+		api.Error(w, err)
+		return
+	}
+	err = ch.db.Omit(clause.Associations).Save(channel).Error
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	api.JSON(w, channel)
+}
+
+// DeleteChannel handler function for DELETE method, the channel's events,
+// elementary streams and schedule rules are deleted with it.
+func (ch *ChannelHandler) DeleteChannel(w http.ResponseWriter, r *http.Request) {
+	channel, err := ch.findChannel(r)
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	var events int64
+	err = ch.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		events, err = model.DeleteChannels(tx, []uint{channel.ChannelID})
+		return err
+	})
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	api.JSON(w, map[string]interface{}{"message": "Channel deleted successfully", "events": events})
+}
+
+// findChannel loads the channel of the request's channelId with its
+// elementary streams.
+func (ch *ChannelHandler) findChannel(r *http.Request) (*model.Channel, error) {
+	channelId, err := strconv.ParseUint(mux.Vars(r)["channelId"], 10, 32)
+	if err != nil {
+		return nil, err
+	}
+	channel := &model.Channel{}
+	err = ch.db.Preload("Network").Preload("ElementaryStreams").First(channel, "channel_id = ?", channelId).Error
+	if err != nil {
+		return nil, err
+	}
+	channel.NetworkName = channel.Network.Description
+	return channel, nil
+}
+
+// pidUse is a PID and what a channel carries on it.
+type pidUse struct {
+	field string
+	pid   uint
+	use   string
+}
+
+// pidUses returns the PIDs a channel carries, leaving out unset ones. The PMT
+// PID defaults as in the PAT once the channel has an ID.
+func pidUses(channel *model.Channel) []pidUse {
+	uses := []pidUse{
+		{"serviceVPid", channel.ServiceVPid, "video"},
+		{"serviceAPid", channel.ServiceAPid, "audio"},
+		{"pmtPid", channel.PmtPid, "PMT"},
+		{"pcrPid", channel.PcrPid, "PCR"},
+	}
+	if channel.PmtPid == 0 && channel.ChannelID != 0 {
+		uses[2].pid = uint(psi.PMTPID(*channel))
+	}
+	for _, es := range channel.ElementaryStreams {
+		uses = append(uses, pidUse{"elementaryStreams", es.Pid, es.Kind})
+	}
+	var set []pidUse
+	for _, u := range uses {
+		if u.pid != 0 {
+			set = append(set, u)
+		}
+	}
+	return set
+}
+
+// validate checks a channel about to be stored: it needs a description, a
+// known network, a service ID no other channel of the network has, and a
+// video or audio PID. Its PIDs must be outside the reserved PIDs, distinct
+// from each other, the PCR aside, and not used by another channel of the
+// network, which is one transport stream.
+func (ch *ChannelHandler) validate(channel *model.Channel) error {
+	verr := &api.ValidationError{Resource: "channel"}
+	if channel.Description == "" {
+		verr.Add("description", api.ViolationRequired, "description is required")
+	}
+	// As the column defaults do on create.
+	if channel.VideoStreamType == 0 {
+		channel.VideoStreamType = psi.StreamTypeH264Video
+	}
+	if channel.AudioStreamType == 0 {
+		channel.AudioStreamType = psi.StreamTypeMPEG1Audio
+	}
+
+	network := model.Network{}
+	if err := ch.db.Limit(1).Find(&network, "network_id = ?", channel.NetworkID).Error; err != nil {
+		return err
+	}
+	if network.NetworkID == 0 {
+		verr.Add("networkID", api.ViolationUnknown, "network %d does not exist", channel.NetworkID)
+	}
+
+	others := []model.Channel{}
+	if network.NetworkID != 0 {
+		err := ch.db.Preload("ElementaryStreams").Where("network_id = ? AND channel_id <> ?", network.NetworkID, channel.ChannelID).Find(&others).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	if channel.ServiceID == 0 || channel.ServiceID > 0xFFFF {
+		verr.Add("serviceID", api.ViolationInvalid, "service ID %d is not in 1 to 65535", channel.ServiceID)
+	} else {
+		for _, other := range others {
+			if other.ServiceID == channel.ServiceID {
+				verr.Add("serviceID", api.ViolationDuplicate, "service ID %d is used by %s", channel.ServiceID, other.Description)
+			}
+		}
+	}
+
+	if channel.ServiceVPid == 0 && channel.ServiceAPid == 0 {
+		verr.Add("serviceAPid", api.ViolationRequired, "a video or audio PID is required")
+	}
+	uses := pidUses(channel)
+	own := map[uint]pidUse{}
+	for _, u := range uses {
+		if u.pid < 0x20 || u.pid >= psi.MaxPID {
+			verr.Add(u.field, api.ViolationInvalid, "%s PID 0x%X is not in 0x20 to 0x%X", u.use, u.pid, psi.MaxPID-1)
+			continue
+		}
+		// The PCR is usually carried in the video or audio packets.
+		if u.use != "PCR" {
+			if first, ok := own[u.pid]; ok {
+				verr.Add(u.field, api.ViolationInvalid, "%s PID 0x%X is also the %s PID", u.use, u.pid, first.use)
+				continue
+			}
+			own[u.pid] = u
+		}
+	}
+	for _, other := range others {
+		for _, o := range pidUses(&other) {
+			for _, u := range uses {
+				if u.pid == o.pid {
+					verr.Add(u.field, api.ViolationPIDConflict, "%s PID 0x%X is the %s PID of %s", u.use, u.pid, o.use, other.Description)
+				}
+			}
+		}
+	}
+	return verr.Err()
+}
@@ -3,9 +3,10 @@ package controller
 
 import (
 	"encoding/json"
-	"log"
 	"net/http"
+	"strconv"
 
+	"epg/src/api"
 	"epg/src/model"
 
 	"github.com/gorilla/mux"
@@ -27,22 +28,26 @@ func (ch *CountryHandler) GetAllCountries(w http.ResponseWriter, r *http.Request
 	countries := []model.Country{}
 	err := ch.db.Preload("Timezones").Find(&countries).Error
 	if err != nil {
-		ch.handleError(w, err)
+		api.Error(w, err)
 		return
 	}
-	ch.encodeJSONResponse(w, countries)
+	api.JSON(w, countries)
 }
 
 // GetCountryById handler function for GET method
 func (ch *CountryHandler) GetCountryById(w http.ResponseWriter, r *http.Request) {
-	countryCode := mux.Vars(r)["countryCode"]
+	countryId, err := strconv.ParseInt(mux.Vars(r)["countryId"], 10, 64)
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
 	country := &model.Country{}
-	err := ch.db.Preload("Timezones").Where("country_code = ?", countryCode).First(country).Error
+	err = ch.db.Preload("Timezones").Where("country_id = ?", countryId).First(country).Error
 	if err != nil {
-		ch.handleError(w, err)
+		api.Error(w, err)
 		return
 	}
-	ch.encodeJSONResponse(w, country)
+	api.JSON(w, country)
 }
 
 // GetCountryByCode handler function for GET method
@@ -51,10 +56,10 @@ func (ch *CountryHandler) GetCountryByCode(w http.ResponseWriter, r *http.Reques
 	countries := []model.Country{}
 	err := ch.db.Preload("Timezones").Where("country_code = ?", countryCode).Find(&countries).Error
 	if err != nil {
-		ch.handleError(w, err)
+		api.Error(w, err)
 		return
 	}
-	ch.encodeJSONResponse(w, countries)
+	api.JSON(w, countries)
 }
 
 // CreateCountry handler function for POST method
@@ -62,15 +67,15 @@ func (ch *CountryHandler) CreateCountry(w http.ResponseWriter, r *http.Request)
 	country := &model.Country{}
 	err := json.NewDecoder(r.Body).Decode(country)
 	if err != nil {
-		ch.handleError(w, err)
+		api.Error(w, err)
 		return
 	}
 	err = ch.db.Create(country).Error
 	if err != nil {
-		ch.handleError(w, err)
+		api.Error(w, err)
 		return
 	}
-	ch.encodeJSONResponse(w, country)
+	api.Created(w, country)
 }
 
 // UpdateCountry handler function for PUT method
@@ -78,48 +83,45 @@ func (ch *CountryHandler) UpdateCountry(w http.ResponseWriter, r *http.Request)
 	country := &model.Country{}
 	err := json.NewDecoder(r.Body).Decode(country)
 	if err != nil {
-		ch.handleError(w, err)
+		api.Error(w, err)
 		return
 	}
-	countryCode := mux.Vars(r)["countryCode"]
-	country.CountryCode = countryCode
+	countryId, err := strconv.ParseInt(mux.Vars(r)["countryId"], 10, 64)
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	err = ch.db.Where("country_id = ?", countryId).First(&model.Country{}).Error
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	country.CountryID = uint(countryId)
 	err = ch.db.Save(country).Error
 	if err != nil {
-		ch.handleError(w, err)
+		api.Error(w, err)
 		return
 	}
-	ch.encodeJSONResponse(w, country)
+	api.JSON(w, country)
 }
 
 // DeleteCountry handler function for DELETE method
 func (ch *CountryHandler) DeleteCountry(w http.ResponseWriter, r *http.Request) {
-	countryCode := mux.Vars(r)["countryCode"]
-	country := &model.Country{}
-	err := ch.db.Where("country_code = ?", countryCode).First(country).Error
+	countryId, err := strconv.ParseInt(mux.Vars(r)["countryId"], 10, 64)
 	if err != nil {
-		ch.handleError(w, err)
+		api.Error(w, err)
 		return
 	}
-	err = ch.db.Delete(country).Error
+	country := &model.Country{}
+	err = ch.db.Where("country_id = ?", countryId).First(country).Error
 	if err != nil {
-		ch.handleError(w, err)
+		api.Error(w, err)
 		return
 	}
-	ch.encodeJSONResponse(w, map[string]interface{}{"message": "Country deleted successfully"})
-}
-
-// handleError ...
-func (ch *CountryHandler) handleError(w http.ResponseWriter, err error) {
-	msg := map[string]interface{}{"status": false, "message": err.Error()}
-	w.Header().Add("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(msg)
-}
-
-// encodeJSONResponse ...
-func (ch *CountryHandler) encodeJSONResponse(w http.ResponseWriter, data interface{}) {
-	w.Header().Add("Content-Type", "application/json")
-	err := json.NewEncoder(w).Encode(data)
+	err = ch.db.Delete(country).Error
 	if err != nil {
-		log.Println(err)
+		api.Error(w, err)
+		return
 	}
+	api.JSON(w, map[string]interface{}{"message": "Country deleted successfully"})
 }
@@ -0,0 +1,49 @@
+// eitHandler.go
+package controller
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"time"
+
+	"epg/src/dvb/eit"
+
+	"gorm.io/gorm"
+)
+
+// defaultEITDays is the schedule length served when no days are requested.
+const defaultEITDays = 7
+
+// EITHandler serves the generated EIT to external injectors.
+type EITHandler struct {
+	db *gorm.DB
+}
+
+// NewEITHandler ...
+func NewEITHandler(db *gorm.DB) *EITHandler {
+	return &EITHandler{db: db}
+}
+
+// GetEITXML renders the current and upcoming events in TSDuck SI XML for
+// "tsp -P eitinject --files", optionally limited to ?days=N.
+func (eh *EITHandler) GetEITXML(w http.ResponseWriter, r *http.Request) {
+	days := defaultEITDays
+	if value := r.URL.Query().Get("days"); value != "" {
+		var err error
+		days, err = strconv.Atoi(value)
+		if err != nil || days < 1 || days > eit.MaxScheduleDays {
+			http.Error(w, "days must be between 1 and "+strconv.Itoa(eit.MaxScheduleDays), http.StatusBadRequest)
+			return
+		}
+	}
+
+	var buf bytes.Buffer
+	err := eit.NewGenerator(eh.db, days).WriteTSDuckXML(&buf, time.Now())
+	if err != nil {
+		HandleHtmlError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.Write(buf.Bytes())
+}
@@ -0,0 +1,88 @@
+// elementaryStreamHandler.go
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"epg/src/api"
+	"epg/src/model"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// ElementaryStreamHandler ...
+type ElementaryStreamHandler struct {
+	db *gorm.DB
+}
+
+// NewElementaryStreamHandler ...
+func NewElementaryStreamHandler(db *gorm.DB) *ElementaryStreamHandler {
+	return &ElementaryStreamHandler{db: db}
+}
+
+// GetAllElementaryStreams handler function for GET method
+func (esh *ElementaryStreamHandler) GetAllElementaryStreams(w http.ResponseWriter, r *http.Request) {
+	elementaryStreams := []model.ElementaryStream{}
+	err := esh.db.Order("channel_id, pid").Find(&elementaryStreams).Error
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	api.JSON(w, elementaryStreams)
+}
+
+// GetElementaryStreamById handler function for GET method
+func (esh *ElementaryStreamHandler) GetElementaryStreamById(w http.ResponseWriter, r *http.Request) {
+	elementaryStreamId, err := strconv.ParseInt(mux.Vars(r)["elementaryStreamId"], 10, 64)
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	elementaryStream := &model.ElementaryStream{}
+	err = esh.db.First(elementaryStream, elementaryStreamId).Error
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	api.JSON(w, elementaryStream)
+}
+
+// CreateElementaryStream handler function for POST method
+func (esh *ElementaryStreamHandler) CreateElementaryStream(w http.ResponseWriter, r *http.Request) {
+	elementaryStream := &model.ElementaryStream{}
+	err := json.NewDecoder(r.Body).Decode(elementaryStream)
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	err = esh.db.Omit("Channel").Create(elementaryStream).Error
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	api.Created(w, elementaryStream)
+}
+
+// DeleteElementaryStream handler function for DELETE method
+func (esh *ElementaryStreamHandler) DeleteElementaryStream(w http.ResponseWriter, r *http.Request) {
+	elementaryStreamId, err := strconv.ParseInt(mux.Vars(r)["elementaryStreamId"], 10, 64)
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	elementaryStream := &model.ElementaryStream{}
+	err = esh.db.First(elementaryStream, elementaryStreamId).Error
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	err = esh.db.Delete(elementaryStream).Error
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	api.JSON(w, map[string]interface{}{"message": "Elementary stream deleted successfully"})
+}
@@ -0,0 +1,294 @@
+// epgHandler.go
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"epg/src/model"
+
+	"gorm.io/gorm"
+)
+
+// defaultGenreColor colours events whose genre has no colour, as on the genre page.
+const defaultGenreColor = "#A0A0A0"
+
+// EPGHandler renders the programme guide.
+type EPGHandler struct {
+	db *gorm.DB
+}
+
+// NewEPGHandler ...
+func NewEPGHandler(db *gorm.DB) *EPGHandler {
+	return &EPGHandler{db: db}
+}
+
+// EPGPageData holds the guide of a network's channels for one day.
+type EPGPageData struct {
+	Title   string
+	Heading string
+	// Networks and Timezones fill the selectors, the timezones are the ones
+	// of the network's country.
+	Networks   []model.Network
+	Timezones  []model.Timezone
+	NetworkID  uint
+	TimezoneID uint
+	// Date is the day shown, Day the same for reading and Zone its offset.
+	Date     string
+	Day      string
+	Zone     string
+	Previous string
+	Next     string
+	Today    string
+	Hours    []EPGHour
+	Rows     []EPGRow
+	// DayStart and DayEnd bound the day in Unix milliseconds for the now
+	// marker, shown at Now percent of the day when ShowNow.
+	DayStart int64
+	DayEnd   int64
+	Now      float64
+	ShowNow  bool
+}
+
+// EPGHour is an hour mark of the time scale, Left percent into the day.
+type EPGHour struct {
+	Label string
+	Left  float64
+}
+
+// EPGRow is a channel and its events of the day.
+type EPGRow struct {
+	Channel model.Channel
+	Cells   []EPGCell
+}
+
+// EPGCell is an event placed Left percent into the day and Width percent long.
+type EPGCell struct {
+	Event     model.Event
+	Start     string
+	End       string
+	Left      float64
+	Width     float64
+	Color     string
+	TextColor string
+	Ratings   []EPGRating
+	// OnAir marks the event showing now.
+	OnAir bool
+}
+
+// EPGRating is a rating of an event, Icon is empty when there is no icon.
+type EPGRating struct {
+	Value string
+	Icon  string
+}
+
+// GetEPGHTML handler function for GET method, the guide of a network for a
+// day. It takes ?network= (default the first network), ?tz= a timezone of the
+// network's country (default the network's) and ?date= (YYYY-MM-DD, default
+// today).
+func (eh *EPGHandler) GetEPGHTML(w http.ResponseWriter, r *http.Request) {
+	networks := []model.Network{}
+	if err := eh.db.Preload("Timezone").Order("network_id").Find(&networks).Error; err != nil {
+		HandleHtmlError(w, err)
+		return
+	}
+	if len(networks) == 0 {
+		HandleHtmlError(w, fmt.Errorf("no networks"))
+		return
+	}
+
+	network := networks[0]
+	if v := r.URL.Query().Get("network"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			http.Error(w, "invalid network: "+v, http.StatusBadRequest)
+			return
+		}
+		found := false
+		for _, n := range networks {
+			if n.NetworkID == uint(id) {
+				network, found = n, true
+			}
+		}
+		if !found {
+			http.Error(w, "network not found: "+v, http.StatusNotFound)
+			return
+		}
+	}
+
+	timezones := []model.Timezone{}
+	err := eh.db.Where("country_code = ?", network.Timezone.CountryCode).Order("timezone_name").Find(&timezones).Error
+	if err != nil {
+		HandleHtmlError(w, err)
+		return
+	}
+	timezone := network.Timezone
+	if v := r.URL.Query().Get("tz"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			http.Error(w, "invalid timezone: "+v, http.StatusBadRequest)
+			return
+		}
+		found := false
+		for _, tz := range timezones {
+			if tz.TimeZoneID == uint(id) {
+				timezone, found = tz, true
+			}
+		}
+		if !found {
+			http.Error(w, "timezone not found: "+v, http.StatusNotFound)
+			return
+		}
+	}
+	location := timezone.Location()
+
+	now := time.Now().In(location)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, location)
+	from := today
+	if v := r.URL.Query().Get("date"); v != "" {
+		from, err = time.ParseInLocation("2006-01-02", v, location)
+		if err != nil {
+			http.Error(w, "invalid date: "+v, http.StatusBadRequest)
+			return
+		}
+	}
+	// A day is 23 or 25 hours long when daylight saving starts or ends.
+	to := from.AddDate(0, 0, 1)
+
+	channels := []model.Channel{}
+	err = eh.db.Where("network_id = ?", network.NetworkID).Order("logical_channel_number, channel_id").Find(&channels).Error
+	if err != nil {
+		HandleHtmlError(w, err)
+		return
+	}
+	colors, err := eh.genreColors()
+	if err != nil {
+		HandleHtmlError(w, err)
+		return
+	}
+
+	_, offset := from.Zone()
+	data := EPGPageData{
+		Title:      "EPG",
+		Heading:    network.Description,
+		Networks:   networks,
+		Timezones:  timezones,
+		NetworkID:  network.NetworkID,
+		TimezoneID: timezone.TimeZoneID,
+		Date:       from.Format("2006-01-02"),
+		Day:        from.Format("Monday 2 January 2006"),
+		Zone:       fmt.Sprintf("%s (UTC%s)", timezone.TimezoneName, formatOffset(offset)),
+		Previous:   from.AddDate(0, 0, -1).Format("2006-01-02"),
+		Next:       to.Format("2006-01-02"),
+		Today:      today.Format("2006-01-02"),
+		DayStart:   from.UnixMilli(),
+		DayEnd:     to.UnixMilli(),
+		Now:        percentOf(now, from, to),
+		ShowNow:    !now.Before(from) && now.Before(to),
+	}
+	for h := from; h.Before(to); h = h.Add(time.Hour) {
+		data.Hours = append(data.Hours, EPGHour{Label: h.Format("15:04"), Left: percentOf(h, from, to)})
+	}
+
+	for _, channel := range channels {
+		events, err := model.FindChannelEvents(eh.db, channel.ChannelID, from, to)
+		if err != nil {
+			HandleHtmlError(w, err)
+			return
+		}
+		row := EPGRow{Channel: channel}
+		for _, event := range events {
+			left := percentOf(event.StartTime, from, to)
+			color, ok := colors[event.Genre.NibbleLevel1]
+			if !ok {
+				color = defaultGenreColor
+			}
+			cell := EPGCell{
+				Event:     event,
+				Start:     event.StartTime.In(location).Format("15:04"),
+				End:       event.EndTime.In(location).Format("15:04"),
+				Left:      left,
+				Width:     percentOf(event.EndTime, from, to) - left,
+				Color:     color,
+				TextColor: textColor(color),
+				OnAir:     !now.Before(event.StartTime) && now.Before(event.EndTime),
+			}
+			for _, er := range event.EventRatings {
+				cell.Ratings = append(cell.Ratings, ratingIcon(er.RatingValue))
+			}
+			row.Cells = append(row.Cells, cell)
+		}
+		data.Rows = append(data.Rows, row)
+	}
+
+	RenderTemplate(w, "static/html/epg.html", data)
+}
+
+// genreColors returns the colours of the genres by their first nibble.
+func (eh *EPGHandler) genreColors() (map[uint8]string, error) {
+	genreColors := []model.GenreColor{}
+	if err := eh.db.Find(&genreColors).Error; err != nil {
+		return nil, err
+	}
+	colors := map[uint8]string{}
+	for _, gc := range genreColors {
+		if gc.ColorHex != "" {
+			colors[gc.NibbleLevel1] = gc.ColorHex
+		}
+	}
+	return colors, nil
+}
+
+// ratingIcon returns a rating with its icon under static/img/ratings/<country>/
+// when there is one.
+func ratingIcon(value model.RatingValue) EPGRating {
+	rating := EPGRating{Value: value.Value}
+	country := strings.ToLower(value.RatingSystem.Country.CountryCode)
+	if country == "" {
+		return rating
+	}
+	file := value.IconFile()
+	if _, err := os.Stat(path.Join("static", "img", "ratings", country, file)); err == nil {
+		rating.Icon = "/static/" + path.Join("img", "ratings", country, file)
+	}
+	return rating
+}
+
+// percentOf returns how far t is into [from, to) in percent, clipped to the
+// day.
+func percentOf(t, from, to time.Time) float64 {
+	if t.Before(from) {
+		return 0
+	}
+	if t.After(to) {
+		return 100
+	}
+	return float64(t.Sub(from)) / float64(to.Sub(from)) * 100
+}
+
+// textColor returns black or white, whichever reads better on the colour hex.
+func textColor(hex string) string {
+	v, err := strconv.ParseUint(strings.TrimPrefix(hex, "#"), 16, 32)
+	if err != nil || len(hex) != 7 {
+		return "#000000"
+	}
+	red, green, blue := float64(v>>16&0xff), float64(v>>8&0xff), float64(v&0xff)
+	if 0.299*red+0.587*green+0.114*blue > 150 {
+		return "#000000"
+	}
+	return "#FFFFFF"
+}
+
+// formatOffset returns a UTC offset in seconds as +hh:mm.
+func formatOffset(seconds int) string {
+	sign := '+'
+	if seconds < 0 {
+		sign, seconds = '-', -seconds
+	}
+	return fmt.Sprintf("%c%02d:%02d", sign, seconds/3600, seconds/60%60)
+}
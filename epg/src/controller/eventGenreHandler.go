@@ -0,0 +1,98 @@
+// eventGenreHandler.go
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"epg/src/api"
+	"epg/src/model"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// EventGenreHandler ...
+type EventGenreHandler struct {
+	db *gorm.DB
+}
+
+// NewEventGenreHandler ...
+func NewEventGenreHandler(db *gorm.DB) *EventGenreHandler {
+	return &EventGenreHandler{db: db}
+}
+
+// GetAllEventGenres handler function for GET method
+func (egh *EventGenreHandler) GetAllEventGenres(w http.ResponseWriter, r *http.Request) {
+	eventGenres := []model.EventGenre{}
+	err := egh.db.Preload("Event").Preload("Genre").Find(&eventGenres).Error
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	api.JSON(w, eventGenres)
+}
+
+// GetEventGenreById handler function for GET method
+func (egh *EventGenreHandler) GetEventGenreById(w http.ResponseWriter, r *http.Request) {
+	eventId, err := strconv.ParseInt(mux.Vars(r)["eventId"], 10, 64)
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	genreId, err := strconv.ParseInt(mux.Vars(r)["genreId"], 10, 64)
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	eventGenre := &model.EventGenre{}
+	err = egh.db.Preload("Event").Preload("Genre").Where("event_id = ? AND genre_id = ?", eventId, genreId).First(eventGenre).Error
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	api.JSON(w, eventGenre)
+}
+
+// CreateEventGenre handler function for POST method
+func (egh *EventGenreHandler) CreateEventGenre(w http.ResponseWriter, r *http.Request) {
+	eventGenre := &model.EventGenre{}
+	err := json.NewDecoder(r.Body).Decode(eventGenre)
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	err = egh.db.Create(eventGenre).Error
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	api.Created(w, eventGenre)
+}
+
+// DeleteEventGenre handler function for DELETE method
+func (egh *EventGenreHandler) DeleteEventGenre(w http.ResponseWriter, r *http.Request) {
+	eventId, err := strconv.ParseInt(mux.Vars(r)["eventId"], 10, 64)
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	genreId, err := strconv.ParseInt(mux.Vars(r)["genreId"], 10, 64)
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	eventGenre := &model.EventGenre{}
+	err = egh.db.Where("event_id = ? AND genre_id = ?", eventId, genreId).First(eventGenre).Error
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	err = egh.db.Delete(eventGenre).Error
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	api.JSON(w, map[string]interface{}{"message": "Event genre deleted successfully"})
+}
@@ -3,12 +3,13 @@ package controller
 
 import (
 	"encoding/json"
-	"log"
 	"net/http"
 	"strconv"
 	"time"
 
+	"epg/src/api"
 	"epg/src/model"
+	"epg/src/schedule"
 
 	"github.com/gorilla/mux"
 	"gorm.io/gorm"
@@ -24,31 +25,66 @@ func NewEventHandler(db *gorm.DB) *EventHandler {
 	return &EventHandler{db: db}
 }
 
-// GetAllEvents handler function for GET method
+// EventList is how GetAllEvents is paged, filtered, sorted and preloaded.
+var EventList = api.List{
+	Filters: map[string]api.Filter{
+		"channel":  api.Equals("channel_id", "Only the events of this channel"),
+		"network":  api.ByID("channel_id IN (SELECT channel_id FROM channels WHERE network_id = ?)", "Only the events of the channels of this network"),
+		"genre":    api.ByID("(genre_id = ? OR event_id IN (SELECT event_id FROM event_genres WHERE genre_id = ?))", "Only the events of this genre, first or additional"),
+		"category": api.Equals("category_id", "Only the events of this category"),
+		"from":     api.After("end_time", "Only the events ending after this RFC 3339 time"),
+		"to":       api.Before("start_time", "Only the events starting before this RFC 3339 time"),
+		"q":        api.Contains("title", "Only the events whose title contains this"),
+	},
+	Sorts: map[string]string{
+		"start":   "start_time",
+		"end":     "end_time",
+		"title":   "title",
+		"channel": "channel_id",
+		"id":      "event_id",
+	},
+	Sort: "start,channel",
+	Key:  "event_id",
+	Includes: map[string][]string{
+		"channel":  {"Channel"},
+		"category": {"Category"},
+		"genre":    {"Genre.GenreColor"},
+		"ratings":  {"EventRatings.RatingValue"},
+		"genres":   {"EventGenres.Genre"},
+	},
+}
+
+// GetAllEvents handler function for GET method, see EventList for the
+// parameters.
 func (eh *EventHandler) GetAllEvents(w http.ResponseWriter, r *http.Request) {
 	events := []model.Event{}
-	err := eh.db.Preload("Channel").Preload("Category").Preload("Genre").Preload("EventRatings").Find(&events).Error
+	page, err := EventList.Find(eh.db, r, &events)
 	if err != nil {
-		eh.handleError(w, err)
+		api.Error(w, err)
 		return
 	}
-	eh.encodeJSONResponse(w, events)
+	for i := range events {
+		if events[i].Genre.GenreID != 0 {
+			events[i].Genre.ColorHex = genreColor(events[i].Genre)
+		}
+	}
+	api.Paged(w, r, page, events)
 }
 
 // GetEventById handler function for GET method
 func (eh *EventHandler) GetEventById(w http.ResponseWriter, r *http.Request) {
 	eventId, err := strconv.ParseInt(mux.Vars(r)["eventId"], 10, 64)
 	if err != nil {
-		eh.handleError(w, err)
+		api.Error(w, err)
 		return
 	}
 	event := &model.Event{}
-	err = eh.db.Preload("Channel").Preload("Category").Preload("Genre").Preload("EventRatings").Where("event_id = ?", eventId).First(event).Error
+	err = eh.db.Preload("Channel").Preload("Category").Preload("Genre").Preload("EventRatings").Preload("EventGenres").Where("event_id = ?", eventId).First(event).Error
 	if err != nil {
-		eh.handleError(w, err)
+		api.Error(w, err)
 		return
 	}
-	eh.encodeJSONResponse(w, event)
+	api.JSON(w, event)
 }
 
 // http://localhost:8080/eventbytime/2025-02-09%2000:00:00
@@ -58,7 +94,7 @@ func (eh *EventHandler) GetEventByTime(w http.ResponseWriter, r *http.Request) {
 	timeLayout := "2006-01-02 15:04:05"
 	timeCreated, err := time.Parse(timeLayout, timeStr)
 	if err != nil {
-		eh.handleError(w, err)
+		api.Error(w, err)
 		return
 	}
 
@@ -71,14 +107,16 @@ func (eh *EventHandler) GetEventByTime(w http.ResponseWriter, r *http.Request) {
 		Preload("EventRatings.RatingValue").
 		Preload("EventRatings.RatingValue.RatingSystem").
 		Preload("EventRatings.RatingValue.RatingSystem.Country").
+		Preload("EventGenres").
+		Preload("EventGenres.Genre").
 		Where("created_at >= ?", timeCreated).
 		Find(&events).Error
 	if err != nil {
-		eh.handleError(w, err)
+		api.Error(w, err)
 		return
 	}
 
-	eh.encodeJSONResponse(w, events)
+	api.JSON(w, events)
 }
 
 // CreateEvent handler function for POST method
@@ -86,15 +124,22 @@ func (eh *EventHandler) CreateEvent(w http.ResponseWriter, r *http.Request) {
 	event := &model.Event{}
 	err := json.NewDecoder(r.Body).Decode(event)
 	if err != nil {
-		eh.handleError(w, err)
+		api.Error(w, err)
 		return
 	}
-	err = eh.db.Create(event).Error
+	// Checked and stored in one transaction so no other event can take the
+	// slot in between.
+	err = eh.db.Transaction(func(tx *gorm.DB) error {
+		if err := schedule.ValidateEvent(tx, event); err != nil {
+			return err
+		}
+		return tx.Create(event).Error
+	})
 	if err != nil {
-		eh.handleError(w, err)
+		api.Error(w, err)
 		return
 	}
-	eh.encodeJSONResponse(w, event)
+	api.Created(w, event)
 }
 
 // UpdateEvent handler function for PUT method
@@ -102,56 +147,50 @@ func (eh *EventHandler) UpdateEvent(w http.ResponseWriter, r *http.Request) {
 	event := &model.Event{}
 	err := json.NewDecoder(r.Body).Decode(event)
 	if err != nil {
-		eh.handleError(w, err)
+		api.Error(w, err)
 		return
 	}
 	eventId, err := strconv.ParseInt(mux.Vars(r)["eventId"], 10, 64)
 	if err != nil {
-		eh.handleError(w, err)
+		api.Error(w, err)
+		return
+	}
+	err = eh.db.Where("event_id = ?", eventId).First(&model.Event{}).Error
+	if err != nil {
+		api.Error(w, err)
 		return
 	}
 	event.EventID = uint(eventId)
-	err = eh.db.Save(event).Error
+	err = eh.db.Transaction(func(tx *gorm.DB) error {
+		if err := schedule.ValidateEvent(tx, event); err != nil {
+			return err
+		}
+		return tx.Save(event).Error
+	})
 	if err != nil {
-		eh.handleError(w, err)
+		api.Error(w, err)
 		return
 	}
-	eh.encodeJSONResponse(w, event)
+	api.JSON(w, event)
 }
 
 // DeleteEvent handler function for DELETE method
 func (eh *EventHandler) DeleteEvent(w http.ResponseWriter, r *http.Request) {
 	eventId, err := strconv.ParseInt(mux.Vars(r)["eventId"], 10, 64)
 	if err != nil {
-		eh.handleError(w, err)
+		api.Error(w, err)
 		return
 	}
 	event := &model.Event{}
 	err = eh.db.Where("event_id = ?", eventId).First(event).Error
 	if err != nil {
-		eh.handleError(w, err)
+		api.Error(w, err)
 		return
 	}
 	err = eh.db.Delete(event).Error
 	if err != nil {
-		eh.handleError(w, err)
+		api.Error(w, err)
 		return
 	}
-	eh.encodeJSONResponse(w, map[string]interface{}{"message": "Event deleted successfully"})
-}
-
-// handleError ...
-func (eh *EventHandler) handleError(w http.ResponseWriter, err error) {
-	msg := map[string]interface{}{"status": false, "message": err.Error()}
-	w.Header().Add("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(msg)
-}
-
-// encodeJSONResponse ...
-func (eh *EventHandler) encodeJSONResponse(w http.ResponseWriter, data interface{}) {
-	w.Header().Add("Content-Type", "application/json")
-	err := json.NewEncoder(w).Encode(data)
-	if err != nil {
-		log.Println(err)
-	}
+	api.JSON(w, map[string]interface{}{"message": "Event deleted successfully"})
 }
@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"strconv"
 
+	"epg/src/api"
 	"epg/src/model"
 
 	"github.com/gorilla/mux"
@@ -20,6 +21,63 @@ func NewGenreHandler(db *gorm.DB) *GenreHandler {
 	return &GenreHandler{db: db}
 }
 
+// GenreList is how GetAllGenres is paged, filtered and sorted, the colour
+// is always preloaded.
+var GenreList = api.List{
+	Filters: map[string]api.Filter{
+		"level1": api.Equals("nibble_level_1", "Only the genres of this level 1 nibble"),
+		"q":      api.Contains("description", "Only the genres whose description contains this"),
+	},
+	Sorts: map[string]string{
+		"level1":      "nibble_level_1",
+		"level2":      "nibble_level_2",
+		"description": "description",
+		"id":          "genre_id",
+	},
+	Sort:     "level1,level2",
+	Key:      "genre_id",
+	Preloads: []string{"GenreColor"},
+}
+
+// GetAllGenres handler function for GET method, see GenreList for the
+// parameters.
+func (gh *GenreHandler) GetAllGenres(w http.ResponseWriter, r *http.Request) {
+	genres := []model.Genre{}
+	page, err := GenreList.Find(gh.db, r, &genres)
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	for i := range genres {
+		genres[i].ColorHex = genreColor(genres[i])
+	}
+	api.Paged(w, r, page, genres)
+}
+
+// GetGenreById handler function for GET method
+func (gh *GenreHandler) GetGenreById(w http.ResponseWriter, r *http.Request) {
+	genreId, err := strconv.ParseInt(mux.Vars(r)["genreId"], 10, 64)
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	genre := &model.Genre{}
+	if err = gh.db.Preload("GenreColor").Where("genre_id = ?", genreId).First(genre).Error; err != nil {
+		api.Error(w, err)
+		return
+	}
+	genre.ColorHex = genreColor(*genre)
+	api.JSON(w, genre)
+}
+
+// genreColor returns the colour of a genre's level 1 nibble, or the default.
+func genreColor(genre model.Genre) string {
+	if genre.GenreColor == nil || genre.GenreColor.ColorHex == "" {
+		return defaultGenreColor
+	}
+	return genre.GenreColor.ColorHex
+}
+
 // GetAllGenresHTML fetches genres from the /genre endpoint and renders the HTML page.
 func (gh *GenreHandler) GetAllGenresHTML(w http.ResponseWriter, r *http.Request) {
 	var genres []model.Genre
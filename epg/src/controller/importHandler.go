@@ -0,0 +1,58 @@
+// importHandler.go
+package controller
+
+import (
+	"io"
+	"net/http"
+	"strings"
+
+	"epg/src/api"
+	config "epg/src/config"
+	"epg/src/xmltv"
+
+	"gorm.io/gorm"
+)
+
+// maxImportSize bounds the size of an uploaded schedule.
+const maxImportSize = 64 << 20
+
+// ImportHandler ...
+type ImportHandler struct {
+	db *gorm.DB
+}
+
+// NewImportHandler ...
+func NewImportHandler(db *gorm.DB) *ImportHandler {
+	return &ImportHandler{db: db}
+}
+
+// ImportXMLTV handler function for POST method, the XMLTV document is the
+// request body or a multipart "file" field. ?replace=true replaces
+// overlapping events instead of reporting them as conflicts.
+func (ih *ImportHandler) ImportXMLTV(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	var body io.Reader = r.Body
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			api.Error(w, api.WithStatus(http.StatusBadRequest, err))
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+
+	tv, err := xmltv.Parse(body)
+	if err != nil {
+		api.Error(w, api.WithStatus(http.StatusBadRequest, err))
+		return
+	}
+	importer := xmltv.NewImporter(ih.db, config.Config.XMLTVChannels)
+	importer.Replace = r.URL.Query().Get("replace") == "true"
+	result, err := importer.Import(tv)
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	api.JSON(w, result)
+}
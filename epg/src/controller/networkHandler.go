@@ -2,15 +2,18 @@
 package controller
 
 import (
+	"encoding/json"
 	"errors"
 	"net/http"
 	"strconv"
 
+	"epg/src/api"
 	"epg/src/model"
 
 	"github.com/gorilla/mux"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // NetworkHandler ...
@@ -140,6 +143,185 @@ func (nh *NetworkHandler) GetNetworkByIdHTML(w http.ResponseWriter, r *http.Requ
 	RenderTemplate(w, "static/html/network.html", data)
 }
 
+// GetAllNetworks handler function for GET method
+func (nh *NetworkHandler) GetAllNetworks(w http.ResponseWriter, r *http.Request) {
+	networks := []model.Network{}
+	err := nh.db.Preload("Country").Preload("Timezone").Order("network_id").Find(&networks).Error
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	for i := range networks {
+		fillNetwork(&networks[i])
+	}
+	api.JSON(w, networks)
+}
+
+// GetNetworkById handler function for GET method
+func (nh *NetworkHandler) GetNetworkById(w http.ResponseWriter, r *http.Request) {
+	network, err := nh.findNetwork(r)
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	api.JSON(w, network)
+}
+
+// CreateNetwork handler function for POST method
+func (nh *NetworkHandler) CreateNetwork(w http.ResponseWriter, r *http.Request) {
+	network := &model.Network{}
+	err := json.NewDecoder(r.Body).Decode(network)
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	network.NetworkID = 0
+	if err = nh.validate(network); err != nil {
+		api.Error(w, err)
+		return
+	}
+	err = nh.db.Omit(clause.Associations).Create(network).Error
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	network, err = nh.loadNetwork(network.NetworkID)
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	api.Created(w, network)
+}
+
+// UpdateNetwork handler function for PUT method
+func (nh *NetworkHandler) UpdateNetwork(w http.ResponseWriter, r *http.Request) {
+	existing, err := nh.findNetwork(r)
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	network := &model.Network{}
+	err = json.NewDecoder(r.Body).Decode(network)
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	network.NetworkID = existing.NetworkID
+	if err = nh.validate(network); err != nil {
+		api.Error(w, err)
+		return
+	}
+	err = nh.db.Omit(clause.Associations).Save(network).Error
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	network, err = nh.loadNetwork(network.NetworkID)
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	api.JSON(w, network)
+}
+
+// DeleteNetwork handler function for DELETE method, the network's channels
+// are deleted with it, see model.DeleteChannels.
+func (nh *NetworkHandler) DeleteNetwork(w http.ResponseWriter, r *http.Request) {
+	network, err := nh.findNetwork(r)
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	var channelIDs []uint
+	var events int64
+	err = nh.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.Channel{}).Where("network_id = ?", network.NetworkID).Pluck("channel_id", &channelIDs).Error; err != nil {
+			return err
+		}
+		var err error
+		if events, err = model.DeleteChannels(tx, channelIDs); err != nil {
+			return err
+		}
+		return tx.Delete(&model.Network{}, network.NetworkID).Error
+	})
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	api.JSON(w, map[string]interface{}{"message": "Network deleted successfully", "channels": len(channelIDs), "events": events})
+}
+
+// findNetwork loads the network of the request's networkId.
+func (nh *NetworkHandler) findNetwork(r *http.Request) (*model.Network, error) {
+	networkId, err := strconv.ParseUint(mux.Vars(r)["networkId"], 10, 32)
+	if err != nil {
+		return nil, err
+	}
+	return nh.loadNetwork(uint(networkId))
+}
+
+// loadNetwork loads a network with its country and timezone.
+func (nh *NetworkHandler) loadNetwork(networkId uint) (*model.Network, error) {
+	network := &model.Network{}
+	err := nh.db.Preload("Country").Preload("Timezone").First(network, "network_id = ?", networkId).Error
+	if err != nil {
+		return nil, err
+	}
+	fillNetwork(network)
+	return network, nil
+}
+
+// validate checks a network about to be stored: it needs a description, a
+// known country with a timezone of that country, and a service ID, its
+// transport stream ID, no other network has.
+func (nh *NetworkHandler) validate(network *model.Network) error {
+	verr := &api.ValidationError{Resource: "network"}
+	if network.Description == "" {
+		verr.Add("description", api.ViolationRequired, "description is required")
+	}
+
+	country := model.Country{}
+	if err := nh.db.Limit(1).Find(&country, "country_id = ?", network.CountryID).Error; err != nil {
+		return err
+	}
+	if country.CountryID == 0 {
+		verr.Add("countryID", api.ViolationUnknown, "country %d does not exist", network.CountryID)
+	}
+	timezone := model.Timezone{}
+	if err := nh.db.Limit(1).Find(&timezone, "time_zone_id = ?", network.TimezoneID).Error; err != nil {
+		return err
+	}
+	if timezone.TimeZoneID == 0 {
+		verr.Add("timezoneID", api.ViolationUnknown, "timezone %d does not exist", network.TimezoneID)
+	} else if country.CountryID != 0 && timezone.CountryCode != country.CountryCode {
+		verr.Add("timezoneID", api.ViolationInvalid, "timezone %s is not in %s", timezone.TimezoneName, country.CountryCode)
+	}
+
+	if network.ServiceID == 0 || network.ServiceID > 0xFFFF {
+		verr.Add("serviceID", api.ViolationInvalid, "service ID %d is not in 1 to 65535", network.ServiceID)
+	} else {
+		other := model.Network{}
+		err := nh.db.Where("service_id = ? AND network_id <> ?", network.ServiceID, network.NetworkID).Limit(1).Find(&other).Error
+		if err != nil {
+			return err
+		}
+		if other.NetworkID != 0 {
+			verr.Add("serviceID", api.ViolationDuplicate, "service ID %d is used by network %s", network.ServiceID, other.Description)
+		}
+	}
+	return verr.Err()
+}
+
+// fillNetwork sets the country and timezone fields of a network from its
+// loaded Country and Timezone.
+func fillNetwork(network *model.Network) {
+	network.CountryCode = network.Country.CountryCode
+	network.CountryName = network.Country.CountryName
+	network.TimezoneName = network.Timezone.TimezoneName
+	network.StandardOffset = network.Timezone.StandardOffset
+	network.DSTOffset = network.Timezone.DSTOffset
+}
+
 /*
 // GetAllNetworksHTML handler function for GET method
 func (nh *NetworkHandler) GetAllNetworksHTML(w http.ResponseWriter, r *http.Request) {
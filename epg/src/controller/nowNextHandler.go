@@ -0,0 +1,105 @@
+// nowNextHandler.go
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"epg/src/api"
+	"epg/src/nownext"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// streamKeepAlive is the time between comments keeping an idle stream open
+// through proxies.
+const streamKeepAlive = 30 * time.Second
+
+// NowNextHandler serves the present and following events of the channels.
+type NowNextHandler struct {
+	db     *gorm.DB
+	broker *nownext.Broker
+}
+
+// NewNowNextHandler ...
+func NewNowNextHandler(db *gorm.DB, broker *nownext.Broker) *NowNextHandler {
+	return &NowNextHandler{db: db, broker: broker}
+}
+
+// GetNow handler function for GET method, the present and following event of
+// every channel.
+func (nh *NowNextHandler) GetNow(w http.ResponseWriter, r *http.Request) {
+	snapshot, err := nownext.Find(nh.db, 0, time.Now())
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	api.JSON(w, snapshot)
+}
+
+// GetChannelNowNext handler function for GET method, the present and
+// following event of one channel.
+func (nh *NowNextHandler) GetChannelNowNext(w http.ResponseWriter, r *http.Request) {
+	channelId, err := strconv.ParseUint(mux.Vars(r)["channelId"], 10, 32)
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	snapshot, err := nownext.Find(nh.db, uint(channelId), time.Now())
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = fmt.Errorf("channel %d: %w", channelId, err)
+	}
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	api.JSON(w, snapshot.Channels[0])
+}
+
+// StreamNowNext handler function for GET method, a Server-Sent Events stream
+// with a "nownext" event carrying the state of every channel on connecting
+// and whenever it changes.
+func (nh *NowNextHandler) StreamNowNext(w http.ResponseWriter, r *http.Request) {
+	rc := http.NewResponseController(w)
+	// The stream outlives the server's write timeout.
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	updates := nh.broker.Subscribe()
+	defer nh.broker.Unsubscribe(updates)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	rc.Flush()
+
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		var err error
+		select {
+		case <-r.Context().Done():
+			return
+		case data, ok := <-updates:
+			if !ok {
+				return
+			}
+			_, err = fmt.Fprintf(w, "event: nownext\ndata: %s\n\n", data)
+		case <-keepAlive.C:
+			_, err = fmt.Fprint(w, ": keep-alive\n\n")
+		}
+		if err == nil {
+			err = rc.Flush()
+		}
+		if err != nil {
+			return
+		}
+	}
+}
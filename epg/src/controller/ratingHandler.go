@@ -2,11 +2,10 @@
 package controller
 
 import (
-	"encoding/json"
-	"log"
 	"net/http"
 	"strconv"
 
+	"epg/src/api"
 	"epg/src/model"
 
 	"github.com/gorilla/mux"
@@ -28,7 +27,7 @@ func (rsh *RatingHandler) GetAllRatingsHTML(w http.ResponseWriter, r *http.Reque
 	ratingSystems := []model.RatingSystem{}
 	err := rsh.db.Preload("Country").Preload("RatingValues").Find(&ratingSystems).Error
 	if err != nil {
-		rsh.handleError(w, err)
+		api.Error(w, err)
 		return
 	}
 
@@ -68,45 +67,51 @@ func (rsh *RatingHandler) GetAllRatingsHTML(w http.ResponseWriter, r *http.Reque
 	RenderTemplate(w, "static/html/rating.html", data)
 }
 
-// GetAllRatingSystems handler function for GET method
+// RatingSystemList is how GetAllRatingSystems is paged, filtered, sorted
+// and preloaded.
+var RatingSystemList = api.List{
+	Filters: map[string]api.Filter{
+		"country": api.Equals("country_id", "Only the rating systems of this country"),
+		"q":       api.Contains("description", "Only the rating systems whose description contains this"),
+	},
+	Sorts: map[string]string{
+		"country":     "country_id",
+		"description": "description",
+		"id":          "rating_system_id",
+	},
+	Sort: "id",
+	Key:  "rating_system_id",
+	Includes: map[string][]string{
+		"country": {"Country"},
+		"values":  {"RatingValues"},
+	},
+	Include: "country,values",
+}
+
+// GetAllRatingSystems handler function for GET method, see
+// RatingSystemList for the parameters.
 func (rsh *RatingHandler) GetAllRatingSystems(w http.ResponseWriter, r *http.Request) {
 	ratingSystems := []model.RatingSystem{}
-	err := rsh.db.Preload("Country").Preload("RatingValues").Find(&ratingSystems).Error
+	page, err := RatingSystemList.Find(rsh.db, r, &ratingSystems)
 	if err != nil {
-		rsh.handleError(w, err)
+		api.Error(w, err)
 		return
 	}
-	rsh.encodeJSONResponse(w, ratingSystems)
+	api.Paged(w, r, page, ratingSystems)
 }
 
 // GetRatingSystemById handler function for GET method
 func (rsh *RatingHandler) GetRatingSystemById(w http.ResponseWriter, r *http.Request) {
 	ratingSystemId, err := strconv.ParseInt(mux.Vars(r)["ratingSystemId"], 10, 64)
 	if err != nil {
-		rsh.handleError(w, err)
+		api.Error(w, err)
 		return
 	}
 	ratingSystem := &model.RatingSystem{}
 	err = rsh.db.Preload("Country").Preload("RatingValues").Where("rating_system_id = ?", ratingSystemId).First(ratingSystem).Error
 	if err != nil {
-		rsh.handleError(w, err)
+		api.Error(w, err)
 		return
 	}
-	rsh.encodeJSONResponse(w, ratingSystem)
-}
-
-// handleError ...
-func (rsh *RatingHandler) handleError(w http.ResponseWriter, err error) {
-	msg := map[string]interface{}{"status": false, "message": err.Error()}
-	w.Header().Add("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(msg)
-}
-
-// encodeJSONResponse ...
-func (rsh *RatingHandler) encodeJSONResponse(w http.ResponseWriter, data interface{}) {
-	w.Header().Add("Content-Type", "application/json")
-	err := json.NewEncoder(w).Encode(data)
-	if err != nil {
-		log.Println(err)
-	}
+	api.JSON(w, ratingSystem)
 }
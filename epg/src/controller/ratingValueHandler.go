@@ -2,11 +2,10 @@
 package controller
 
 import (
-	"encoding/json"
-	"log"
 	"net/http"
 	"strconv"
 
+	"epg/src/api"
 	"epg/src/model"
 
 	"github.com/gorilla/mux"
@@ -23,45 +22,50 @@ func NewRatingValueHandler(db *gorm.DB) *RatingValueHandler {
 	return &RatingValueHandler{db: db}
 }
 
-// GetAllRatingValues handler function for GET method
+// RatingValueList is how GetAllRatingValues is paged, filtered, sorted and
+// preloaded.
+var RatingValueList = api.List{
+	Filters: map[string]api.Filter{
+		"system": api.Equals("rating_system_id", "Only the values of this rating system"),
+	},
+	Sorts: map[string]string{
+		"system": "rating_system_id",
+		"age":    "min_age",
+		"value":  "value",
+		"id":     "rating_value_id",
+	},
+	Sort: "system,age",
+	Key:  "rating_value_id",
+	Includes: map[string][]string{
+		"system": {"RatingSystem"},
+	},
+	Include: "system",
+}
+
+// GetAllRatingValues handler function for GET method, see RatingValueList
+// for the parameters.
 func (rvh *RatingValueHandler) GetAllRatingValues(w http.ResponseWriter, r *http.Request) {
 	ratingValues := []model.RatingValue{}
-	err := rvh.db.Preload("RatingSystem").Find(&ratingValues).Error
+	page, err := RatingValueList.Find(rvh.db, r, &ratingValues)
 	if err != nil {
-		rvh.handleError(w, err)
+		api.Error(w, err)
 		return
 	}
-	rvh.encodeJSONResponse(w, ratingValues)
+	api.Paged(w, r, page, ratingValues)
 }
 
 // GetRatingValueById handler function for GET method
 func (rvh *RatingValueHandler) GetRatingValueById(w http.ResponseWriter, r *http.Request) {
 	ratingValueId, err := strconv.ParseInt(mux.Vars(r)["ratingValueId"], 10, 64)
 	if err != nil {
-		rvh.handleError(w, err)
+		api.Error(w, err)
 		return
 	}
 	ratingValue := &model.RatingValue{}
 	err = rvh.db.Preload("RatingSystem.Country").Preload("RatingSystem").Where("rating_value_id = ?", ratingValueId).First(ratingValue).Error
 	if err != nil {
-		rvh.handleError(w, err)
+		api.Error(w, err)
 		return
 	}
-	rvh.encodeJSONResponse(w, ratingValue)
-}
-
-// handleError ...
-func (rvh *RatingValueHandler) handleError(w http.ResponseWriter, err error) {
-	msg := map[string]interface{}{"status": false, "message": err.Error()}
-	w.Header().Add("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(msg)
-}
-
-// encodeJSONResponse ...
-func (rvh *RatingValueHandler) encodeJSONResponse(w http.ResponseWriter, data interface{}) {
-	w.Header().Add("Content-Type", "application/json")
-	err := json.NewEncoder(w).Encode(data)
-	if err != nil {
-		log.Println(err)
-	}
+	api.JSON(w, ratingValue)
 }
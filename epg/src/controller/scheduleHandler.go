@@ -0,0 +1,94 @@
+// scheduleHandler.go
+package controller
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"epg/src/api"
+	config "epg/src/config"
+	"epg/src/schedule"
+
+	"gorm.io/gorm"
+)
+
+// maxScheduleCheckHours bounds the ?hours= window of the schedule checks.
+const maxScheduleCheckHours = 24 * 64
+
+// ScheduleHandler checks the schedules of the channels for overlaps and gaps.
+type ScheduleHandler struct {
+	db *gorm.DB
+}
+
+// NewScheduleHandler ...
+func NewScheduleHandler(db *gorm.DB) *ScheduleHandler {
+	return &ScheduleHandler{db: db}
+}
+
+// GetScheduleReport handler function for GET method, reports the overlaps
+// and broadcast window gaps of ?channel= (default all) from ?from= (RFC 3339,
+// default now) for ?hours= (default a week).
+func (sh *ScheduleHandler) GetScheduleReport(w http.ResponseWriter, r *http.Request) {
+	channelID, from, to, err := scheduleWindow(r)
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	reports, err := schedule.NewValidator(sh.db).Check(channelID, from, to)
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	api.JSON(w, reports)
+}
+
+// FillScheduleGaps handler function for POST method, reports like
+// GetScheduleReport and fills the gaps with the configured filler event.
+func (sh *ScheduleHandler) FillScheduleGaps(w http.ResponseWriter, r *http.Request) {
+	channelID, from, to, err := scheduleWindow(r)
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	cfg := config.Config.Filler
+	filler := schedule.Filler{
+		Title:       cfg.Title,
+		Description: cfg.Description,
+		GenreID:     cfg.GenreID,
+		CategoryID:  cfg.CategoryID,
+		MinGap:      time.Duration(cfg.MinGapMinutes) * time.Minute,
+	}
+	reports, err := schedule.NewValidator(sh.db).Fill(channelID, from, to, filler)
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	api.JSON(w, reports)
+}
+
+// scheduleWindow reads the ?channel=, ?from= and ?hours= of a schedule check.
+func scheduleWindow(r *http.Request) (channelID uint, from, to time.Time, err error) {
+	query := r.URL.Query()
+	if value := query.Get("channel"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return 0, from, to, api.Errorf(http.StatusBadRequest, "channel must be a channel ID")
+		}
+		channelID = uint(id)
+	}
+	from = time.Now()
+	if value := query.Get("from"); value != "" {
+		if from, err = time.Parse(time.RFC3339, value); err != nil {
+			return 0, from, to, api.Errorf(http.StatusBadRequest, "from must be an RFC 3339 time")
+		}
+	}
+	hours := 24 * defaultMaterializeDays
+	if value := query.Get("hours"); value != "" {
+		hours, err = strconv.Atoi(value)
+		if err != nil || hours < 1 || hours > maxScheduleCheckHours {
+			return 0, from, to, api.Errorf(http.StatusBadRequest, "hours must be between 1 and %d", maxScheduleCheckHours)
+		}
+	}
+	return channelID, from, from.Add(time.Duration(hours) * time.Hour), nil
+}
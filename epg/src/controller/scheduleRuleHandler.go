@@ -0,0 +1,182 @@
+// scheduleRuleHandler.go
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"epg/src/api"
+	"epg/src/dvb/eit"
+	"epg/src/model"
+	"epg/src/schedule"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// defaultMaterializeDays is the window materialised when no days are requested.
+const defaultMaterializeDays = 7
+
+// ScheduleRuleHandler ...
+type ScheduleRuleHandler struct {
+	db *gorm.DB
+}
+
+// NewScheduleRuleHandler ...
+func NewScheduleRuleHandler(db *gorm.DB) *ScheduleRuleHandler {
+	return &ScheduleRuleHandler{db: db}
+}
+
+// GetAllScheduleRules handler function for GET method
+func (sh *ScheduleRuleHandler) GetAllScheduleRules(w http.ResponseWriter, r *http.Request) {
+	rules := []model.ScheduleRule{}
+	err := sh.db.Order("channel_id, start_time").Find(&rules).Error
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	api.JSON(w, rules)
+}
+
+// GetScheduleRuleById handler function for GET method
+func (sh *ScheduleRuleHandler) GetScheduleRuleById(w http.ResponseWriter, r *http.Request) {
+	rule, err := sh.findRule(r)
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	api.JSON(w, rule)
+}
+
+// CreateScheduleRule handler function for POST method
+func (sh *ScheduleRuleHandler) CreateScheduleRule(w http.ResponseWriter, r *http.Request) {
+	rule := &model.ScheduleRule{}
+	err := json.NewDecoder(r.Body).Decode(rule)
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	rule.ScheduleRuleID = 0
+	if err = sh.validate(rule); err != nil {
+		api.Error(w, err)
+		return
+	}
+	err = sh.db.Omit(clause.Associations).Create(rule).Error
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	api.Created(w, rule)
+}
+
+// UpdateScheduleRule handler function for PUT method, the events the rule
+// has materialised from now on are removed to be materialised afresh.
+func (sh *ScheduleRuleHandler) UpdateScheduleRule(w http.ResponseWriter, r *http.Request) {
+	existing, err := sh.findRule(r)
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	rule := &model.ScheduleRule{}
+	err = json.NewDecoder(r.Body).Decode(rule)
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	rule.ScheduleRuleID = existing.ScheduleRuleID
+	rule.CreatedAt = existing.CreatedAt
+	if err = sh.validate(rule); err != nil {
+		api.Error(w, err)
+		return
+	}
+	err = sh.db.Omit(clause.Associations).Save(rule).Error
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	if _, err = schedule.NewMaterializer(sh.db).Clear(rule.ScheduleRuleID, time.Now()); err != nil {
+		api.Error(w, err)
+		return
+	}
+	api.JSON(w, rule)
+}
+
+// DeleteScheduleRule handler function for DELETE method, the events the rule
+// has materialised that have not started yet go with it.
+func (sh *ScheduleRuleHandler) DeleteScheduleRule(w http.ResponseWriter, r *http.Request) {
+	rule, err := sh.findRule(r)
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	deleted, err := schedule.NewMaterializer(sh.db).Clear(rule.ScheduleRuleID, time.Now())
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	err = sh.db.Delete(rule).Error
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	api.JSON(w, map[string]interface{}{"message": "Schedule rule deleted successfully", "eventsDeleted": deleted})
+}
+
+// MaterializeScheduleRules handler function for POST method, creates the
+// events of the enabled rules from now for ?days=N days.
+func (sh *ScheduleRuleHandler) MaterializeScheduleRules(w http.ResponseWriter, r *http.Request) {
+	days := defaultMaterializeDays
+	if value := r.URL.Query().Get("days"); value != "" {
+		var err error
+		days, err = strconv.Atoi(value)
+		if err != nil || days < 1 || days > eit.MaxScheduleDays {
+			api.Error(w, api.Errorf(http.StatusBadRequest, "days must be between 1 and %d", eit.MaxScheduleDays))
+			return
+		}
+	}
+	now := time.Now()
+	result, err := schedule.NewMaterializer(sh.db).Materialize(now, now.AddDate(0, 0, days))
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	api.JSON(w, result)
+}
+
+// findRule loads the rule of the request's scheduleRuleId.
+func (sh *ScheduleRuleHandler) findRule(r *http.Request) (*model.ScheduleRule, error) {
+	scheduleRuleId, err := strconv.ParseInt(mux.Vars(r)["scheduleRuleId"], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	rule := &model.ScheduleRule{}
+	err = sh.db.First(rule, scheduleRuleId).Error
+	return rule, err
+}
+
+// validate checks that a rule's references exist and that it compiles.
+func (sh *ScheduleRuleHandler) validate(rule *model.ScheduleRule) error {
+	err := sh.db.Preload("Network").Preload("Network.Timezone").First(&rule.Channel, rule.ChannelID).Error
+	if err != nil {
+		return badReference(fmt.Errorf("channel %d: %w", rule.ChannelID, err))
+	}
+	if err = sh.db.First(&model.Genre{}, rule.GenreID).Error; err != nil {
+		return badReference(fmt.Errorf("genre %d: %w", rule.GenreID, err))
+	}
+	if err = sh.db.First(&model.Category{}, rule.CategoryID).Error; err != nil {
+		return badReference(fmt.Errorf("category %d: %w", rule.CategoryID, err))
+	}
+	if rule.RatingValueID != nil {
+		if err = sh.db.First(&model.RatingValue{}, *rule.RatingValueID).Error; err != nil {
+			return badReference(fmt.Errorf("rating value %d: %w", *rule.RatingValueID, err))
+		}
+	}
+	if _, err = schedule.Compile(*rule); err != nil {
+		return api.WithStatus(http.StatusBadRequest, err)
+	}
+	return nil
+}
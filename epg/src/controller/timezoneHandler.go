@@ -1,10 +1,10 @@
 package controller
 
 import (
-	"encoding/json"
-	"log"
 	"net/http"
+	"strconv"
 
+	"epg/src/api"
 	"epg/src/model"
 
 	"github.com/gorilla/mux"
@@ -26,37 +26,24 @@ func (th *TimezoneHandler) GetAllTimezones(w http.ResponseWriter, r *http.Reques
 	timezones := []model.Timezone{}
 	err := th.db.Preload("Country").Find(&timezones).Error
 	if err != nil {
-		th.handleError(w, err)
+		api.Error(w, err)
 		return
 	}
-	th.encodeJSONResponse(w, timezones)
+	api.JSON(w, timezones)
 }
 
 // GetTimezoneById handler function for GET method
 func (th *TimezoneHandler) GetTimezoneById(w http.ResponseWriter, r *http.Request) {
-	countryCode := mux.Vars(r)["countryCode"]
-	timezoneName := mux.Vars(r)["timezoneName"]
-	timezone := &model.Timezone{}
-	err := th.db.Preload("Country").Where("country_code = ? AND timezone_name = ?", countryCode, timezoneName).First(timezone).Error
+	timezoneId, err := strconv.ParseInt(mux.Vars(r)["timezoneId"], 10, 64)
 	if err != nil {
-		th.handleError(w, err)
+		api.Error(w, err)
 		return
 	}
-	th.encodeJSONResponse(w, timezone)
-}
-
-// handleError ...
-func (th *TimezoneHandler) handleError(w http.ResponseWriter, err error) {
-	msg := map[string]interface{}{"status": false, "message": err.Error()}
-	w.Header().Add("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(msg)
-}
-
-// encodeJSONResponse ...
-func (th *TimezoneHandler) encodeJSONResponse(w http.ResponseWriter, data interface{}) {
-	w.Header().Add("Content-Type", "application/json")
-	err := json.NewEncoder(w).Encode(data)
+	timezone := &model.Timezone{}
+	err = th.db.Preload("Country").Where("time_zone_id = ?", timezoneId).First(timezone).Error
 	if err != nil {
-		log.Println(err)
+		api.Error(w, err)
+		return
 	}
+	api.JSON(w, timezone)
 }
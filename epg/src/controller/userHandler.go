@@ -0,0 +1,222 @@
+// userHandler.go
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"epg/src/api"
+	"epg/src/auth"
+	"epg/src/model"
+
+	"github.com/gorilla/mux"
+
+	"gorm.io/gorm"
+)
+
+// UserHandler ...
+type UserHandler struct {
+	db *gorm.DB
+}
+
+// NewUserHandler ...
+func NewUserHandler(db *gorm.DB) *UserHandler {
+	return &UserHandler{db: db}
+}
+
+// UserRequest is the body creating or updating a user. An update without a
+// password keeps the old one.
+type UserRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Role     string `json:"role"`
+	Disabled bool   `json:"disabled"`
+}
+
+// UserList is how GET /api/v1/users is paged, filtered and sorted.
+var UserList = api.List{
+	Filters: map[string]api.Filter{
+		"role": {Type: "string", Description: "Only users of this role", Apply: func(db *gorm.DB, value string) (*gorm.DB, error) {
+			return db.Where("role = ?", value), nil
+		}},
+	},
+	Sorts: map[string]string{"username": "username", "role": "role", "id": "user_id"},
+	Sort:  "username",
+	Key:   "user_id",
+}
+
+// GetAllUsers handler function for GET method
+func (uh *UserHandler) GetAllUsers(w http.ResponseWriter, r *http.Request) {
+	users := []model.User{}
+	page, err := UserList.Find(uh.db, r, &users)
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	api.Paged(w, r, page, users)
+}
+
+// GetUserById handler function for GET method
+func (uh *UserHandler) GetUserById(w http.ResponseWriter, r *http.Request) {
+	user, err := uh.findUser(r)
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	api.JSON(w, user)
+}
+
+// CreateUser handler function for POST method
+func (uh *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
+	req := &UserRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		api.Error(w, err)
+		return
+	}
+	user := &model.User{}
+	if err := uh.apply(user, req); err != nil {
+		api.Error(w, err)
+		return
+	}
+	if err := uh.db.Create(user).Error; err != nil {
+		api.Error(w, err)
+		return
+	}
+	api.Created(w, user)
+}
+
+// UpdateUser handler function for PUT method. Changing the password or
+// disabling the user logs it out everywhere.
+func (uh *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
+	user, err := uh.findUser(r)
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	req := &UserRequest{}
+	if err = json.NewDecoder(r.Body).Decode(req); err != nil {
+		api.Error(w, err)
+		return
+	}
+	if err = uh.apply(user, req); err != nil {
+		api.Error(w, err)
+		return
+	}
+	err = uh.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(user).Error; err != nil {
+			return err
+		}
+		if req.Password != "" || user.Disabled {
+			return auth.EndSessions(tx, user.UserID)
+		}
+		return nil
+	})
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	api.JSON(w, user)
+}
+
+// DeleteUser handler function for DELETE method
+func (uh *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	user, err := uh.findUser(r)
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	if err = uh.keepAdmin(user, false); err != nil {
+		api.Error(w, err)
+		return
+	}
+	err = uh.db.Transaction(func(tx *gorm.DB) error {
+		if err := auth.EndSessions(tx, user.UserID); err != nil {
+			return err
+		}
+		return tx.Delete(&model.User{}, user.UserID).Error
+	})
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	api.JSON(w, map[string]string{"message": "User deleted successfully"})
+}
+
+// findUser loads the user of the request's userId.
+func (uh *UserHandler) findUser(r *http.Request) (*model.User, error) {
+	userId, err := strconv.ParseUint(mux.Vars(r)["userId"], 10, 32)
+	if err != nil {
+		return nil, err
+	}
+	user := &model.User{}
+	if err = uh.db.First(user, "user_id = ?", userId).Error; err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// apply validates req and sets it on user: it needs a username no other
+// user has, one of the roles and, unless it updates a user, a password.
+func (uh *UserHandler) apply(user *model.User, req *UserRequest) error {
+	verr := &api.ValidationError{Resource: "user"}
+	if req.Username == "" {
+		verr.Add("username", api.ViolationRequired, "username is required")
+	} else {
+		other := model.User{}
+		if err := uh.db.Where("username = ? AND user_id <> ?", req.Username, user.UserID).Limit(1).Find(&other).Error; err != nil {
+			return err
+		}
+		if other.UserID != 0 {
+			verr.Add("username", api.ViolationDuplicate, "username %s is taken", req.Username)
+		}
+	}
+	if !model.ValidRole(req.Role) {
+		verr.Add("role", api.ViolationInvalid, "role %q is not one of %s, %s or %s", req.Role, model.RoleViewer, model.RoleScheduler, model.RoleAdmin)
+	}
+	if req.Password == "" && user.UserID == 0 {
+		verr.Add("password", api.ViolationRequired, "password is required")
+	} else if req.Password != "" {
+		if err := auth.CheckPasswordLength(req.Password); err != nil {
+			verr.Add("password", api.ViolationInvalid, "%v", err)
+		}
+	}
+	if err := verr.Err(); err != nil {
+		return err
+	}
+
+	if user.UserID != 0 && (req.Role != model.RoleAdmin || req.Disabled) {
+		if err := uh.keepAdmin(user, true); err != nil {
+			return err
+		}
+	}
+	if req.Password != "" {
+		hash, err := auth.HashPassword(req.Password)
+		if err != nil {
+			return err
+		}
+		user.PasswordHash = hash
+	}
+	user.Username, user.Role, user.Disabled = req.Username, req.Role, req.Disabled
+	return nil
+}
+
+// keepAdmin refuses with 409 to remove, demote or disable the last enabled
+// admin, which would leave no one to manage the users.
+func (uh *UserHandler) keepAdmin(user *model.User, demoted bool) error {
+	if user.Role != model.RoleAdmin || user.Disabled {
+		return nil
+	}
+	var admins int64
+	err := uh.db.Model(&model.User{}).Where("role = ? AND NOT disabled AND user_id <> ?", model.RoleAdmin, user.UserID).Count(&admins).Error
+	if err != nil {
+		return err
+	}
+	if admins == 0 {
+		if demoted {
+			return api.Errorf(http.StatusConflict, "%s is the last admin and must stay an enabled admin", user.Username)
+		}
+		return api.Errorf(http.StatusConflict, "%s is the last admin and cannot be deleted", user.Username)
+	}
+	return nil
+}
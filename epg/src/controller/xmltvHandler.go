@@ -0,0 +1,94 @@
+// xmltvHandler.go
+package controller
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"time"
+
+	config "epg/src/config"
+	"epg/src/xmltv"
+
+	"gorm.io/gorm"
+)
+
+// maxXMLTVHours bounds the ?hours= window of the XMLTV export.
+const maxXMLTVHours = 24 * 31
+
+// XMLTVHandler serves the guide to XMLTV consumers such as Kodi, Jellyfin
+// and Tvheadend.
+type XMLTVHandler struct {
+	db *gorm.DB
+}
+
+// NewXMLTVHandler ...
+func NewXMLTVHandler(db *gorm.DB) *XMLTVHandler {
+	return &XMLTVHandler{db: db}
+}
+
+// GetXMLTV renders the channels and events as XMLTV. ?channel= selects a
+// channel by channel ID or XMLTV id, ?from= (XMLTV date or RFC 3339,
+// default now) and ?hours= limit the events to a window.
+func (xh *XMLTVHandler) GetXMLTV(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := xmltv.Filter{From: time.Now()}
+
+	if value := query.Get("channel"); value != "" {
+		if id, err := strconv.ParseUint(value, 10, 32); err == nil {
+			filter.ChannelID = uint(id)
+		} else if id, ok := config.Config.XMLTVChannels[value]; ok {
+			filter.ChannelID = id
+		} else {
+			http.Error(w, "unknown channel "+value, http.StatusBadRequest)
+			return
+		}
+	}
+
+	if value := query.Get("from"); value != "" {
+		from, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			from, err = xmltv.ParseTime(value)
+		}
+		if err != nil {
+			http.Error(w, "from must be an XMLTV or RFC 3339 date", http.StatusBadRequest)
+			return
+		}
+		filter.From = from
+	}
+
+	if value := query.Get("hours"); value != "" {
+		hours, err := strconv.Atoi(value)
+		if err != nil || hours < 1 || hours > maxXMLTVHours {
+			http.Error(w, "hours must be between 1 and "+strconv.Itoa(maxXMLTVHours), http.StatusBadRequest)
+			return
+		}
+		filter.To = filter.From.Add(time.Duration(hours) * time.Hour)
+	}
+
+	tv, err := xmltv.NewExporter(xh.db, config.Config.XMLTVChannels, baseURL(r)).Export(filter)
+	if err != nil {
+		HandleHtmlError(w, err)
+		return
+	}
+	var buf bytes.Buffer
+	if err := xmltv.Write(&buf, tv); err != nil {
+		HandleHtmlError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.Write(buf.Bytes())
+}
+
+// baseURL returns the scheme and host the request was made to, honouring
+// the X-Forwarded-Proto of a reverse proxy.
+func baseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto == "http" || proto == "https" {
+		scheme = proto
+	}
+	return scheme + "://" + r.Host
+}
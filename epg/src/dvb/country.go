@@ -0,0 +1,96 @@
+// country codes, languages and character tables used in SI
+package dvb
+
+import "strings"
+
+// CountryInfo holds the broadcast conventions for a country.
+type CountryInfo struct {
+	// Alpha3 is the ISO 3166 alpha-3 code carried in country_code fields.
+	Alpha3 string
+	// Language is the ISO 639-2 code carried in ISO_639_language_code fields.
+	Language string
+	// Charset is the preferred character table for text in that language.
+	Charset Charset
+}
+
+// defaultCountry is used for countries missing from the countries table.
+var defaultCountry = CountryInfo{Alpha3: "", Language: "eng", Charset: Latin1}
+
+// countries is keyed by the ISO 3166 alpha-2 codes loaded from csv/countries.csv.
+var countries = map[string]CountryInfo{
+	"AE": {"ARE", "ara", ISO8859_6},
+	"AR": {"ARG", "spa", Latin1},
+	"AT": {"AUT", "ger", Latin1},
+	"AU": {"AUS", "eng", Latin1},
+	"BE": {"BEL", "dut", Latin1},
+	"BR": {"BRA", "por", Latin1},
+	"CA": {"CAN", "eng", Latin1},
+	"CH": {"CHE", "ger", Latin1},
+	"CL": {"CHL", "spa", Latin1},
+	"CN": {"CHN", "chi", UTF8},
+	"CO": {"COL", "spa", Latin1},
+	"CZ": {"CZE", "cze", ISO8859_2},
+	"DE": {"DEU", "ger", Latin1},
+	"DK": {"DNK", "dan", Latin1},
+	"EG": {"EGY", "ara", ISO8859_6},
+	"ES": {"ESP", "spa", Latin1},
+	"FI": {"FIN", "fin", Latin1},
+	"FR": {"FRA", "fre", Latin1},
+	"GB": {"GBR", "eng", Latin1},
+	"GR": {"GRC", "gre", ISO8859_7},
+	"HK": {"HKG", "chi", UTF8},
+	"HR": {"HRV", "hrv", ISO8859_2},
+	"ID": {"IDN", "ind", Latin1},
+	"IL": {"ISR", "heb", ISO8859_8},
+	"IN": {"IND", "hin", UTF8},
+	"IS": {"ISL", "ice", Latin1},
+	"IT": {"ITA", "ita", Latin1},
+	"JP": {"JPN", "jpn", UTF8},
+	"LU": {"LUX", "fre", Latin1},
+	"LV": {"LVA", "lav", ISO8859_13},
+	"MX": {"MEX", "spa", Latin1},
+	"MY": {"MYS", "may", Latin1},
+	"NL": {"NLD", "dut", Latin1},
+	"NO": {"NOR", "nor", Latin1},
+	"NZ": {"NZL", "eng", Latin1},
+	"PE": {"PER", "spa", Latin1},
+	"PH": {"PHL", "eng", Latin1},
+	"PK": {"PAK", "urd", UTF8},
+	"PL": {"POL", "pol", ISO8859_2},
+	"PT": {"PRT", "por", Latin1},
+	"RO": {"ROU", "rum", ISO8859_2},
+	"RS": {"SRB", "srp", ISO8859_5},
+	"RU": {"RUS", "rus", ISO8859_5},
+	"SA": {"SAU", "ara", ISO8859_6},
+	"SE": {"SWE", "swe", Latin1},
+	"SG": {"SGP", "eng", Latin1},
+	"SI": {"SVN", "slv", ISO8859_2},
+	"SK": {"SVK", "slo", ISO8859_2},
+	"TH": {"THA", "tha", UTF8},
+	"TR": {"TUR", "tur", ISO8859_9},
+	"TW": {"TWN", "chi", UTF8},
+	"UA": {"UKR", "ukr", ISO8859_5},
+	"US": {"USA", "eng", Latin1},
+	"VN": {"VNM", "vie", UTF8},
+	"ZA": {"ZAF", "eng", Latin1},
+}
+
+// LookupCountry returns the broadcast conventions for an ISO 3166 alpha-2 country
+// code, falling back to English in ISO 8859-1 for unknown countries.
+func LookupCountry(alpha2 string) CountryInfo {
+	if info, ok := countries[strings.ToUpper(strings.TrimSpace(alpha2))]; ok {
+		return info
+	}
+	return defaultCountry
+}
+
+// LookupAlpha3 returns the ISO 3166 alpha-2 code of an alpha-3 country_code.
+func LookupAlpha3(alpha3 string) (string, bool) {
+	alpha3 = strings.ToUpper(alpha3)
+	for alpha2, info := range countries {
+		if info.Alpha3 == alpha3 {
+			return alpha2, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,29 @@
+// MPEG-2 CRC32 used by PSI/SI sections
+package dvb
+
+// crcTable holds the precomputed CRC32/MPEG-2 remainders (polynomial 0x04C11DB7).
+var crcTable = func() [256]uint32 {
+	var table [256]uint32
+	for i := range table {
+		crc := uint32(i) << 24
+		for bit := 0; bit < 8; bit++ {
+			if crc&0x80000000 != 0 {
+				crc = crc<<1 ^ 0x04C11DB7
+			} else {
+				crc <<= 1
+			}
+		}
+		table[i] = crc
+	}
+	return table
+}()
+
+// CRC32 computes the MPEG-2 CRC32 of data as defined in ISO/IEC 13818-1 Annex B.
+// Running it over a complete section including its CRC field yields zero.
+func CRC32(data []byte) uint32 {
+	crc := uint32(0xFFFFFFFF)
+	for _, b := range data {
+		crc = crc<<8 ^ crcTable[byte(crc>>24)^b]
+	}
+	return crc
+}
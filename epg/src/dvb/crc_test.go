@@ -0,0 +1,28 @@
+package dvb
+
+import "testing"
+
+func TestCRC32(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want uint32
+	}{
+		{"empty", nil, 0xFFFFFFFF},
+		// The check value of CRC-32/MPEG-2.
+		{"check", []byte("123456789"), 0x0376E6E7},
+		{"zero byte", []byte{0x00}, 0x4E08BFB4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CRC32(tt.data); got != tt.want {
+				t.Errorf("CRC32(%q) = 0x%08X, want 0x%08X", tt.data, got, tt.want)
+			}
+			// A CRC appended big endian makes the CRC of the whole zero.
+			withCRC := append(append([]byte{}, tt.data...), byte(tt.want>>24), byte(tt.want>>16), byte(tt.want>>8), byte(tt.want))
+			if got := CRC32(withCRC); got != 0 {
+				t.Errorf("CRC32 over the data and its CRC = 0x%08X, want 0", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+// content reference identifiers (RFC 4078, ETSI TS 102 323)
+package dvb
+
+import (
+	"fmt"
+	"strings"
+)
+
+// cridScheme prefixes a CRID in its textual form, it is not broadcast.
+const cridScheme = "crid://"
+
+// CRID is a parsed content reference identifier crid://<authority>/<data>[#<imi>].
+// Authority is empty for a relative CRID that relies on a default authority.
+type CRID struct {
+	Authority string
+	Data      string
+	IMI       string
+}
+
+// ParseCRID parses a CRID given with or without the crid:// scheme. A value
+// starting with '/' is a relative CRID.
+func ParseCRID(s string) (CRID, error) {
+	rest := strings.TrimSpace(s)
+	if len(rest) >= len(cridScheme) && strings.EqualFold(rest[:len(cridScheme)], cridScheme) {
+		rest = rest[len(cridScheme):]
+	}
+
+	var crid CRID
+	if i := strings.IndexByte(rest, '#'); i >= 0 {
+		crid.IMI = rest[i+1:]
+		rest = rest[:i]
+	}
+	slash := strings.IndexByte(rest, '/')
+	if slash < 0 {
+		return CRID{}, fmt.Errorf("crid %q has no data part", s)
+	}
+	crid.Authority = rest[:slash]
+	crid.Data = rest[slash:]
+	if crid.Authority != "" && !ValidAuthority(crid.Authority) {
+		return CRID{}, fmt.Errorf("crid %q has an invalid authority", s)
+	}
+	if crid.Data == "/" {
+		return CRID{}, fmt.Errorf("crid %q has an empty data part", s)
+	}
+	return crid, nil
+}
+
+// ValidAuthority reports whether s looks like a CRID authority, a DNS name
+// optionally followed by ";" and a date.
+func ValidAuthority(s string) bool {
+	name, _, _ := strings.Cut(s, ";")
+	if !strings.Contains(name, ".") || strings.HasPrefix(name, ".") || strings.HasSuffix(name, ".") {
+		return false
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the CRID in its textual crid:// form.
+func (c CRID) String() string {
+	s := cridScheme + c.Authority + c.Data
+	if c.IMI != "" {
+		s += "#" + c.IMI
+	}
+	return s
+}
+
+// Broadcast returns the CRID as carried in a content_identifier_descriptor,
+// without the crid:// scheme.
+func (c CRID) Broadcast() string {
+	s := c.Authority + c.Data
+	if c.IMI != "" {
+		s += "#" + c.IMI
+	}
+	return s
+}
+
+// AppendDefaultAuthority appends a default_authority_descriptor for authority,
+// it belongs in the NIT transport stream loop or the SDT service loop.
+func AppendDefaultAuthority(buf []byte, authority string) ([]byte, error) {
+	return AppendDescriptor(buf, TagDefaultAuthority, []byte(authority))
+}
@@ -0,0 +1,35 @@
+package dvb
+
+import "testing"
+
+func TestParseCRID(t *testing.T) {
+	tests := []struct {
+		s         string
+		want      CRID
+		broadcast string
+	}{
+		{"crid://example.com/news", CRID{Authority: "example.com", Data: "/news"}, "example.com/news"},
+		{"CRID://Example.com/news#1", CRID{Authority: "Example.com", Data: "/news", IMI: "1"}, "Example.com/news#1"},
+		{"example.com;2026/a/b", CRID{Authority: "example.com;2026", Data: "/a/b"}, "example.com;2026/a/b"},
+		{" /news ", CRID{Data: "/news"}, "/news"},
+	}
+	for _, tt := range tests {
+		got, err := ParseCRID(tt.s)
+		if err != nil {
+			t.Errorf("ParseCRID(%q) error = %v", tt.s, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseCRID(%q) = %+v, want %+v", tt.s, got, tt.want)
+		}
+		if b := got.Broadcast(); b != tt.broadcast {
+			t.Errorf("ParseCRID(%q).Broadcast() = %q, want %q", tt.s, b, tt.broadcast)
+		}
+	}
+
+	for _, s := range []string{"", "crid://example.com", "example/news", "crid://.com/news", "crid://exa mple.com/news", "crid://example.com/"} {
+		if got, err := ParseCRID(s); err == nil {
+			t.Errorf("ParseCRID(%q) = %+v, want an error", s, got)
+		}
+	}
+}
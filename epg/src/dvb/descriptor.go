@@ -0,0 +1,51 @@
+// SI descriptor framing
+package dvb
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Descriptor tags (ISO/IEC 13818-1 table 2-45 and EN 300 468 table 12).
+const (
+	TagISO639Language       = 0x0A
+	TagNetworkName          = 0x40
+	TagServiceList          = 0x41
+	TagService              = 0x48
+	TagShortEvent           = 0x4D
+	TagExtendedEvent        = 0x4E
+	TagContent              = 0x54
+	TagParentalRating       = 0x55
+	TagTeletext             = 0x56
+	TagLocalTimeOffset      = 0x58
+	TagSubtitling           = 0x59
+	TagPrivateDataSpecifier = 0x5F
+	TagDefaultAuthority     = 0x73
+	TagContentIdentifier    = 0x76
+
+	// TagLogicalChannel is the EACEM logical_channel_descriptor, only defined
+	// after a private_data_specifier_descriptor for PrivateDataSpecifierEACEM.
+	TagLogicalChannel = 0x83
+)
+
+// PrivateDataSpecifierEACEM is the private_data_specifier of EACEM/EICTA
+// descriptors such as the logical_channel_descriptor (ETSI TS 101 162).
+const PrivateDataSpecifierEACEM = 0x00000028
+
+// MaxDescriptorLength is the largest descriptor body allowed by the 8-bit descriptor_length.
+const MaxDescriptorLength = 255
+
+// AppendDescriptor appends a descriptor with tag and body to buf.
+func AppendDescriptor(buf []byte, tag uint8, body []byte) ([]byte, error) {
+	if len(body) > MaxDescriptorLength {
+		return nil, fmt.Errorf("descriptor 0x%02X body is %d bytes, exceeds %d", tag, len(body), MaxDescriptorLength)
+	}
+	buf = append(buf, tag, byte(len(body)))
+	return append(buf, body...), nil
+}
+
+// AppendPrivateDataSpecifier appends a private_data_specifier_descriptor, which
+// scopes the private descriptors following it in the same loop.
+func AppendPrivateDataSpecifier(buf []byte, specifier uint32) ([]byte, error) {
+	return AppendDescriptor(buf, TagPrivateDataSpecifier, binary.BigEndian.AppendUint32(nil, specifier))
+}
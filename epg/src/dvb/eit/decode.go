@@ -0,0 +1,177 @@
+// EIT section decoding, the inverse of the encoder for importing off-air guides
+package eit
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"time"
+
+	"epg/src/dvb"
+)
+
+// Table is a decoded EIT section.
+type Table struct {
+	TableID           uint8
+	ServiceID         uint16
+	TransportStreamID uint16
+	OriginalNetworkID uint16
+	Version           uint8
+	Events            []DecodedEvent
+}
+
+// DecodedEvent is an entry of a decoded EIT event loop with the descriptors
+// the importer understands.
+type DecodedEvent struct {
+	EventID       uint16
+	StartTime     time.Time
+	Duration      time.Duration
+	RunningStatus uint8
+	// Language is the ISO 639-2 code of the short event descriptor.
+	Language           string
+	Title              string
+	ShortText          string
+	ExtendedText       string
+	Contents           []Content
+	ParentalRatings    []ParentalRating
+	ContentIdentifiers []ContentIdentifier
+}
+
+// Content is an entry of a content_descriptor.
+type Content struct {
+	Level1, Level2 uint8
+}
+
+// IsEIT reports whether tableID is an EIT, actual or other, present/following or schedule.
+func IsEIT(tableID uint8) bool {
+	return tableID >= 0x4E && tableID <= 0x6F
+}
+
+// Decode decodes an EIT section.
+func Decode(s dvb.Section) (*Table, error) {
+	if !IsEIT(s.TableID) {
+		return nil, fmt.Errorf("table_id 0x%02X is not an EIT", s.TableID)
+	}
+	if len(s.Payload) < tableHeaderSize {
+		return nil, fmt.Errorf("EIT section of service %d is too short", s.TableIDExtension)
+	}
+	t := &Table{
+		TableID:           s.TableID,
+		ServiceID:         s.TableIDExtension,
+		TransportStreamID: binary.BigEndian.Uint16(s.Payload),
+		OriginalNetworkID: binary.BigEndian.Uint16(s.Payload[2:]),
+		Version:           s.Version,
+	}
+
+	loop := s.Payload[tableHeaderSize:]
+	for len(loop) > 0 {
+		if len(loop) < eventHeaderSize {
+			return nil, fmt.Errorf("EIT service %d event loop is truncated", t.ServiceID)
+		}
+		e := DecodedEvent{
+			EventID:       binary.BigEndian.Uint16(loop),
+			StartTime:     dvb.DecodeMJDTime([5]byte(loop[2:7])),
+			Duration:      dvb.DecodeBCDDuration([3]byte(loop[7:10])),
+			RunningStatus: loop[10] >> 5,
+		}
+		length := int(binary.BigEndian.Uint16(loop[10:]) & 0x0FFF)
+		if len(loop) < eventHeaderSize+length {
+			return nil, fmt.Errorf("EIT service %d event %d descriptors are truncated", t.ServiceID, e.EventID)
+		}
+		if err := e.decodeDescriptors(loop[eventHeaderSize : eventHeaderSize+length]); err != nil {
+			return nil, fmt.Errorf("EIT service %d event %d: %w", t.ServiceID, e.EventID, err)
+		}
+		t.Events = append(t.Events, e)
+		loop = loop[eventHeaderSize+length:]
+	}
+	return t, nil
+}
+
+// decodeDescriptors fills in the event from its descriptor loop, skipping
+// descriptors it doesn't know.
+func (e *DecodedEvent) decodeDescriptors(b []byte) error {
+	var extended strings.Builder
+	for len(b) > 0 {
+		if len(b) < 2 || len(b) < 2+int(b[1]) {
+			return fmt.Errorf("descriptor loop is truncated")
+		}
+		tag, body := b[0], b[2:2+int(b[1])]
+		b = b[2+int(b[1]):]
+
+		switch tag {
+		case dvb.TagShortEvent:
+			// Only the first language is kept.
+			if e.Language != "" || len(body) < shortEventFixedSize {
+				continue
+			}
+			e.Language = string(body[:3])
+			name, rest, ok := lengthPrefixed(body[3:])
+			text, _, ok2 := lengthPrefixed(rest)
+			if !ok || !ok2 {
+				return fmt.Errorf("short_event_descriptor is truncated")
+			}
+			e.Title, e.ShortText = dvb.DecodeText(name), dvb.DecodeText(text)
+		case dvb.TagExtendedEvent:
+			if len(body) < extendedEventFixedSize {
+				return fmt.Errorf("extended_event_descriptor is truncated")
+			}
+			if e.Language != "" && string(body[1:4]) != e.Language {
+				continue
+			}
+			items, rest, ok := lengthPrefixed(body[4:])
+			text, _, ok2 := lengthPrefixed(rest)
+			if !ok || !ok2 {
+				return fmt.Errorf("extended_event_descriptor is truncated")
+			}
+			for len(items) > 0 {
+				description, rest, ok := lengthPrefixed(items)
+				item, rest, ok2 := lengthPrefixed(rest)
+				if !ok || !ok2 {
+					return fmt.Errorf("extended_event_descriptor items are truncated")
+				}
+				fmt.Fprintf(&extended, "%s: %s\n", dvb.DecodeText(description), dvb.DecodeText(item))
+				items = rest
+			}
+			extended.WriteString(dvb.DecodeText(text))
+		case dvb.TagContent:
+			for ; len(body) >= 2; body = body[2:] {
+				e.Contents = append(e.Contents, Content{Level1: body[0] >> 4, Level2: body[0] & 0x0F})
+			}
+		case dvb.TagParentalRating:
+			for ; len(body) >= 4; body = body[4:] {
+				e.ParentalRatings = append(e.ParentalRatings, ParentalRating{CountryCode: string(body[:3]), Rating: body[3]})
+			}
+		case dvb.TagContentIdentifier:
+			for len(body) > 0 {
+				cridType, location := body[0]>>2, body[0]&0x03
+				switch location {
+				case 0:
+					crid, rest, ok := lengthPrefixed(body[1:])
+					if !ok {
+						return fmt.Errorf("content_identifier_descriptor is truncated")
+					}
+					e.ContentIdentifiers = append(e.ContentIdentifiers, ContentIdentifier{Type: cridType, CRID: string(crid)})
+					body = rest
+				case 1:
+					// A crid_ref into a CRI table, which we don't decode.
+					if len(body) < 3 {
+						return fmt.Errorf("content_identifier_descriptor is truncated")
+					}
+					body = body[3:]
+				default:
+					body = nil
+				}
+			}
+		}
+	}
+	e.ExtendedText = extended.String()
+	return nil
+}
+
+// lengthPrefixed splits an 8-bit length prefixed field off b.
+func lengthPrefixed(b []byte) (field, rest []byte, ok bool) {
+	if len(b) < 1 || len(b) < 1+int(b[0]) {
+		return nil, nil, false
+	}
+	return b[1 : 1+int(b[0])], b[1+int(b[0]):], true
+}
@@ -0,0 +1,162 @@
+package eit
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"epg/src/dvb"
+	"epg/src/model"
+)
+
+// decodeSections serialises and parses back sections, returning the events
+// of their decoded EITs.
+func decodeSections(t *testing.T, svc Service, sections []dvb.Section) []DecodedEvent {
+	t.Helper()
+	var events []DecodedEvent
+	for _, s := range sections {
+		b, err := s.Bytes()
+		if err != nil {
+			t.Fatalf("Bytes() error = %v", err)
+		}
+		parsed, err := dvb.ParseSection(b)
+		if err != nil {
+			t.Fatalf("ParseSection() error = %v", err)
+		}
+		table, err := Decode(parsed)
+		if err != nil {
+			t.Fatalf("Decode() error = %v", err)
+		}
+		if table.ServiceID != svc.ServiceID || table.TransportStreamID != svc.TransportStreamID ||
+			table.OriginalNetworkID != svc.OriginalNetworkID {
+			t.Errorf("Decode() service %d/%d/%d, want %d/%d/%d", table.ServiceID, table.TransportStreamID, table.OriginalNetworkID,
+				svc.ServiceID, svc.TransportStreamID, svc.OriginalNetworkID)
+		}
+		events = append(events, table.Events...)
+	}
+	return events
+}
+
+func TestPresentFollowingRoundTrip(t *testing.T) {
+	svc := testService
+	svc.CountryCode = "AU"
+	svc.DefaultAuthority = "example.com"
+	noAuthority := svc
+	noAuthority.DefaultAuthority = ""
+
+	start := testNow.Add(-30 * time.Minute)
+	end := testNow.Add(time.Hour)
+	news := model.Genre{GenreID: 2, NibbleLevel1: 0x2, NibbleLevel2: 0x1}
+	sport := model.Genre{GenreID: 5, NibbleLevel1: 0x4, NibbleLevel2: 0x0}
+	undefined := model.Genre{GenreID: 1, NibbleLevel1: 0x0, NibbleLevel2: 0x0}
+
+	tests := []struct {
+		name  string
+		svc   Service
+		event model.Event
+		want  DecodedEvent
+	}{
+		{
+			name:  "title only",
+			svc:   svc,
+			event: model.Event{DVBEventID: 1, StartTime: start, EndTime: end, Title: "News", Genre: undefined},
+			want:  DecodedEvent{EventID: 1, StartTime: start, Duration: 90 * time.Minute, RunningStatus: RunningStatusRunning, Language: "eng", Title: "News"},
+		},
+		{
+			name: "texts in another zone",
+			svc:  svc,
+			event: model.Event{DVBEventID: 2, StartTime: start.In(time.FixedZone("AEDT", 11*3600)), EndTime: end, Title: "Café",
+				ShortDescription: ptr("Short"), ExtendedDescription: ptr("Extended description"), Genre: undefined},
+			want: DecodedEvent{EventID: 2, StartTime: start, Duration: 90 * time.Minute, RunningStatus: RunningStatusRunning, Language: "eng",
+				Title: "Café", ShortText: "Short", ExtendedText: "Extended description"},
+		},
+		{
+			name: "genres",
+			svc:  svc,
+			event: model.Event{DVBEventID: 3, StartTime: start, EndTime: end, Title: "Sport", Genre: sport,
+				EventGenres: []model.EventGenre{{Genre: news}, {Genre: sport}, {Genre: undefined}}},
+			want: DecodedEvent{EventID: 3, StartTime: start, Duration: 90 * time.Minute, RunningStatus: RunningStatusRunning, Language: "eng",
+				Title: "Sport", Contents: []Content{{Level1: 0x4, Level2: 0x0}, {Level1: 0x2, Level2: 0x1}}},
+		},
+		{
+			name: "ratings",
+			svc:  svc,
+			event: model.Event{DVBEventID: 4, StartTime: start, EndTime: end, Title: "Film", Genre: undefined,
+				EventRatings: []model.EventRating{rated("GB", 12), rated("AU", 15), rated("AU", 18), rated("AU", 0), rated("ZZ", 15)}},
+			want: DecodedEvent{EventID: 4, StartTime: start, Duration: 90 * time.Minute, RunningStatus: RunningStatusRunning, Language: "eng",
+				Title: "Film", ParentalRatings: []ParentalRating{{CountryCode: "AUS", Rating: 15}, {CountryCode: "GBR", Rating: 9}}},
+		},
+		{
+			name: "CRIDs",
+			svc:  svc,
+			event: model.Event{DVBEventID: 5, StartTime: start, EndTime: end, Title: "Series", Genre: undefined,
+				ProgrammeCRID: ptr("crid://other.org/prog1#imi"), SeriesCRID: ptr("/series1#imi")},
+			want: DecodedEvent{EventID: 5, StartTime: start, Duration: 90 * time.Minute, RunningStatus: RunningStatusRunning, Language: "eng",
+				Title: "Series", ContentIdentifiers: []ContentIdentifier{
+					{Type: CRIDTypeProgramme, CRID: "other.org/prog1#imi"},
+					{Type: CRIDTypeSeries, CRID: "example.com/series1"},
+				}},
+		},
+		{
+			name: "unresolvable CRIDs skipped",
+			svc:  noAuthority,
+			event: model.Event{DVBEventID: 6, StartTime: start, EndTime: end, Title: "Series", Genre: undefined,
+				ProgrammeCRID: ptr("/prog1"), SeriesCRID: ptr("not a crid")},
+			want: DecodedEvent{EventID: 6, StartTime: start, Duration: 90 * time.Minute, RunningStatus: RunningStatusRunning, Language: "eng", Title: "Series"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sections, err := tt.svc.PresentFollowing([]model.Event{tt.event}, testNow)
+			if err != nil {
+				t.Fatalf("PresentFollowing() error = %v", err)
+			}
+			events := decodeSections(t, tt.svc, sections)
+			if len(events) != 1 {
+				t.Fatalf("decoded %d events, want 1", len(events))
+			}
+			if !reflect.DeepEqual(events[0], tt.want) {
+				t.Errorf("decoded event\n%+v\nwant\n%+v", events[0], tt.want)
+			}
+		})
+	}
+}
+
+func TestScheduleRoundTrip(t *testing.T) {
+	long := strings.Repeat("A long extended description. ", 40)
+	var events []model.Event
+	for i := 0; i < 48; i++ {
+		start := testNow.Truncate(time.Hour).Add(time.Duration(i) * time.Hour)
+		events = append(events, model.Event{
+			DVBEventID:          uint16(100 + i),
+			StartTime:           start,
+			EndTime:             start.Add(time.Hour),
+			Title:               "Event",
+			ExtendedDescription: &long,
+		})
+	}
+
+	tables, err := testService.Schedule(events, testNow, 2)
+	if err != nil {
+		t.Fatalf("Schedule() error = %v", err)
+	}
+	var decoded []DecodedEvent
+	for _, sections := range tables {
+		decoded = append(decoded, decodeSections(t, testService, sections)...)
+	}
+	// The event started at 09:00 is still running at now.
+	want := events[:39]
+	if len(decoded) != len(want) {
+		t.Fatalf("decoded %d events, want %d", len(decoded), len(want))
+	}
+	for i, e := range decoded {
+		if e.EventID != want[i].DVBEventID || !e.StartTime.Equal(want[i].StartTime) || e.Duration != time.Hour {
+			t.Errorf("event %d = %d at %v for %v, want %d at %v for 1h", i, e.EventID, e.StartTime, e.Duration,
+				want[i].DVBEventID, want[i].StartTime)
+		}
+		if e.RunningStatus != RunningStatusUndefined || e.Title != "Event" || e.ExtendedText != long {
+			t.Errorf("event %d running status %d, title %q, extended text %q", i, e.RunningStatus, e.Title, e.ExtendedText)
+		}
+	}
+}
@@ -0,0 +1,312 @@
+// event descriptors built from model.Event
+package eit
+
+import (
+	"sort"
+	"strings"
+
+	"epg/src/dvb"
+	"epg/src/model"
+)
+
+const (
+	// shortEventFixedSize is ISO_639_language_code, event_name_length and text_length.
+	shortEventFixedSize = 5
+	// extendedEventFixedSize is descriptor_number/last_descriptor_number,
+	// ISO_639_language_code, length_of_items and text_length.
+	extendedEventFixedSize = 6
+	// maxExtendedDescriptors is the number of values descriptor_number can take.
+	maxExtendedDescriptors = 16
+	// contentEntrySize is content_nibble_level_1/2 and user_byte.
+	contentEntrySize = 2
+	// parentalRatingEntrySize is country_code and rating.
+	parentalRatingEntrySize = 4
+)
+
+// eventDescriptors builds the descriptor loop of an event.
+func (svc Service) eventDescriptors(e *model.Event) ([]byte, error) {
+	country := dvb.LookupCountry(svc.CountryCode)
+
+	buf, err := appendShortEvent(nil, country, e)
+	if err != nil {
+		return nil, err
+	}
+	buf, err = appendExtendedEvents(buf, country, e)
+	if err != nil {
+		return nil, err
+	}
+	buf, err = appendContent(buf, e)
+	if err != nil {
+		return nil, err
+	}
+	buf, err = appendParentalRating(buf, e)
+	if err != nil {
+		return nil, err
+	}
+	return appendContentIdentifier(buf, svc.DefaultAuthority, e)
+}
+
+// appendShortEvent appends a short_event_descriptor carrying the event title and
+// short description, the title takes precedence when both do not fit.
+func appendShortEvent(buf []byte, country dvb.CountryInfo, e *model.Event) ([]byte, error) {
+	budget := dvb.MaxDescriptorLength - shortEventFixedSize
+	name, _ := dvb.EncodeTextLimit(e.Title, country.Charset, budget)
+	var text []byte
+	if e.ShortDescription != nil {
+		text, _ = dvb.EncodeTextLimit(*e.ShortDescription, country.Charset, budget-len(name))
+	}
+
+	body := make([]byte, 0, shortEventFixedSize+len(name)+len(text))
+	body = append(body, languageCode(country)...)
+	body = append(body, byte(len(name)))
+	body = append(body, name...)
+	body = append(body, byte(len(text)))
+	body = append(body, text...)
+	return dvb.AppendDescriptor(buf, dvb.TagShortEvent, body)
+}
+
+// appendExtendedEvents appends the extended description split across as many
+// chained extended_event_descriptors as needed, up to the 16 the numbering allows.
+func appendExtendedEvents(buf []byte, country dvb.CountryInfo, e *model.Event) ([]byte, error) {
+	chunks, _ := extendedText(e, country.Charset)
+	if len(chunks) == 0 {
+		return buf, nil
+	}
+
+	last := byte(len(chunks) - 1)
+	for i, chunk := range chunks {
+		body := make([]byte, 0, extendedEventFixedSize+len(chunk))
+		body = append(body, byte(i)<<4|last)
+		body = append(body, languageCode(country)...)
+		// length_of_items: no itemised description
+		body = append(body, 0)
+		body = append(body, byte(len(chunk)))
+		body = append(body, chunk...)
+
+		var err error
+		buf, err = dvb.AppendDescriptor(buf, dvb.TagExtendedEvent, body)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return buf, nil
+}
+
+// extendedText splits the extended description of an event into the encoded text
+// of each extended_event_descriptor, also returning the characters each one carries.
+func extendedText(e *model.Event, cs dvb.Charset) (chunks [][]byte, texts []string) {
+	if e.ExtendedDescription == nil {
+		return nil, nil
+	}
+	rest := *e.ExtendedDescription
+	for rest != "" && len(chunks) < maxExtendedDescriptors {
+		chunk, remaining := dvb.EncodeTextLimit(rest, cs, dvb.MaxDescriptorLength-extendedEventFixedSize)
+		if len(remaining) == len(rest) {
+			break
+		}
+		chunks = append(chunks, chunk)
+		texts = append(texts, rest[:len(rest)-len(remaining)])
+		rest = remaining
+	}
+	return chunks, texts
+}
+
+// TextOverflow reports which texts of an event would be cut short on air in
+// the character tables of a country: the title, the short description sharing
+// the short_event_descriptor with it, and the extended description.
+func TextOverflow(e *model.Event, countryCode string) (title, short, extended bool) {
+	cs := dvb.LookupCountry(countryCode).Charset
+	budget := dvb.MaxDescriptorLength - shortEventFixedSize
+	name, rest := dvb.EncodeTextLimit(e.Title, cs, budget)
+	title = rest != ""
+	if e.ShortDescription != nil && *e.ShortDescription != "" {
+		_, rest = dvb.EncodeTextLimit(*e.ShortDescription, cs, budget-len(name))
+		short = rest != ""
+	}
+	if e.ExtendedDescription != nil {
+		_, texts := extendedText(e, cs)
+		extended = strings.Join(texts, "") != *e.ExtendedDescription
+	}
+	return title, short, extended
+}
+
+// appendContent appends a content_descriptor with one content nibble pair per genre
+// of the event, it is left out when the event has no defined genre.
+func appendContent(buf []byte, e *model.Event) ([]byte, error) {
+	genres := ContentGenres(e)
+	if len(genres) == 0 {
+		return buf, nil
+	}
+	if max := dvb.MaxDescriptorLength / contentEntrySize; len(genres) > max {
+		genres = genres[:max]
+	}
+
+	body := make([]byte, 0, len(genres)*contentEntrySize)
+	for _, g := range genres {
+		// user_byte is not used
+		body = append(body, g.NibbleLevel1<<4|g.NibbleLevel2&0x0F, 0)
+	}
+	return dvb.AppendDescriptor(buf, dvb.TagContent, body)
+}
+
+// ContentGenres returns the primary genre of an event followed by its additional
+// EventGenres, without duplicates and skipping undefined content (nibble level 1 of 0).
+// Genre and EventGenres.Genre must be loaded.
+func ContentGenres(e *model.Event) []model.Genre {
+	candidates := []model.Genre{e.Genre}
+	for _, eg := range e.EventGenres {
+		candidates = append(candidates, eg.Genre)
+	}
+
+	var genres []model.Genre
+	seen := map[uint]bool{}
+	for _, g := range candidates {
+		if g.GenreID == 0 || g.NibbleLevel1 == 0 || seen[g.GenreID] {
+			continue
+		}
+		seen[g.GenreID] = true
+		genres = append(genres, g)
+	}
+	return genres
+}
+
+// ParentalRating is one country entry of a parental_rating_descriptor.
+type ParentalRating struct {
+	// CountryCode is the ISO 3166 alpha-3 code of the rating system's country.
+	CountryCode string
+	// Rating is the minimum age minus 3, or 0 when no minimum age applies.
+	Rating uint8
+}
+
+// ParentalRatings returns one entry per country the event is rated in, using the
+// highest minimum age when an event carries several ratings from one country.
+// EventRatings.RatingValue.RatingSystem.Country must be loaded.
+func ParentalRatings(e *model.Event) []ParentalRating {
+	var ratings []ParentalRating
+	index := map[string]int{}
+	for _, er := range e.EventRatings {
+		alpha3 := dvb.LookupCountry(er.RatingValue.RatingSystem.Country.CountryCode).Alpha3
+		if alpha3 == "" {
+			continue
+		}
+		rating := RatingFromMinAge(er.RatingValue.MinAge)
+		if i, ok := index[alpha3]; ok {
+			if rating > ratings[i].Rating {
+				ratings[i].Rating = rating
+			}
+			continue
+		}
+		index[alpha3] = len(ratings)
+		ratings = append(ratings, ParentalRating{CountryCode: alpha3, Rating: rating})
+	}
+	sort.Slice(ratings, func(i, j int) bool { return ratings[i].CountryCode < ratings[j].CountryCode })
+	return ratings
+}
+
+// RatingFromMinAge converts a minimum viewing age to the DVB rating value
+// (rating = min_age - 3). Ages below 4 have no DVB rating and map to 0 (undefined),
+// ages above 18 are clamped to 0x0F.
+func RatingFromMinAge(minAge uint) uint8 {
+	switch {
+	case minAge < 4:
+		return 0
+	case minAge > 18:
+		return 0x0F
+	default:
+		return uint8(minAge - 3)
+	}
+}
+
+// appendParentalRating appends a parental_rating_descriptor with an entry per
+// rated country, it is left out when the event has no ratings.
+func appendParentalRating(buf []byte, e *model.Event) ([]byte, error) {
+	ratings := ParentalRatings(e)
+	if len(ratings) == 0 {
+		return buf, nil
+	}
+	if max := dvb.MaxDescriptorLength / parentalRatingEntrySize; len(ratings) > max {
+		ratings = ratings[:max]
+	}
+
+	body := make([]byte, 0, len(ratings)*parentalRatingEntrySize)
+	for _, r := range ratings {
+		body = append(body, r.CountryCode...)
+		body = append(body, r.Rating)
+	}
+	return dvb.AppendDescriptor(buf, dvb.TagParentalRating, body)
+}
+
+// CRID types carried in content_identifier_descriptor (ETSI TS 102 323 table 12).
+const (
+	CRIDTypeProgramme = 0x01
+	CRIDTypeSeries    = 0x02
+)
+
+// ContentIdentifier is one CRID entry of a content_identifier_descriptor.
+type ContentIdentifier struct {
+	Type uint8
+	// CRID is the broadcast form, see dvb.CRID.Broadcast.
+	CRID string
+}
+
+// ContentIdentifiers returns the programme and series CRIDs of an event in their
+// broadcast form. Every CRID is broadcast with its authority, a relative one
+// taking defaultAuthority, so it resolves without a default_authority_descriptor.
+// Series CRIDs identify a group rather than an instance so any IMI on them is
+// dropped. Malformed or oversized CRIDs are skipped.
+func ContentIdentifiers(e *model.Event, defaultAuthority string) []ContentIdentifier {
+	var ids []ContentIdentifier
+	for _, entry := range []struct {
+		crid     *string
+		cridType uint8
+	}{
+		{e.ProgrammeCRID, CRIDTypeProgramme},
+		{e.SeriesCRID, CRIDTypeSeries},
+	} {
+		if entry.crid == nil || *entry.crid == "" {
+			continue
+		}
+		crid, err := dvb.ParseCRID(*entry.crid)
+		if err != nil {
+			continue
+		}
+		// A relative CRID cannot be resolved without a default authority.
+		if crid.Authority == "" {
+			if defaultAuthority == "" {
+				continue
+			}
+			crid.Authority = defaultAuthority
+		}
+		if entry.cridType == CRIDTypeSeries {
+			crid.IMI = ""
+		}
+		broadcast := crid.Broadcast()
+		if len(broadcast) > dvb.MaxDescriptorLength-2 {
+			continue
+		}
+		ids = append(ids, ContentIdentifier{Type: entry.cridType, CRID: broadcast})
+	}
+	return ids
+}
+
+// appendContentIdentifier appends a content_identifier_descriptor carrying the
+// event's CRIDs inline (crid_location 0), it is left out when there are none.
+func appendContentIdentifier(buf []byte, defaultAuthority string, e *model.Event) ([]byte, error) {
+	ids := ContentIdentifiers(e, defaultAuthority)
+	if len(ids) == 0 {
+		return buf, nil
+	}
+
+	var body []byte
+	for _, id := range ids {
+		// crid_type(6) crid_location(2)=0, crid_length, crid_byte
+		body = append(body, id.Type<<2, byte(len(id.CRID)))
+		body = append(body, id.CRID...)
+	}
+	return dvb.AppendDescriptor(buf, dvb.TagContentIdentifier, body)
+}
+
+// languageCode returns the 3 byte ISO_639_language_code for a country.
+func languageCode(country dvb.CountryInfo) []byte {
+	return []byte(country.Language)[:3]
+}
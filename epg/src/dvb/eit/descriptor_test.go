@@ -0,0 +1,230 @@
+package eit
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"epg/src/model"
+)
+
+// descriptor is a descriptor of a descriptor loop.
+type descriptor struct {
+	Tag  uint8
+	Body []byte
+}
+
+// splitDescriptors returns the descriptors of a descriptor loop.
+func splitDescriptors(t *testing.T, loop []byte) []descriptor {
+	t.Helper()
+	var descriptors []descriptor
+	for len(loop) > 0 {
+		if len(loop) < 2 || len(loop) < 2+int(loop[1]) {
+			t.Fatalf("descriptor loop truncated: % X", loop)
+		}
+		end := 2 + int(loop[1])
+		descriptors = append(descriptors, descriptor{loop[0], loop[2:end]})
+		loop = loop[end:]
+	}
+	return descriptors
+}
+
+// eventDescriptorsOf returns the descriptors of an event as svc carries them.
+func eventDescriptorsOf(t *testing.T, svc Service, e model.Event) []descriptor {
+	t.Helper()
+	loop, err := svc.eventDescriptors(&e)
+	if err != nil {
+		t.Fatalf("eventDescriptors() error = %v", err)
+	}
+	return splitDescriptors(t, loop)
+}
+
+func ptr(s string) *string { return &s }
+
+func TestShortEvent(t *testing.T) {
+	german := testService
+	german.CountryCode = "DE"
+	long := strings.Repeat("a", 300)
+
+	tests := []struct {
+		name  string
+		svc   Service
+		event model.Event
+		want  []byte
+	}{
+		{"title", testService, model.Event{Title: "News"}, []byte("eng\x04News\x00")},
+		{"short description", testService, model.Event{Title: "News", ShortDescription: ptr("Short")}, []byte("eng\x04News\x05Short")},
+		{"language and table", german, model.Event{Title: "Grüße"}, []byte("ger\x08\x10\x00\x01Gr\xFC\xDFe\x00")},
+		// The title takes precedence when both do not fit.
+		{"title too long", testService, model.Event{Title: long, ShortDescription: ptr("Short")}, []byte("eng\xFA" + long[:250] + "\x00")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			descriptors := eventDescriptorsOf(t, tt.svc, tt.event)
+			if len(descriptors) != 1 || descriptors[0].Tag != 0x4D {
+				t.Fatalf("descriptors = %+v, want one short_event_descriptor", descriptors)
+			}
+			if !bytes.Equal(descriptors[0].Body, tt.want) {
+				t.Errorf("short_event_descriptor = % X, want % X", descriptors[0].Body, tt.want)
+			}
+		})
+	}
+}
+
+// repeat returns a slice of n times s.
+func repeat(s string, n int) []string {
+	ss := make([]string, n)
+	for i := range ss {
+		ss[i] = s
+	}
+	return ss
+}
+
+func TestExtendedEvent(t *testing.T) {
+	// extended_event_descriptors carry up to 249 characters each.
+	tests := []struct {
+		name        string
+		description string
+		want        []string
+	}{
+		{"empty", "", nil},
+		{"one", "Extended", []string{"Extended"}},
+		{"chained", strings.Repeat("x", 600), append(repeat(strings.Repeat("x", 249), 2), strings.Repeat("x", 102))},
+		{"at most 16", strings.Repeat("x", 17*249), repeat(strings.Repeat("x", 249), 16)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			descriptors := eventDescriptorsOf(t, testService, model.Event{Title: "News", ExtendedDescription: &tt.description})
+			var got []string
+			for i, d := range descriptors[1:] {
+				number := []byte{byte(i)<<4 | byte(len(tt.want)-1)}
+				header := append(number, "eng\x00"...)
+				if d.Tag != 0x4E || !bytes.Equal(d.Body[:5], header) || int(d.Body[5]) != len(d.Body)-6 {
+					t.Errorf("descriptor %d = %X % X, want extended_event_descriptor % X", i, d.Tag, d.Body[:6], header)
+					continue
+				}
+				got = append(got, string(d.Body[6:]))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("extended texts of %d characters = %q, want %q", len(tt.description), got, tt.want)
+			}
+		})
+	}
+}
+
+// bodiesOf returns the bodies of the descriptors with tag.
+func bodiesOf(descriptors []descriptor, tag uint8) [][]byte {
+	var bodies [][]byte
+	for _, d := range descriptors {
+		if d.Tag == tag {
+			bodies = append(bodies, d.Body)
+		}
+	}
+	return bodies
+}
+
+func TestContent(t *testing.T) {
+	undefined := model.Genre{GenreID: 1, NibbleLevel1: 0x0, NibbleLevel2: 0x0}
+	news := model.Genre{GenreID: 2, NibbleLevel1: 0x2, NibbleLevel2: 0x1}
+	sport := model.Genre{GenreID: 5, NibbleLevel1: 0x4, NibbleLevel2: 0x0}
+
+	tests := []struct {
+		name  string
+		event model.Event
+		want  [][]byte
+	}{
+		{"no genre", model.Event{}, nil},
+		{"undefined", model.Event{Genre: undefined}, nil},
+		{"primary", model.Event{Genre: news}, [][]byte{{0x21, 0x00}}},
+		{
+			"primary first without duplicates",
+			model.Event{Genre: sport, EventGenres: []model.EventGenre{{Genre: news}, {Genre: sport}, {Genre: undefined}}},
+			[][]byte{{0x40, 0x00, 0x21, 0x00}},
+		},
+		{"additional only", model.Event{Genre: undefined, EventGenres: []model.EventGenre{{Genre: news}}}, [][]byte{{0x21, 0x00}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.event.Title = "News"
+			if got := bodiesOf(eventDescriptorsOf(t, testService, tt.event), 0x54); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("content_descriptors = % X, want % X", got, tt.want)
+			}
+		})
+	}
+}
+
+// rated returns an event rating of minAge in the rating system of a country.
+func rated(countryCode string, minAge uint) model.EventRating {
+	return model.EventRating{RatingValue: model.RatingValue{
+		MinAge:       minAge,
+		RatingSystem: model.RatingSystem{Country: model.Country{CountryCode: countryCode}},
+	}}
+}
+
+func TestParentalRating(t *testing.T) {
+	tests := []struct {
+		name    string
+		ratings []model.EventRating
+		want    [][]byte
+	}{
+		{"none", nil, nil},
+		{"one", []model.EventRating{rated("AU", 15)}, [][]byte{[]byte("AUS\x0C")}},
+		{
+			"highest per country, by country code",
+			[]model.EventRating{rated("GB", 12), rated("AU", 15), rated("AU", 18), rated("AU", 0)},
+			[][]byte{[]byte("AUS\x0FGBR\x09")},
+		},
+		{"unknown country", []model.EventRating{rated("ZZ", 15)}, nil},
+		{"no minimum age", []model.EventRating{rated("AU", 0)}, [][]byte{[]byte("AUS\x00")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := model.Event{Title: "Film", EventRatings: tt.ratings}
+			if got := bodiesOf(eventDescriptorsOf(t, testService, e), 0x55); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parental_rating_descriptors = % X, want % X", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRatingFromMinAge(t *testing.T) {
+	for _, tt := range []struct {
+		minAge uint
+		want   uint8
+	}{{0, 0}, {3, 0}, {4, 1}, {12, 9}, {18, 15}, {21, 15}} {
+		if got := RatingFromMinAge(tt.minAge); got != tt.want {
+			t.Errorf("RatingFromMinAge(%d) = %d, want %d", tt.minAge, got, tt.want)
+		}
+	}
+}
+
+func TestContentIdentifier(t *testing.T) {
+	tests := []struct {
+		name      string
+		authority string
+		programme *string
+		series    *string
+		want      [][]byte
+	}{
+		{"none", "example.com", nil, nil, nil},
+		{
+			"whole CRIDs",
+			"example.com", ptr("crid://other.org/prog1#imi"), ptr("crid://example.com/series1#imi"),
+			[][]byte{[]byte("\x04\x13other.org/prog1#imi\x08\x13example.com/series1")},
+		},
+		{"relative CRID", "example.com", ptr("/prog1"), nil, [][]byte{[]byte("\x04\x11example.com/prog1")}},
+		{"relative CRID without authority", "", ptr("/prog1"), ptr("crid://example.com/series1"), [][]byte{[]byte("\x08\x13example.com/series1")}},
+		{"malformed", "example.com", ptr("not a crid"), ptr(""), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := testService
+			svc.DefaultAuthority = tt.authority
+			e := model.Event{Title: "Series", ProgrammeCRID: tt.programme, SeriesCRID: tt.series}
+			if got := bodiesOf(eventDescriptorsOf(t, svc, e), 0x76); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("content_identifier_descriptors = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
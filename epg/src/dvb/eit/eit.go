@@ -0,0 +1,202 @@
+// EIT (Event Information Table) section encoder, ETSI EN 300 468 clause 5.2.4
+package eit
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"epg/src/dvb"
+	"epg/src/model"
+)
+
+const (
+	// PID carrying EIT sections.
+	PID = 0x12
+
+	// TableIDPresentFollowing is the table_id of the actual TS present/following EIT.
+	TableIDPresentFollowing = 0x4E
+
+	// eventHeaderSize is the fixed part of each entry in the event loop.
+	eventHeaderSize = 12
+	// tableHeaderSize is transport_stream_id, original_network_id,
+	// segment_last_section_number and last_table_id.
+	tableHeaderSize = 6
+	// maxDescriptorsLength is the 12-bit descriptors_loop_length limit.
+	maxDescriptorsLength = 0x0FFF
+)
+
+// Running status values (EN 300 468 table 6).
+const (
+	RunningStatusUndefined  = 0
+	RunningStatusNotRunning = 1
+	RunningStatusStartsSoon = 2
+	RunningStatusPausing    = 3
+	RunningStatusRunning    = 4
+	RunningStatusOffAir     = 5
+)
+
+// Service identifies the DVB service an EIT sub-table belongs to.
+type Service struct {
+	ServiceID         uint16
+	TransportStreamID uint16
+	OriginalNetworkID uint16
+	// CountryCode is the ISO 3166 alpha-2 code of the owning network, it selects
+	// the language and character table of event text.
+	CountryCode string
+	// DefaultAuthority is the CRID authority relative CRIDs of the service resolve against.
+	DefaultAuthority string
+}
+
+// NewService returns the EIT service identity of a channel, the channel's Network
+// and Network.Country must be loaded.
+// Each of our networks carries a single transport stream so Network.ServiceID is used
+// for both the original_network_id and the transport_stream_id.
+func NewService(channel model.Channel) Service {
+	return Service{
+		ServiceID:         uint16(channel.ServiceID),
+		TransportStreamID: uint16(channel.Network.ServiceID),
+		OriginalNetworkID: uint16(channel.Network.ServiceID),
+		CountryCode:       channel.Network.Country.CountryCode,
+		DefaultAuthority:  DefaultAuthority(channel),
+	}
+}
+
+// DefaultAuthority returns the CRID authority of a channel, taken from the
+// channel's AuthorityMeta CRID or else the network's CridDescription when that
+// holds an authority. It is empty when neither does.
+func DefaultAuthority(channel model.Channel) string {
+	if channel.AuthorityMeta != nil {
+		if crid, err := dvb.ParseCRID(*channel.AuthorityMeta); err == nil && crid.Authority != "" {
+			return crid.Authority
+		}
+	}
+	return NetworkAuthority(channel.Network)
+}
+
+// NetworkAuthority returns the CRID authority in a network's CridDescription,
+// or empty when it doesn't hold one.
+func NetworkAuthority(network model.Network) string {
+	authority := strings.TrimPrefix(strings.TrimSuffix(network.CridDescription, "/"), "crid://")
+	if dvb.ValidAuthority(authority) {
+		return authority
+	}
+	return ""
+}
+
+// SubTableKey returns the version tracking key of the service's sub-table tableID.
+func (svc Service) SubTableKey(tableID uint8) dvb.SubTableKey {
+	return dvb.SubTableKey{
+		TableID:          tableID,
+		TableIDExtension: svc.ServiceID,
+		Extra:            uint32(svc.TransportStreamID)<<16 | uint32(svc.OriginalNetworkID),
+	}
+}
+
+// ApplyVersions numbers each sub-table generated for the service using vt.
+func (svc Service) ApplyVersions(vt *dvb.VersionTracker, subTables ...[]dvb.Section) {
+	for _, sections := range subTables {
+		if len(sections) > 0 {
+			vt.Apply(svc.SubTableKey(sections[0].TableID), sections)
+		}
+	}
+}
+
+// PresentFollowing builds the two sections of the present/following sub-table for
+// svc at time now. Section 0 carries the event running at now and section 1 the
+// next event, either may be empty when there is no such event. Sections are
+// returned with version 0, see ApplyVersions.
+func (svc Service) PresentFollowing(events []model.Event, now time.Time) ([]dvb.Section, error) {
+	present, following := FindPresentFollowing(events, now)
+
+	sections := make([]dvb.Section, 2)
+	for i, entry := range []struct {
+		event         *model.Event
+		runningStatus uint8
+	}{
+		{present, RunningStatusRunning},
+		{following, RunningStatusNotRunning},
+	} {
+		payload := svc.tableHeader(1, TableIDPresentFollowing)
+		if entry.event != nil {
+			encoded, err := svc.encodeEvent(entry.event, entry.runningStatus)
+			if err != nil {
+				return nil, err
+			}
+			payload = append(payload, encoded...)
+		}
+		sections[i] = dvb.Section{
+			TableID:           TableIDPresentFollowing,
+			TableIDExtension:  svc.ServiceID,
+			CurrentNext:       true,
+			SectionNumber:     uint8(i),
+			LastSectionNumber: 1,
+			Payload:           payload,
+		}
+	}
+
+	return sections, nil
+}
+
+// FindPresentFollowing returns the event running at now and the one after it.
+func FindPresentFollowing(events []model.Event, now time.Time) (present, following *model.Event) {
+	sorted := sortEvents(events)
+	for i := range sorted {
+		e := &sorted[i]
+		if !e.EndTime.After(now) {
+			continue
+		}
+		if present == nil && !e.StartTime.After(now) {
+			present = e
+			continue
+		}
+		return present, e
+	}
+	return present, nil
+}
+
+// sortEvents returns a copy of events ordered by start time.
+func sortEvents(events []model.Event) []model.Event {
+	sorted := make([]model.Event, len(events))
+	copy(sorted, events)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].StartTime.Before(sorted[j].StartTime) })
+	return sorted
+}
+
+// tableHeader returns the fields following last_section_number in every EIT section.
+func (svc Service) tableHeader(segmentLastSectionNumber, lastTableID uint8) []byte {
+	buf := make([]byte, 0, tableHeaderSize)
+	buf = binary.BigEndian.AppendUint16(buf, svc.TransportStreamID)
+	buf = binary.BigEndian.AppendUint16(buf, svc.OriginalNetworkID)
+	return append(buf, segmentLastSectionNumber, lastTableID)
+}
+
+// encodeEvent encodes one entry of the EIT event loop.
+func (svc Service) encodeEvent(e *model.Event, runningStatus uint8) ([]byte, error) {
+	descriptors, err := svc.eventDescriptors(e)
+	if err != nil {
+		return nil, err
+	}
+	if len(descriptors) > maxDescriptorsLength {
+		return nil, fmt.Errorf("event %d descriptors are %d bytes, exceeds %d", e.EventID, len(descriptors), maxDescriptorsLength)
+	}
+
+	startTime := dvb.EncodeMJDTime(e.StartTime)
+	duration := dvb.EncodeBCDDuration(e.EndTime.Sub(e.StartTime))
+
+	buf := make([]byte, 0, eventHeaderSize+len(descriptors))
+	buf = binary.BigEndian.AppendUint16(buf, EventID(e))
+	buf = append(buf, startTime[:]...)
+	buf = append(buf, duration[:]...)
+	// running_status(3) free_CA_mode(1)=0 descriptors_loop_length(12)
+	buf = binary.BigEndian.AppendUint16(buf, uint16(runningStatus)<<13|uint16(len(descriptors)))
+	return append(buf, descriptors...), nil
+}
+
+// EventID returns the 16-bit DVB event_id of an event, allocated per channel
+// when the event is saved.
+func EventID(e *model.Event) uint16 {
+	return e.DVBEventID
+}
@@ -0,0 +1,103 @@
+package eit
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"epg/src/dvb"
+	"epg/src/model"
+)
+
+var testService = Service{
+	ServiceID:         1000,
+	TransportStreamID: 1,
+	OriginalNetworkID: 2,
+}
+
+var testNow = time.Date(2026, 10, 18, 9, 30, 0, 0, time.UTC)
+
+// event returns an event with a DVB event_id starting at start for d.
+func event(id uint16, start time.Time, d time.Duration) model.Event {
+	return model.Event{DVBEventID: id, StartTime: start, EndTime: start.Add(d)}
+}
+
+// loopEvent is the fixed part of an EIT event loop entry.
+type loopEvent struct {
+	EventID       uint16
+	StartTime     [5]byte
+	Duration      [3]byte
+	RunningStatus uint8
+}
+
+// entry returns the event loop entry expected for an event.
+func entry(e model.Event, runningStatus uint8) loopEvent {
+	return loopEvent{e.DVBEventID, dvb.EncodeMJDTime(e.StartTime), dvb.EncodeBCDDuration(e.EndTime.Sub(e.StartTime)), runningStatus}
+}
+
+// eventLoop returns the entries of the event loop of an EIT section payload,
+// skipping their descriptors.
+func eventLoop(payload []byte) []loopEvent {
+	var events []loopEvent
+	for loop := payload[tableHeaderSize:]; len(loop) >= eventHeaderSize; {
+		e := loopEvent{EventID: uint16(loop[0])<<8 | uint16(loop[1]), RunningStatus: loop[10] >> 5}
+		copy(e.StartTime[:], loop[2:7])
+		copy(e.Duration[:], loop[7:10])
+		events = append(events, e)
+		loop = loop[eventHeaderSize+(int(loop[10]&0x0F)<<8|int(loop[11])):]
+	}
+	return events
+}
+
+func TestPresentFollowing(t *testing.T) {
+	ended := event(1, testNow.Add(-2*time.Hour), time.Hour)
+	present := event(2, testNow.Add(-time.Hour), 90*time.Minute)
+	following := event(3, testNow.Add(30*time.Minute), time.Hour)
+	later := event(4, testNow.Add(90*time.Minute), time.Hour)
+	tests := []struct {
+		name   string
+		events []model.Event
+		want   [2][]loopEvent
+	}{
+		{"none", nil, [2][]loopEvent{}},
+		{
+			"present and following",
+			[]model.Event{later, following, ended, present},
+			[2][]loopEvent{{entry(present, RunningStatusRunning)}, {entry(following, RunningStatusNotRunning)}},
+		},
+		{
+			"between events",
+			[]model.Event{ended, following, later},
+			[2][]loopEvent{nil, {entry(following, RunningStatusNotRunning)}},
+		},
+		{
+			"last event",
+			[]model.Event{ended, present},
+			[2][]loopEvent{{entry(present, RunningStatusRunning)}, nil},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sections, err := testService.PresentFollowing(tt.events, testNow)
+			if err != nil {
+				t.Fatalf("PresentFollowing() error = %v", err)
+			}
+			if len(sections) != 2 {
+				t.Fatalf("PresentFollowing() returned %d sections, want 2", len(sections))
+			}
+			for i, s := range sections {
+				if s.TableID != TableIDPresentFollowing || s.TableIDExtension != testService.ServiceID || s.Version != 0 ||
+					!s.CurrentNext || s.SectionNumber != uint8(i) || s.LastSectionNumber != 1 {
+					t.Errorf("section %d = %+v", i, s)
+				}
+				if header := []byte{0x00, 0x01, 0x00, 0x02, 0x01, TableIDPresentFollowing}; !bytes.Equal(s.Payload[:tableHeaderSize], header) {
+					t.Errorf("section %d header = % X, want % X", i, s.Payload[:tableHeaderSize], header)
+				}
+				if got := eventLoop(s.Payload); !reflect.DeepEqual(got, tt.want[i]) {
+					t.Errorf("section %d events = %+v, want %+v", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
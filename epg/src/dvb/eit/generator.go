@@ -0,0 +1,99 @@
+// EIT generation for every channel in the database
+package eit
+
+import (
+	"log"
+	"time"
+
+	"epg/src/dvb"
+	"epg/src/model"
+
+	"gorm.io/gorm"
+)
+
+// Generator builds the serialised EIT sections of all channels from the
+// database, keeping version numbers across calls.
+type Generator struct {
+	db       *gorm.DB
+	days     int
+	versions *dvb.VersionTracker
+}
+
+// NewGenerator returns a Generator whose schedule covers days days.
+func NewGenerator(db *gorm.DB, days int) *Generator {
+	return &Generator{db: db, days: days, versions: dvb.NewVersionTracker()}
+}
+
+// PresentFollowing returns the present/following sections of every channel at now.
+func (g *Generator) PresentFollowing(now time.Time) ([][]byte, error) {
+	return g.generate(now, func(svc Service, events []model.Event) ([][]dvb.Section, error) {
+		sections, err := svc.PresentFollowing(events, now)
+		return [][]dvb.Section{sections}, err
+	})
+}
+
+// Schedule returns the schedule sections of every channel at now.
+func (g *Generator) Schedule(now time.Time) ([][]byte, error) {
+	return g.generate(now, func(svc Service, events []model.Event) ([][]dvb.Section, error) {
+		return svc.Schedule(events, now, g.days)
+	})
+}
+
+// generate serialises the sub-tables build returns for each channel, a
+// channel whose sections cannot be built is left out whole.
+func (g *Generator) generate(now time.Time, build func(Service, []model.Event) ([][]dvb.Section, error)) ([][]byte, error) {
+	var out [][]byte
+	err := g.forEachChannel(now, func(svc Service, events []model.Event) error {
+		subTables, err := build(svc, events)
+		if err != nil {
+			return err
+		}
+		svc.ApplyVersions(g.versions, subTables...)
+		var channelOut [][]byte
+		for _, sections := range subTables {
+			for i := range sections {
+				b, err := sections[i].Bytes()
+				if err != nil {
+					return err
+				}
+				channelOut = append(channelOut, b)
+			}
+		}
+		out = append(out, channelOut...)
+		return nil
+	})
+	return out, err
+}
+
+// forEachChannel calls fn with every channel's service and its events in the
+// EventWindow of now. A channel fn fails on is
+// logged and skipped, so one channel's bad data does not take the EIT of
+// every other service off air.
+func (g *Generator) forEachChannel(now time.Time, fn func(Service, []model.Event) error) error {
+	channels := []model.Channel{}
+	err := g.db.Preload("Network").Preload("Network.Country").Order("channel_id").Find(&channels).Error
+	if err != nil {
+		return err
+	}
+
+	from, to := EventWindow(now, g.days)
+
+	for _, channel := range channels {
+		events, err := model.FindChannelEvents(g.db, channel.ChannelID, from, to)
+		if err != nil {
+			return err
+		}
+		if err := fn(NewService(channel), events); err != nil {
+			log.Printf("eit: channel %d (service %d) skipped: %v", channel.ChannelID, channel.ServiceID, err)
+		}
+	}
+	return nil
+}
+
+// EventWindow returns the span of events the generator reads at now for a
+// schedule of days days, running a day past the schedule horizon so the p/f
+// table finds the event following the last one scheduled.
+func EventWindow(now time.Time, days int) (from, to time.Time) {
+	from, horizon := ScheduleWindow(now, days)
+	return from, horizon.Add(24 * time.Hour)
+}
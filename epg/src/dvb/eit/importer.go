@@ -0,0 +1,275 @@
+// EIT import into the events table
+package eit
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"epg/src/dvb"
+	"epg/src/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Importer collects decoded EIT sections and stores their events, matching
+// services to channels by service_id. Events are keyed on channel and start
+// time: an event already starting at that time on the channel is updated.
+type Importer struct {
+	db *gorm.DB
+	// CategoryID is given to created events, DVB has no equivalent of our categories.
+	CategoryID uint
+
+	events map[importKey]importedEvent
+
+	// Created, Updated and Skipped count the events of the last Commit,
+	// skipped ones belonging to services no channel carries.
+	Created, Updated, Skipped int
+}
+
+// importKey identifies an event in the captured stream.
+type importKey struct {
+	originalNetworkID, transportStreamID, serviceID, eventID uint16
+}
+
+type importedEvent struct {
+	key   importKey
+	event DecodedEvent
+}
+
+// NewImporter returns an Importer creating events in the first category.
+func NewImporter(db *gorm.DB) (*Importer, error) {
+	var category model.Category
+	err := db.Order("category_id").First(&category).Error
+	if err != nil {
+		return nil, fmt.Errorf("default category: %w", err)
+	}
+	return &Importer{db: db, CategoryID: category.CategoryID, events: map[importKey]importedEvent{}}, nil
+}
+
+// Add collects the events of a decoded section, later sections replace the
+// events of earlier ones with the same event_id.
+func (im *Importer) Add(t *Table) {
+	for _, e := range t.Events {
+		key := importKey{t.OriginalNetworkID, t.TransportStreamID, t.ServiceID, e.EventID}
+		im.events[key] = importedEvent{key: key, event: e}
+	}
+}
+
+// Commit stores the collected events in one transaction.
+func (im *Importer) Commit() error {
+	im.Created, im.Updated, im.Skipped = 0, 0, 0
+
+	collected := make([]importedEvent, 0, len(im.events))
+	for _, e := range im.events {
+		collected = append(collected, e)
+	}
+	sort.Slice(collected, func(i, j int) bool {
+		a, b := collected[i], collected[j]
+		if a.key.serviceID != b.key.serviceID {
+			return a.key.serviceID < b.key.serviceID
+		}
+		return a.event.StartTime.Before(b.event.StartTime)
+	})
+
+	return im.db.Transaction(func(tx *gorm.DB) error {
+		for _, e := range collected {
+			channel, ok, err := findServiceChannel(tx, e.key)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				im.Skipped++
+				continue
+			}
+			if err := im.store(tx, channel, &e.event); err != nil {
+				return fmt.Errorf("service %d event %d: %w", e.key.serviceID, e.key.eventID, err)
+			}
+		}
+		im.events = map[importKey]importedEvent{}
+		return nil
+	})
+}
+
+// findServiceChannel returns the channel carrying a service, preferring one
+// whose network matches the original_network_id as NewService assigns it.
+func findServiceChannel(tx *gorm.DB, key importKey) (model.Channel, bool, error) {
+	channels := []model.Channel{}
+	err := tx.Preload("Network").Where("service_id = ?", key.serviceID).Order("channel_id").Find(&channels).Error
+	if err != nil || len(channels) == 0 {
+		return model.Channel{}, false, err
+	}
+	for _, channel := range channels {
+		if uint16(channel.Network.ServiceID) == key.originalNetworkID {
+			return channel, true, nil
+		}
+	}
+	return channels[0], true, nil
+}
+
+// store creates or updates the event of a channel and replaces its genres and ratings.
+func (im *Importer) store(tx *gorm.DB, channel model.Channel, e *DecodedEvent) error {
+	channelID := channel.ChannelID
+	start := e.StartTime.UTC()
+	existing := []model.Event{}
+	err := tx.Where("channel_id = ? AND start_time = ?", channelID, start).Limit(1).Find(&existing).Error
+	if err != nil {
+		return err
+	}
+	created := len(existing) == 0
+	event := model.Event{ChannelID: channelID, StartTime: start, CategoryID: im.CategoryID}
+	if !created {
+		event = existing[0]
+	}
+
+	// The broadcast event_id is kept unless another event of the channel holds it.
+	event.DVBEventID = e.EventID
+	event.EndTime = start.Add(e.Duration)
+	event.Title = e.Title
+	event.ShortDescription = optionalText(e.ShortText)
+	event.ExtendedDescription = optionalText(e.ExtendedText)
+	event.ProgrammeCRID, event.SeriesCRID = nil, nil
+	authority := DefaultAuthority(channel)
+	for _, id := range e.ContentIdentifiers {
+		crid := importCRID(id.CRID, authority)
+		switch id.Type {
+		case CRIDTypeProgramme:
+			event.ProgrammeCRID = crid
+		case CRIDTypeSeries:
+			event.SeriesCRID = crid
+		}
+	}
+
+	var genres []uint
+	for _, c := range e.Contents {
+		if c.Level1 == 0 {
+			continue
+		}
+		genreID, err := upsertGenre(tx, c)
+		if err != nil {
+			return err
+		}
+		genres = append(genres, genreID)
+	}
+	if len(genres) == 0 {
+		genreID, err := upsertGenre(tx, Content{})
+		if err != nil {
+			return err
+		}
+		genres = append(genres, genreID)
+	}
+	event.GenreID = genres[0]
+
+	if err := tx.Omit(clause.Associations).Save(&event).Error; err != nil {
+		return err
+	}
+	if created {
+		im.Created++
+	} else {
+		im.Updated++
+	}
+
+	if err := tx.Where("event_id = ?", event.EventID).Delete(&model.EventGenre{}).Error; err != nil {
+		return err
+	}
+	seen := map[uint]bool{genres[0]: true}
+	for _, genreID := range genres[1:] {
+		if seen[genreID] {
+			continue
+		}
+		seen[genreID] = true
+		err := tx.Omit(clause.Associations).Create(&model.EventGenre{EventID: event.EventID, GenreID: genreID}).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Where("event_id = ?", event.EventID).Delete(&model.EventRating{}).Error; err != nil {
+		return err
+	}
+	for _, rating := range e.ParentalRatings {
+		ratingValueID, ok, err := findRatingValue(tx, rating)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		err = tx.Omit(clause.Associations).Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&model.EventRating{EventID: event.EventID, RatingValueID: ratingValueID}).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// upsertGenre returns the genre of a content nibble pair, adding it when the
+// genres table lacks it.
+func upsertGenre(tx *gorm.DB, c Content) (uint, error) {
+	genres := []model.Genre{}
+	err := tx.Where("nibble_level_1 = ? AND nibble_level_2 = ?", c.Level1, c.Level2).Order("genre_id").Limit(1).Find(&genres).Error
+	if err != nil {
+		return 0, err
+	}
+	if len(genres) > 0 {
+		return genres[0].GenreID, nil
+	}
+	genre := model.Genre{
+		NibbleLevel1: c.Level1,
+		NibbleLevel2: c.Level2,
+		Description:  fmt.Sprintf("Content 0x%X%X", c.Level1, c.Level2),
+	}
+	err = tx.Omit(clause.Associations).Create(&genre).Error
+	return genre.GenreID, err
+}
+
+// findRatingValue returns the rating of the country's rating system with the
+// highest minimum age not above the DVB rating's, see RatingFromMinAge.
+// Undefined and broadcaster defined ratings have none.
+func findRatingValue(tx *gorm.DB, rating ParentalRating) (uint, bool, error) {
+	if rating.Rating == 0 || rating.Rating > 0x0F {
+		return 0, false, nil
+	}
+	alpha2, ok := dvb.LookupAlpha3(rating.CountryCode)
+	if !ok {
+		return 0, false, nil
+	}
+	values := []model.RatingValue{}
+	err := tx.Joins("JOIN rating_systems ON rating_systems.rating_system_id = rating_values.rating_system_id").
+		Joins("JOIN countries ON countries.country_id = rating_systems.country_id").
+		Where("countries.country_code = ? AND rating_values.min_age <= ?", alpha2, uint(rating.Rating)+3).
+		Order("rating_values.min_age DESC, rating_values.rating_value_id").
+		Limit(1).Find(&values).Error
+	if err != nil || len(values) == 0 {
+		return 0, false, err
+	}
+	return values[0].RatingValueID, true, nil
+}
+
+// importCRID returns a broadcast CRID in the crid:// form stored on events.
+// Relative CRIDs resolve against the channel's default authority, or are kept
+// relative when it has none.
+func importCRID(broadcast, defaultAuthority string) *string {
+	crid, err := dvb.ParseCRID(broadcast)
+	if err != nil {
+		return nil
+	}
+	if crid.Authority == "" {
+		crid.Authority = defaultAuthority
+	}
+	s := crid.String()
+	if crid.Authority == "" {
+		s = strings.TrimPrefix(s, "crid://")
+	}
+	return &s
+}
+
+// optionalText returns nil for empty text.
+func optionalText(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
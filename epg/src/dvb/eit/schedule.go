@@ -0,0 +1,137 @@
+// EIT schedule encoder, segment layout as per ETSI TS 101 211 clause 4.1.4
+package eit
+
+import (
+	"fmt"
+	"time"
+
+	"epg/src/dvb"
+	"epg/src/model"
+)
+
+const (
+	// TableIDScheduleFirst is the first actual TS schedule table_id (0x50 - 0x5F).
+	TableIDScheduleFirst = 0x50
+	// ScheduleTables is the number of schedule table_ids available.
+	ScheduleTables = 16
+
+	// SegmentDuration is the span of time covered by one segment.
+	SegmentDuration = 3 * time.Hour
+	// SegmentsPerTable is the number of segments in each schedule table (4 days).
+	SegmentsPerTable = 32
+	// SectionsPerSegment is the number of section numbers reserved per segment.
+	SectionsPerSegment = 8
+
+	// MaxScheduleDays is the furthest ahead the 16 schedule tables can reach.
+	MaxScheduleDays = ScheduleTables * SegmentsPerTable * 3 / 24
+)
+
+// Schedule lays out the service's events for the next days days into schedule
+// sub-tables, one slice of sections per table_id starting at 0x50. Table 0x50
+// segment 0 starts at midnight UTC of now. Events that have already finished are
+// left out, one started before midnight and still running goes into segment 0,
+// and segments without events are carried as a single empty section.
+// Sections are returned with version 0, see ApplyVersions.
+func (svc Service) Schedule(events []model.Event, now time.Time, days int) ([][]dvb.Section, error) {
+	if days < 1 || days > MaxScheduleDays {
+		return nil, fmt.Errorf("schedule of %d days is outside 1 to %d", days, MaxScheduleDays)
+	}
+	origin, horizon := ScheduleWindow(now, days)
+	totalSegments := days * 24 / 3
+
+	// Sort each event into its segment by start time.
+	segments := make([][]model.Event, totalSegments)
+	lastSegment := -1
+	for _, e := range sortEvents(events) {
+		if !e.EndTime.After(now) || !e.StartTime.Before(horizon) {
+			continue
+		}
+		segment := 0
+		if e.StartTime.After(origin) {
+			segment = int(e.StartTime.Sub(origin) / SegmentDuration)
+		}
+		segments[segment] = append(segments[segment], e)
+		lastSegment = segment
+	}
+	if lastSegment < 0 {
+		return nil, nil
+	}
+
+	lastTable := lastSegment / SegmentsPerTable
+	lastTableID := uint8(TableIDScheduleFirst + lastTable)
+
+	tables := make([][]dvb.Section, 0, lastTable+1)
+	for table := 0; table <= lastTable; table++ {
+		tableID := uint8(TableIDScheduleFirst + table)
+		first := table * SegmentsPerTable
+		last := first + SegmentsPerTable - 1
+		if table == lastTable {
+			last = lastSegment
+		}
+
+		var sections []dvb.Section
+		for segment := first; segment <= last; segment++ {
+			segmentSections, err := svc.segmentSections(tableID, lastTableID, segment%SegmentsPerTable, segments[segment])
+			if err != nil {
+				return nil, err
+			}
+			sections = append(sections, segmentSections...)
+		}
+
+		lastSectionNumber := sections[len(sections)-1].SectionNumber
+		for i := range sections {
+			sections[i].LastSectionNumber = lastSectionNumber
+		}
+		tables = append(tables, sections)
+	}
+
+	return tables, nil
+}
+
+// ScheduleWindow returns the start of segment 0, midnight UTC of now, and the
+// end of a schedule of days days.
+func ScheduleWindow(now time.Time, days int) (origin, horizon time.Time) {
+	origin = now.UTC().Truncate(24 * time.Hour)
+	return origin, origin.Add(time.Duration(days) * 24 * time.Hour)
+}
+
+// segmentSections packs the events of one segment into at most eight sections.
+func (svc Service) segmentSections(tableID, lastTableID uint8, segment int, events []model.Event) ([]dvb.Section, error) {
+	firstSection := segment * SectionsPerSegment
+	maxPayload := dvb.MaxPayload(dvb.MaxPrivateSectionSize) - tableHeaderSize
+
+	var bodies [][]byte
+	var body []byte
+	for i := range events {
+		encoded, err := svc.encodeEvent(&events[i], RunningStatusUndefined)
+		if err != nil {
+			return nil, err
+		}
+		if len(encoded) > maxPayload {
+			return nil, fmt.Errorf("event %d does not fit into an EIT section", events[i].EventID)
+		}
+		if len(body)+len(encoded) > maxPayload {
+			bodies = append(bodies, body)
+			body = nil
+		}
+		body = append(body, encoded...)
+	}
+	bodies = append(bodies, body)
+	if len(bodies) > SectionsPerSegment {
+		return nil, fmt.Errorf("service %d table 0x%02X segment %d needs %d sections, only %d allowed",
+			svc.ServiceID, tableID, segment, len(bodies), SectionsPerSegment)
+	}
+
+	segmentLastSectionNumber := uint8(firstSection + len(bodies) - 1)
+	sections := make([]dvb.Section, len(bodies))
+	for i, body := range bodies {
+		sections[i] = dvb.Section{
+			TableID:          tableID,
+			TableIDExtension: svc.ServiceID,
+			CurrentNext:      true,
+			SectionNumber:    uint8(firstSection + i),
+			Payload:          append(svc.tableHeader(segmentLastSectionNumber, lastTableID), body...),
+		}
+	}
+	return sections, nil
+}
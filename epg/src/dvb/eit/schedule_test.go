@@ -0,0 +1,96 @@
+package eit
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"epg/src/dvb"
+	"epg/src/model"
+)
+
+// scheduleSection is what the schedule tests check of a section.
+type scheduleSection struct {
+	TableID, SectionNumber, LastSectionNumber, SegmentLastSectionNumber, LastTableID uint8
+	EventIDs                                                                         []uint16
+}
+
+// layoutOf returns the layout of schedule sections, with the event_ids of
+// their event loops.
+func layoutOf(sections []dvb.Section) []scheduleSection {
+	var layout []scheduleSection
+	for _, s := range sections {
+		l := scheduleSection{
+			TableID:                  s.TableID,
+			SectionNumber:            s.SectionNumber,
+			LastSectionNumber:        s.LastSectionNumber,
+			SegmentLastSectionNumber: s.Payload[4],
+			LastTableID:              s.Payload[5],
+		}
+		for _, e := range eventLoop(s.Payload) {
+			l.EventIDs = append(l.EventIDs, e.EventID)
+		}
+		layout = append(layout, l)
+	}
+	return layout
+}
+
+func TestSchedule(t *testing.T) {
+	midnight := testNow.Truncate(24 * time.Hour)
+	events := []model.Event{
+		event(1, midnight.Add(-2*time.Hour), time.Hour),
+		// Still running at now, it is carried in segment 0.
+		event(2, midnight.Add(-time.Hour), 11*time.Hour),
+		event(3, midnight.Add(10*time.Hour), time.Hour),
+		event(4, midnight.Add(11*time.Hour), time.Hour),
+		event(5, midnight.Add(4*24*time.Hour+time.Hour), time.Hour),
+		// Beyond the 5 days.
+		event(6, midnight.Add(5*24*time.Hour), time.Hour),
+	}
+
+	tables, err := testService.Schedule(events, testNow, 5)
+	if err != nil {
+		t.Fatalf("Schedule() error = %v", err)
+	}
+	if len(tables) != 2 {
+		t.Fatalf("Schedule() returned %d tables, want 2", len(tables))
+	}
+
+	// Table 0x50 carries segments 0 to 31, each its first section.
+	first := layoutOf(tables[0])
+	if len(first) != SegmentsPerTable {
+		t.Fatalf("table 0x50 has %d sections, want %d", len(first), SegmentsPerTable)
+	}
+	for segment, got := range first {
+		n := uint8(segment * SectionsPerSegment)
+		want := scheduleSection{TableID: 0x50, SectionNumber: n, LastSectionNumber: 0xF8, SegmentLastSectionNumber: n, LastTableID: 0x51}
+		switch segment {
+		case 0:
+			want.EventIDs = []uint16{2}
+		case 3:
+			want.EventIDs = []uint16{3, 4}
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("table 0x50 segment %d = %+v, want %+v", segment, got, want)
+		}
+	}
+
+	// Table 0x51 stops at segment 0, the last with an event.
+	want := []scheduleSection{{TableID: 0x51, LastTableID: 0x51, EventIDs: []uint16{5}}}
+	if got := layoutOf(tables[1]); !reflect.DeepEqual(got, want) {
+		t.Errorf("table 0x51 = %+v, want %+v", got, want)
+	}
+}
+
+func TestScheduleEmpty(t *testing.T) {
+	ended := event(1, testNow.Add(-2*time.Hour), time.Hour)
+	tables, err := testService.Schedule([]model.Event{ended}, testNow, 1)
+	if err != nil || tables != nil {
+		t.Errorf("Schedule() = %v, %v, want no tables", tables, err)
+	}
+	for _, days := range []int{0, MaxScheduleDays + 1} {
+		if _, err := testService.Schedule(nil, testNow, days); err == nil {
+			t.Errorf("Schedule() of %d days succeeded, want an error", days)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+// TSDuck SI XML export of EIT, as read by "tsp -P eitinject --files"
+package eit
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+
+	"epg/src/dvb"
+	"epg/src/model"
+)
+
+// tsduckTimeLayout is the date format of TSDuck XML time attributes.
+const tsduckTimeLayout = "2006-01-02 15:04:05"
+
+type xmlTSDuck struct {
+	XMLName xml.Name `xml:"tsduck"`
+	EITs    []xmlEIT `xml:"EIT"`
+}
+
+type xmlEIT struct {
+	Type              string     `xml:"type,attr"`
+	Version           uint8      `xml:"version,attr"`
+	Current           bool       `xml:"current,attr"`
+	Actual            bool       `xml:"actual,attr"`
+	ServiceID         uint16     `xml:"service_id,attr"`
+	TransportStreamID uint16     `xml:"transport_stream_id,attr"`
+	OriginalNetworkID uint16     `xml:"original_network_id,attr"`
+	Events            []xmlEvent `xml:"event"`
+}
+
+type xmlEvent struct {
+	EventID           uint16                `xml:"event_id,attr"`
+	StartTime         string                `xml:"start_time,attr"`
+	Duration          string                `xml:"duration,attr"`
+	RunningStatus     string                `xml:"running_status,attr"`
+	CAMode            bool                  `xml:"CA_mode,attr"`
+	ShortEvent        xmlShortEvent         `xml:"short_event_descriptor"`
+	ExtendedEvents    []xmlExtendedEvent    `xml:"extended_event_descriptor"`
+	Content           *xmlContent           `xml:"content_descriptor"`
+	ParentalRating    *xmlParentalRating    `xml:"parental_rating_descriptor"`
+	ContentIdentifier *xmlContentIdentifier `xml:"content_identifier_descriptor"`
+}
+
+type xmlShortEvent struct {
+	LanguageCode string `xml:"language_code,attr"`
+	EventName    string `xml:"event_name"`
+	Text         string `xml:"text"`
+}
+
+type xmlExtendedEvent struct {
+	DescriptorNumber     int    `xml:"descriptor_number,attr"`
+	LastDescriptorNumber int    `xml:"last_descriptor_number,attr"`
+	LanguageCode         string `xml:"language_code,attr"`
+	Text                 string `xml:"text"`
+}
+
+type xmlContent struct {
+	Contents []xmlContentEntry `xml:"content"`
+}
+
+type xmlContentEntry struct {
+	Level1   uint8 `xml:"content_nibble_level_1,attr"`
+	Level2   uint8 `xml:"content_nibble_level_2,attr"`
+	UserByte uint8 `xml:"user_byte,attr"`
+}
+
+type xmlParentalRating struct {
+	Countries []xmlParentalRatingCountry `xml:"country"`
+}
+
+type xmlParentalRatingCountry struct {
+	CountryCode string `xml:"country_code,attr"`
+	Rating      uint8  `xml:"rating,attr"`
+}
+
+type xmlContentIdentifier struct {
+	CRIDs []xmlCRID `xml:"crid"`
+}
+
+type xmlCRID struct {
+	Type     uint8  `xml:"crid_type,attr"`
+	Location uint8  `xml:"crid_location,attr"`
+	CRID     string `xml:"crid,attr"`
+}
+
+// WriteTSDuckXML writes the current and upcoming events of every channel as
+// TSDuck schedule EITs, one per channel and schedule table, leaving the
+// present/following EIT to be derived by eitinject.
+func (g *Generator) WriteTSDuckXML(w io.Writer, now time.Time) error {
+	doc := xmlTSDuck{}
+	origin := now.UTC().Truncate(24 * time.Hour)
+	err := g.forEachChannel(now, func(svc Service, events []model.Event) error {
+		country := dvb.LookupCountry(svc.CountryCode)
+		tables := map[int]*xmlEIT{}
+		var order []int
+		for _, e := range sortEvents(events) {
+			if !e.EndTime.After(now) {
+				continue
+			}
+			table := 0
+			if e.StartTime.After(origin) {
+				table = int(e.StartTime.Sub(origin) / (SegmentsPerTable * SegmentDuration))
+			}
+			if table >= ScheduleTables {
+				continue
+			}
+			subTable, ok := tables[table]
+			if !ok {
+				subTable = &xmlEIT{
+					Type:              fmt.Sprint(table),
+					Current:           true,
+					Actual:            true,
+					ServiceID:         svc.ServiceID,
+					TransportStreamID: svc.TransportStreamID,
+					OriginalNetworkID: svc.OriginalNetworkID,
+				}
+				tables[table] = subTable
+				order = append(order, table)
+			}
+			subTable.Events = append(subTable.Events, svc.xmlEvent(&e, country, now))
+		}
+		for _, table := range order {
+			doc.EITs = append(doc.EITs, *tables[table])
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+// xmlEvent converts an event and its descriptors to TSDuck XML.
+func (svc Service) xmlEvent(e *model.Event, country dvb.CountryInfo, now time.Time) xmlEvent {
+	duration := e.EndTime.Sub(e.StartTime)
+	runningStatus := "undefined"
+	if !e.StartTime.After(now) {
+		runningStatus = "running"
+	}
+
+	x := xmlEvent{
+		EventID:       EventID(e),
+		StartTime:     e.StartTime.UTC().Format(tsduckTimeLayout),
+		Duration:      fmt.Sprintf("%02d:%02d:%02d", int(duration.Hours()), int(duration.Minutes())%60, int(duration.Seconds())%60),
+		RunningStatus: runningStatus,
+		ShortEvent: xmlShortEvent{
+			LanguageCode: country.Language,
+			EventName:    e.Title,
+		},
+	}
+	if e.ShortDescription != nil {
+		x.ShortEvent.Text = *e.ShortDescription
+	}
+
+	_, texts := extendedText(e, country.Charset)
+	for i, text := range texts {
+		x.ExtendedEvents = append(x.ExtendedEvents, xmlExtendedEvent{
+			DescriptorNumber:     i,
+			LastDescriptorNumber: len(texts) - 1,
+			LanguageCode:         country.Language,
+			Text:                 text,
+		})
+	}
+
+	if genres := ContentGenres(e); len(genres) > 0 {
+		x.Content = &xmlContent{}
+		for _, g := range genres {
+			x.Content.Contents = append(x.Content.Contents, xmlContentEntry{Level1: g.NibbleLevel1, Level2: g.NibbleLevel2})
+		}
+	}
+
+	if ratings := ParentalRatings(e); len(ratings) > 0 {
+		x.ParentalRating = &xmlParentalRating{}
+		for _, r := range ratings {
+			x.ParentalRating.Countries = append(x.ParentalRating.Countries, xmlParentalRatingCountry{CountryCode: r.CountryCode, Rating: r.Rating})
+		}
+	}
+
+	if ids := ContentIdentifiers(e, svc.DefaultAuthority); len(ids) > 0 {
+		x.ContentIdentifier = &xmlContentIdentifier{}
+		for _, id := range ids {
+			x.ContentIdentifier.CRIDs = append(x.ContentIdentifier.CRIDs, xmlCRID{Type: id.Type, CRID: id.CRID})
+		}
+	}
+
+	return x
+}
@@ -0,0 +1,85 @@
+// NIT generation from the networks and channels in the database
+package nit
+
+import (
+	"time"
+
+	"epg/src/dvb"
+	"epg/src/dvb/eit"
+	"epg/src/dvb/sdt"
+	"epg/src/model"
+
+	"gorm.io/gorm"
+)
+
+// Generator builds the serialised NIT sections of all networks from the
+// database, keeping version numbers across calls.
+type Generator struct {
+	db       *gorm.DB
+	versions *dvb.VersionTracker
+}
+
+// NewGenerator ...
+func NewGenerator(db *gorm.DB) *Generator {
+	return &Generator{db: db, versions: dvb.NewVersionTracker()}
+}
+
+// Sections returns the NIT actual sections of every network. now is unused,
+// it matches the other generators for the injector.
+func (g *Generator) Sections(now time.Time) ([][]byte, error) {
+	networks := []model.Network{}
+	err := g.db.Preload("Country").Order("network_id").Find(&networks).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var out [][]byte
+	for _, network := range networks {
+		channels := []model.Channel{}
+		err := g.db.Where("network_id = ?", network.NetworkID).Order("channel_id").Find(&channels).Error
+		if err != nil {
+			return nil, err
+		}
+
+		n := NewNetwork(network, channels)
+		sections, err := n.Sections()
+		if err != nil {
+			return nil, err
+		}
+		g.versions.Apply(SubTableKey(n.NetworkID), sections)
+		for i := range sections {
+			b, err := sections[i].Bytes()
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, b)
+		}
+	}
+	return out, nil
+}
+
+// NewNetwork returns the NIT description of a network and its channels, the
+// network's Country must be loaded. As with eit.NewService the network carries
+// a single transport stream identified by Network.ServiceID, which also serves
+// as the network_id.
+func NewNetwork(network model.Network, channels []model.Channel) Network {
+	id := uint16(network.ServiceID)
+	stream := TransportStream{
+		TransportStreamID: id,
+		OriginalNetworkID: id,
+		DefaultAuthority:  eit.NetworkAuthority(network),
+	}
+	for _, channel := range channels {
+		stream.Services = append(stream.Services, Service{
+			ServiceID:            uint16(channel.ServiceID),
+			ServiceType:          sdt.ServiceTypeDigitalTV,
+			LogicalChannelNumber: uint16(channel.LogicalChannelNumber),
+		})
+	}
+	return Network{
+		NetworkID:        id,
+		Name:             network.Description,
+		CountryCode:      network.Country.CountryCode,
+		TransportStreams: []TransportStream{stream},
+	}
+}
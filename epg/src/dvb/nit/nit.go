@@ -0,0 +1,205 @@
+// NIT (Network Information Table) section encoder, ETSI EN 300 468 clause 5.2.1
+package nit
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"epg/src/dvb"
+)
+
+const (
+	// PID carrying NIT sections.
+	PID = 0x10
+
+	// TableIDActual is the table_id of the actual network NIT.
+	TableIDActual = 0x40
+
+	// MaxSectionSize is the largest NIT section, EN 300 468 limits it below the
+	// 4096 bytes of other SI sections.
+	MaxSectionSize = 1024
+
+	// transportStreamHeaderSize is the fixed part of each entry in the transport stream loop.
+	transportStreamHeaderSize = 6
+	// serviceListEntrySize is the size of each service in a service_list_descriptor.
+	serviceListEntrySize = 3
+	// logicalChannelEntrySize is the size of each service in a logical_channel_descriptor.
+	logicalChannelEntrySize = 4
+	// maxLogicalChannelNumber is the 10-bit logical_channel_number limit.
+	maxLogicalChannelNumber = 0x3FF
+	// maxLoopLength is the 12-bit limit of the descriptor and transport stream loop lengths.
+	maxLoopLength = 0x0FFF
+)
+
+// Network describes an NIT sub-table.
+type Network struct {
+	NetworkID uint16
+	Name      string
+	// CountryCode is the ISO 3166 alpha-2 code of the network, it selects the
+	// character table of the network name.
+	CountryCode      string
+	TransportStreams []TransportStream
+}
+
+// TransportStream describes one entry of the NIT transport stream loop.
+type TransportStream struct {
+	TransportStreamID uint16
+	OriginalNetworkID uint16
+	// DefaultAuthority is the CRID authority of the transport stream, empty for none.
+	DefaultAuthority string
+	Services         []Service
+}
+
+// Service describes one service of a transport stream.
+type Service struct {
+	ServiceID   uint16
+	ServiceType uint8
+	// LogicalChannelNumber is the channel number receivers list the service
+	// under, 0 for none.
+	LogicalChannelNumber uint16
+	Hidden               bool
+}
+
+// Sections builds the NIT actual sub-table of the network. The network
+// descriptors are repeated in every section and the transport streams spread
+// over as many sections as needed. Sections are returned with version 0, see
+// dvb.VersionTracker.
+func (n *Network) Sections() ([]dvb.Section, error) {
+	networkDescriptors, err := n.descriptors()
+	if err != nil {
+		return nil, err
+	}
+	if len(networkDescriptors) > maxLoopLength {
+		return nil, fmt.Errorf("network %d descriptors are %d bytes, exceeds %d", n.NetworkID, len(networkDescriptors), maxLoopLength)
+	}
+	// reserved_future_use(4) network_descriptors_length(12)
+	header := binary.BigEndian.AppendUint16(nil, 0xF000|uint16(len(networkDescriptors)))
+	header = append(header, networkDescriptors...)
+	maxLoop := dvb.MaxPayload(MaxSectionSize) - len(header) - 2
+
+	var loops [][]byte
+	var loop []byte
+	for i := range n.TransportStreams {
+		encoded, err := n.TransportStreams[i].encode()
+		if err != nil {
+			return nil, err
+		}
+		if len(encoded) > maxLoop {
+			return nil, fmt.Errorf("transport stream %d is %d bytes, exceeds an NIT section", n.TransportStreams[i].TransportStreamID, len(encoded))
+		}
+		if len(loop)+len(encoded) > maxLoop {
+			loops = append(loops, loop)
+			loop = nil
+		}
+		loop = append(loop, encoded...)
+	}
+	loops = append(loops, loop)
+	if len(loops) > 256 {
+		return nil, fmt.Errorf("NIT of network %d needs %d sections, exceeds 256", n.NetworkID, len(loops))
+	}
+
+	sections := make([]dvb.Section, len(loops))
+	for i, loop := range loops {
+		payload := make([]byte, 0, len(header)+2+len(loop))
+		payload = append(payload, header...)
+		// reserved_future_use(4) transport_stream_loop_length(12)
+		payload = binary.BigEndian.AppendUint16(payload, 0xF000|uint16(len(loop)))
+		payload = append(payload, loop...)
+		sections[i] = dvb.Section{
+			TableID:           TableIDActual,
+			TableIDExtension:  n.NetworkID,
+			CurrentNext:       true,
+			SectionNumber:     uint8(i),
+			LastSectionNumber: uint8(len(loops) - 1),
+			Payload:           payload,
+		}
+	}
+	return sections, nil
+}
+
+// SubTableKey returns the version tracking key of the NIT actual of a network.
+func SubTableKey(networkID uint16) dvb.SubTableKey {
+	return dvb.SubTableKey{TableID: TableIDActual, TableIDExtension: networkID}
+}
+
+// descriptors returns the network_name_descriptor of the network.
+func (n *Network) descriptors() ([]byte, error) {
+	name, _ := dvb.EncodeTextLimit(n.Name, dvb.LookupCountry(n.CountryCode).Charset, dvb.MaxDescriptorLength)
+	return dvb.AppendDescriptor(nil, dvb.TagNetworkName, name)
+}
+
+// encode encodes one entry of the NIT transport stream loop.
+func (t *TransportStream) encode() ([]byte, error) {
+	descriptors, err := t.descriptors()
+	if err != nil {
+		return nil, err
+	}
+	if len(descriptors) > maxLoopLength {
+		return nil, fmt.Errorf("transport stream %d descriptors are %d bytes, exceeds %d", t.TransportStreamID, len(descriptors), maxLoopLength)
+	}
+
+	buf := make([]byte, 0, transportStreamHeaderSize+len(descriptors))
+	buf = binary.BigEndian.AppendUint16(buf, t.TransportStreamID)
+	buf = binary.BigEndian.AppendUint16(buf, t.OriginalNetworkID)
+	// reserved_future_use(4) transport_descriptors_length(12)
+	buf = binary.BigEndian.AppendUint16(buf, 0xF000|uint16(len(descriptors)))
+	return append(buf, descriptors...), nil
+}
+
+// descriptors returns the service_list_descriptors, the default_authority_descriptor
+// and, when any service is numbered, the logical_channel_descriptors of the
+// transport stream. Each descriptor kind is split as the 255 byte limit requires.
+func (t *TransportStream) descriptors() ([]byte, error) {
+	var serviceList, logicalChannels []byte
+	for _, svc := range t.Services {
+		serviceList = binary.BigEndian.AppendUint16(serviceList, svc.ServiceID)
+		serviceList = append(serviceList, svc.ServiceType)
+		if svc.LogicalChannelNumber == 0 {
+			continue
+		}
+		if svc.LogicalChannelNumber > maxLogicalChannelNumber {
+			return nil, fmt.Errorf("service %d logical channel number %d exceeds %d", svc.ServiceID, svc.LogicalChannelNumber, maxLogicalChannelNumber)
+		}
+		// visible_service_flag(1) reserved(5) logical_channel_number(10)
+		flags := uint16(0xFC00)
+		if svc.Hidden {
+			flags = 0x7C00
+		}
+		logicalChannels = binary.BigEndian.AppendUint16(logicalChannels, svc.ServiceID)
+		logicalChannels = binary.BigEndian.AppendUint16(logicalChannels, flags|svc.LogicalChannelNumber)
+	}
+
+	buf, err := appendSplit(nil, dvb.TagServiceList, serviceList, serviceListEntrySize)
+	if err != nil {
+		return nil, err
+	}
+	if t.DefaultAuthority != "" {
+		if buf, err = dvb.AppendDefaultAuthority(buf, t.DefaultAuthority); err != nil {
+			return nil, err
+		}
+	}
+	if len(logicalChannels) > 0 {
+		if buf, err = dvb.AppendPrivateDataSpecifier(buf, dvb.PrivateDataSpecifierEACEM); err != nil {
+			return nil, err
+		}
+		if buf, err = appendSplit(buf, dvb.TagLogicalChannel, logicalChannels, logicalChannelEntrySize); err != nil {
+			return nil, err
+		}
+	}
+	return buf, nil
+}
+
+// appendSplit appends body of fixed size entries as descriptors with tag,
+// starting a new descriptor whenever one would exceed its 255 byte limit.
+func appendSplit(buf []byte, tag uint8, body []byte, entrySize int) ([]byte, error) {
+	perDescriptor := dvb.MaxDescriptorLength / entrySize * entrySize
+	for len(body) > 0 {
+		n := min(len(body), perDescriptor)
+		var err error
+		if buf, err = dvb.AppendDescriptor(buf, tag, body[:n]); err != nil {
+			return nil, err
+		}
+		body = body[n:]
+	}
+	return buf, nil
+}
@@ -0,0 +1,188 @@
+// PAT and PMT generation from the channels in the database
+package psi
+
+import (
+	"fmt"
+	"time"
+
+	"epg/src/dvb"
+	"epg/src/dvb/nit"
+	"epg/src/model"
+
+	"gorm.io/gorm"
+)
+
+// defaultPMTPIDBase is added to the channel ID when a channel has no PMT PID.
+const defaultPMTPIDBase = 0x1000
+
+// Generator builds the serialised PAT and PMT sections of all channels from
+// the database, keeping version numbers across calls.
+type Generator struct {
+	db       *gorm.DB
+	versions *dvb.VersionTracker
+}
+
+// NewGenerator ...
+func NewGenerator(db *gorm.DB) *Generator {
+	return &Generator{db: db, versions: dvb.NewVersionTracker()}
+}
+
+// PAT returns the PAT sections of every transport stream, each listing the NIT
+// and the PMTs of its network's channels. now is unused, it matches the other
+// generators for the injector.
+func (g *Generator) PAT(now time.Time) ([][]byte, error) {
+	channels, err := g.channels()
+	if err != nil {
+		return nil, err
+	}
+
+	// As in eit.NewService each network is one transport stream identified by Network.ServiceID.
+	programs := map[uint16][]Program{}
+	var order []uint16
+	for _, channel := range channels {
+		tsid := uint16(channel.Network.ServiceID)
+		if _, ok := programs[tsid]; !ok {
+			order = append(order, tsid)
+			programs[tsid] = []Program{{ProgramNumber: 0, PID: nit.PID}}
+		}
+		programs[tsid] = append(programs[tsid], Program{ProgramNumber: uint16(channel.ServiceID), PID: PMTPID(channel)})
+	}
+
+	var out [][]byte
+	for _, tsid := range order {
+		sections, err := PAT(tsid, programs[tsid])
+		if err != nil {
+			return nil, err
+		}
+		g.versions.Apply(SubTableKey(TableIDPAT, tsid), sections)
+		if out, err = appendBytes(out, sections); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// PMTPIDs returns the PMT PID of every channel.
+func (g *Generator) PMTPIDs() ([]uint16, error) {
+	channels, err := g.channels()
+	if err != nil {
+		return nil, err
+	}
+	var pids []uint16
+	seen := map[uint16]bool{}
+	for _, channel := range channels {
+		if pid := PMTPID(channel); !seen[pid] {
+			seen[pid] = true
+			pids = append(pids, pid)
+		}
+	}
+	return pids, nil
+}
+
+// PMT returns a function building the PMT sections of the channels whose
+// PMT is carried on pid.
+func (g *Generator) PMT(pid uint16) func(now time.Time) ([][]byte, error) {
+	return func(now time.Time) ([][]byte, error) {
+		channels, err := g.channels()
+		if err != nil {
+			return nil, err
+		}
+		var out [][]byte
+		for _, channel := range channels {
+			if PMTPID(channel) != pid {
+				continue
+			}
+			pmt, err := NewPMT(channel)
+			if err != nil {
+				return nil, err
+			}
+			sections, err := pmt.Sections()
+			if err != nil {
+				return nil, err
+			}
+			g.versions.Apply(SubTableKey(TableIDPMT, pmt.ProgramNumber), sections)
+			if out, err = appendBytes(out, sections); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	}
+}
+
+// channels loads every channel with what the PAT and PMT need.
+func (g *Generator) channels() ([]model.Channel, error) {
+	channels := []model.Channel{}
+	err := g.db.Preload("Network").Preload("Network.Country").Preload("ElementaryStreams").Order("channel_id").Find(&channels).Error
+	return channels, err
+}
+
+// PMTPID returns the PID of a channel's PMT, defaulting to 0x1000 plus the
+// channel ID when none is set.
+func PMTPID(channel model.Channel) uint16 {
+	if channel.PmtPid != 0 {
+		return uint16(channel.PmtPid)
+	}
+	return uint16(defaultPMTPIDBase + channel.ChannelID)
+}
+
+// NewPMT returns the PMT of a channel: its video and audio PIDs followed by
+// its additional elementary streams. The PCR defaults to the video PID and
+// languages to the network country's. The channel's Network, Network.Country
+// and ElementaryStreams must be loaded.
+func NewPMT(channel model.Channel) (PMT, error) {
+	language := dvb.LookupCountry(channel.Network.Country.CountryCode).Language
+	pmt := PMT{
+		ProgramNumber: uint16(channel.ServiceID),
+		PCRPID:        uint16(channel.PcrPid),
+	}
+	if pmt.PCRPID == 0 {
+		pmt.PCRPID = uint16(channel.ServiceVPid)
+	}
+
+	streams := []model.ElementaryStream{
+		{Pid: channel.ServiceVPid, StreamType: channel.VideoStreamType, Kind: model.StreamKindVideo},
+		{Pid: channel.ServiceAPid, StreamType: channel.AudioStreamType, Kind: model.StreamKindAudio},
+	}
+	for _, es := range append(streams, channel.ElementaryStreams...) {
+		if es.Pid == 0 {
+			continue
+		}
+		streamLanguage := language
+		if es.Language != nil && *es.Language != "" {
+			streamLanguage = *es.Language
+		}
+		descriptors, err := streamDescriptors(es.Kind, streamLanguage)
+		if err != nil {
+			return PMT{}, fmt.Errorf("channel %d stream 0x%X: %w", channel.ChannelID, es.Pid, err)
+		}
+		pmt.Streams = append(pmt.Streams, Stream{StreamType: es.StreamType, PID: uint16(es.Pid), Descriptors: descriptors})
+	}
+	return pmt, nil
+}
+
+// streamDescriptors returns the ES_info descriptors of a stream of kind.
+func streamDescriptors(kind, language string) ([]byte, error) {
+	switch kind {
+	case model.StreamKindAudio:
+		return AppendISO639Language(nil, language)
+	case model.StreamKindSubtitle:
+		return AppendSubtitling(nil, language)
+	case model.StreamKindTeletext:
+		return AppendTeletext(nil, language)
+	case model.StreamKindVideo, model.StreamKindData, "":
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unknown stream kind %q", kind)
+}
+
+// appendBytes serialises sections onto out.
+func appendBytes(out [][]byte, sections []dvb.Section) ([][]byte, error) {
+	for i := range sections {
+		b, err := sections[i].Bytes()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, b)
+	}
+	return out, nil
+}
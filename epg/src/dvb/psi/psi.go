@@ -0,0 +1,170 @@
+// PAT and PMT section encoders, ISO/IEC 13818-1 clause 2.4.4
+package psi
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"epg/src/dvb"
+)
+
+const (
+	// PIDPAT is the PID carrying the PAT.
+	PIDPAT = 0x00
+
+	// TableIDPAT is the table_id of the PAT.
+	TableIDPAT = 0x00
+	// TableIDPMT is the table_id of the PMT.
+	TableIDPMT = 0x02
+
+	// Stream types (ISO/IEC 13818-1 table 2-34).
+	StreamTypeMPEG2Video = 0x02
+	StreamTypeMPEG1Audio = 0x03
+	StreamTypeMPEG2Audio = 0x04
+	StreamTypePrivatePES = 0x06
+	StreamTypeAACAudio   = 0x0F
+	StreamTypeH264Video  = 0x1B
+	StreamTypeHEVCVideo  = 0x24
+
+	// MaxPID is the largest 13-bit PID.
+	MaxPID = 0x1FFF
+
+	// programEntrySize is the size of each entry in the PAT program loop.
+	programEntrySize = 4
+	// streamHeaderSize is the fixed part of each entry in the PMT stream loop.
+	streamHeaderSize = 5
+	// maxInfoLength is the 10 bits of program_info_length and ES_info_length
+	// that ISO/IEC 13818-1 allows to be used.
+	maxInfoLength = 0x03FF
+)
+
+// Program is an entry of the PAT, program number 0 points at the NIT.
+type Program struct {
+	ProgramNumber uint16
+	PID           uint16
+}
+
+// PAT builds the PAT sub-table of a transport stream. Sections are returned
+// with version 0, see dvb.VersionTracker.
+func PAT(transportStreamID uint16, programs []Program) ([]dvb.Section, error) {
+	perSection := dvb.MaxPayload(dvb.MaxPSISectionSize) / programEntrySize
+	var sections []dvb.Section
+	for len(programs) > 0 || len(sections) == 0 {
+		n := min(len(programs), perSection)
+		payload := make([]byte, 0, n*programEntrySize)
+		for _, p := range programs[:n] {
+			if p.PID > MaxPID {
+				return nil, fmt.Errorf("program %d PID 0x%X exceeds 0x%X", p.ProgramNumber, p.PID, MaxPID)
+			}
+			payload = binary.BigEndian.AppendUint16(payload, p.ProgramNumber)
+			// reserved(3) PID(13)
+			payload = binary.BigEndian.AppendUint16(payload, 0xE000|p.PID)
+		}
+		sections = append(sections, dvb.Section{
+			TableID:          TableIDPAT,
+			TableIDExtension: transportStreamID,
+			CurrentNext:      true,
+			SectionNumber:    uint8(len(sections)),
+			Payload:          payload,
+		})
+		programs = programs[n:]
+	}
+	if len(sections) > 256 {
+		return nil, fmt.Errorf("PAT of transport stream %d needs %d sections, exceeds 256", transportStreamID, len(sections))
+	}
+	for i := range sections {
+		sections[i].LastSectionNumber = uint8(len(sections) - 1)
+	}
+	return sections, nil
+}
+
+// Stream is an elementary stream of a program.
+type Stream struct {
+	StreamType  uint8
+	PID         uint16
+	Descriptors []byte
+}
+
+// PMT describes the program map of one program.
+type PMT struct {
+	ProgramNumber uint16
+	PCRPID        uint16
+	Descriptors   []byte
+	Streams       []Stream
+}
+
+// Sections builds the single section PMT sub-table of the program. Sections
+// are returned with version 0, see dvb.VersionTracker.
+func (p *PMT) Sections() ([]dvb.Section, error) {
+	if p.PCRPID > MaxPID {
+		return nil, fmt.Errorf("program %d PCR PID 0x%X exceeds 0x%X", p.ProgramNumber, p.PCRPID, MaxPID)
+	}
+	if len(p.Descriptors) > maxInfoLength {
+		return nil, fmt.Errorf("program %d descriptors are %d bytes, exceeds %d", p.ProgramNumber, len(p.Descriptors), maxInfoLength)
+	}
+
+	var payload []byte
+	// reserved(3) PCR_PID(13) reserved(4) program_info_length(12)
+	payload = binary.BigEndian.AppendUint16(payload, 0xE000|p.PCRPID)
+	payload = binary.BigEndian.AppendUint16(payload, 0xF000|uint16(len(p.Descriptors)))
+	payload = append(payload, p.Descriptors...)
+	for _, s := range p.Streams {
+		if s.PID > MaxPID {
+			return nil, fmt.Errorf("program %d stream PID 0x%X exceeds 0x%X", p.ProgramNumber, s.PID, MaxPID)
+		}
+		if len(s.Descriptors) > maxInfoLength {
+			return nil, fmt.Errorf("program %d stream 0x%X descriptors are %d bytes, exceeds %d", p.ProgramNumber, s.PID, len(s.Descriptors), maxInfoLength)
+		}
+		payload = append(payload, s.StreamType)
+		// reserved(3) elementary_PID(13) reserved(4) ES_info_length(12)
+		payload = binary.BigEndian.AppendUint16(payload, 0xE000|s.PID)
+		payload = binary.BigEndian.AppendUint16(payload, 0xF000|uint16(len(s.Descriptors)))
+		payload = append(payload, s.Descriptors...)
+	}
+	if len(payload) > dvb.MaxPayload(dvb.MaxPSISectionSize) {
+		return nil, fmt.Errorf("PMT of program %d is %d bytes, exceeds a section", p.ProgramNumber, len(payload))
+	}
+
+	return []dvb.Section{{
+		TableID:          TableIDPMT,
+		TableIDExtension: p.ProgramNumber,
+		CurrentNext:      true,
+		Payload:          payload,
+	}}, nil
+}
+
+// SubTableKey returns the version tracking key of a PAT or PMT sub-table.
+func SubTableKey(tableID uint8, tableIDExtension uint16) dvb.SubTableKey {
+	return dvb.SubTableKey{TableID: tableID, TableIDExtension: tableIDExtension}
+}
+
+// AppendISO639Language appends an ISO_639_language_descriptor for an audio
+// stream of undefined audio_type in language.
+func AppendISO639Language(buf []byte, language string) ([]byte, error) {
+	if len(language) != 3 {
+		return nil, fmt.Errorf("language %q is not ISO 639-2", language)
+	}
+	return dvb.AppendDescriptor(buf, dvb.TagISO639Language, append([]byte(language), 0x00))
+}
+
+// AppendSubtitling appends a subtitling_descriptor for normal DVB subtitles in
+// language, on composition and ancillary page 1.
+func AppendSubtitling(buf []byte, language string) ([]byte, error) {
+	if len(language) != 3 {
+		return nil, fmt.Errorf("language %q is not ISO 639-2", language)
+	}
+	// subtitling_type(8) composition_page_id(16) ancillary_page_id(16)
+	body := append([]byte(language), 0x10, 0x00, 0x01, 0x00, 0x01)
+	return dvb.AppendDescriptor(buf, dvb.TagSubtitling, body)
+}
+
+// AppendTeletext appends a teletext_descriptor announcing the initial page
+// 100 in language.
+func AppendTeletext(buf []byte, language string) ([]byte, error) {
+	if len(language) != 3 {
+		return nil, fmt.Errorf("language %q is not ISO 639-2", language)
+	}
+	// teletext_type(5)=initial page teletext_magazine_number(3)=1 teletext_page_number(8)
+	body := append([]byte(language), 0x01<<3|0x01, 0x00)
+	return dvb.AppendDescriptor(buf, dvb.TagTeletext, body)
+}
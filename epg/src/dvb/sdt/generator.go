@@ -0,0 +1,85 @@
+// SDT generation for every channel in the database
+package sdt
+
+import (
+	"time"
+
+	"epg/src/dvb"
+	"epg/src/dvb/eit"
+	"epg/src/model"
+
+	"gorm.io/gorm"
+)
+
+// Generator builds the serialised SDT sections of all transport streams from
+// the database, keeping version numbers across calls.
+type Generator struct {
+	db       *gorm.DB
+	days     int
+	versions *dvb.VersionTracker
+}
+
+// NewGenerator returns a Generator advertising an EIT schedule of days days,
+// matching the eit.Generator it runs alongside.
+func NewGenerator(db *gorm.DB, days int) *Generator {
+	return &Generator{db: db, days: days, versions: dvb.NewVersionTracker()}
+}
+
+// Sections returns the SDT actual sections of every transport stream at now.
+func (g *Generator) Sections(now time.Time) ([][]byte, error) {
+	channels := []model.Channel{}
+	err := g.db.Preload("Network").Preload("Network.Country").Preload("Network.Timezone").Order("channel_id").Find(&channels).Error
+	if err != nil {
+		return nil, err
+	}
+
+	type streamKey struct{ tsid, onid uint16 }
+	streams := map[streamKey][]Service{}
+	var order []streamKey
+	for _, channel := range channels {
+		svc := NewService(channel, now)
+		svc.EITPresentFollowing, svc.EITSchedule, err = g.eitFlags(channel.ChannelID, now)
+		if err != nil {
+			return nil, err
+		}
+
+		key := streamKey{svc.TransportStreamID, svc.OriginalNetworkID}
+		if _, ok := streams[key]; !ok {
+			order = append(order, key)
+		}
+		streams[key] = append(streams[key], svc)
+	}
+
+	var out [][]byte
+	for _, key := range order {
+		sections, err := Sections(streams[key])
+		if err != nil {
+			return nil, err
+		}
+		g.versions.Apply(SubTableKey(key.tsid, key.onid), sections)
+		for i := range sections {
+			b, err := sections[i].Bytes()
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, b)
+		}
+	}
+	return out, nil
+}
+
+// eitFlags reports whether the EIT generator carries present/following and
+// schedule events of a channel at now, reading the same windows it does.
+func (g *Generator) eitFlags(channelID uint, now time.Time) (presentFollowing, schedule bool, err error) {
+	_, to := eit.EventWindow(now, g.days)
+	var upcoming int64
+	err = g.db.Model(&model.Event{}).Scopes(model.Overlapping(now, to)).Where("channel_id = ?", channelID).Count(&upcoming).Error
+	if err != nil || upcoming == 0 {
+		return false, false, err
+	}
+
+	_, horizon := eit.ScheduleWindow(now, g.days)
+	var scheduled int64
+	err = g.db.Model(&model.Event{}).Scopes(model.Overlapping(now, horizon)).Where("channel_id = ?", channelID).Count(&scheduled).Error
+	return true, scheduled > 0, err
+}
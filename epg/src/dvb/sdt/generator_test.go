@@ -0,0 +1,49 @@
+package sdt
+
+import (
+	"testing"
+	"time"
+
+	"epg/src/model"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestEITFlags(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = db.AutoMigrate(&model.Event{}); err != nil {
+		t.Fatal(err)
+	}
+	// A one day schedule ends at midnight, the p/f table reads a day further.
+	now := time.Date(2026, 10, 18, 9, 30, 0, 0, time.UTC)
+	g := NewGenerator(db, 1)
+	tests := []struct {
+		name                       string
+		start                      time.Time
+		presentFollowing, schedule bool
+	}{
+		{"ended", now.Add(-90 * time.Minute), false, false},
+		{"scheduled", now.Add(30 * time.Minute), true, true},
+		{"past the schedule", now.Add(21 * time.Hour), true, false},
+		{"past the p/f window", now.Add(45 * time.Hour), false, false},
+	}
+	for i, tt := range tests {
+		channelID := uint(i + 1)
+		e := model.Event{ChannelID: channelID, StartTime: tt.start, EndTime: tt.start.Add(time.Hour), Title: tt.name}
+		if err := db.Create(&e).Error; err != nil {
+			t.Fatal(err)
+		}
+		presentFollowing, schedule, err := g.eitFlags(channelID, now)
+		if err != nil {
+			t.Fatalf("%s: eitFlags() error = %v", tt.name, err)
+		}
+		if presentFollowing != tt.presentFollowing || schedule != tt.schedule {
+			t.Errorf("%s: eitFlags() = %t, %t, want %t, %t", tt.name, presentFollowing, schedule, tt.presentFollowing, tt.schedule)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+// SDT (Service Description Table) section encoder, ETSI EN 300 468 clause 5.2.3
+package sdt
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"epg/src/dvb"
+	"epg/src/dvb/eit"
+	"epg/src/model"
+	"epg/src/schedule"
+)
+
+const (
+	// PID carrying SDT sections.
+	PID = 0x11
+
+	// TableIDActual is the table_id of the actual TS SDT.
+	TableIDActual = 0x42
+
+	// ServiceTypeDigitalTV is the service_type of a digital television service.
+	ServiceTypeDigitalTV = 0x01
+
+	// MaxSectionSize is the largest SDT section, EN 300 468 limits it below the
+	// 4096 bytes of other SI sections.
+	MaxSectionSize = 1024
+
+	// tableHeaderSize is original_network_id and reserved_future_use.
+	tableHeaderSize = 3
+	// serviceHeaderSize is the fixed part of each entry in the service loop.
+	serviceHeaderSize = 5
+	// serviceFixedSize is service_type and the two name lengths of a service_descriptor.
+	serviceFixedSize = 3
+	// maxDescriptorsLength is the 12-bit descriptors_loop_length limit.
+	maxDescriptorsLength = 0x0FFF
+	// startsSoonWindow is how long before the broadcast window opens a service
+	// is signalled as starting in a few seconds.
+	startsSoonWindow = time.Minute
+)
+
+// Service describes one entry of the SDT service loop.
+type Service struct {
+	eit.Service
+	ServiceType   uint8
+	ProviderName  string
+	ServiceName   string
+	RunningStatus uint8
+	// EITPresentFollowing and EITSchedule advertise the EIT carried for the service.
+	EITPresentFollowing bool
+	EITSchedule         bool
+}
+
+// NewService returns the SDT entry of a channel at time now, the channel's
+// Network, Network.Country and Network.Timezone must be loaded.
+func NewService(channel model.Channel, now time.Time) Service {
+	return Service{
+		Service:       eit.NewService(channel),
+		ServiceType:   ServiceTypeDigitalTV,
+		ProviderName:  channel.Network.Description,
+		ServiceName:   channel.Description,
+		RunningStatus: RunningStatus(channel, now),
+	}
+}
+
+// RunningStatus returns the running_status of a channel at now, from its daily
+// broadcast window, see schedule.OnAir.
+func RunningStatus(channel model.Channel, now time.Time) uint8 {
+	if schedule.OnAir(channel, now) {
+		return eit.RunningStatusRunning
+	}
+	if schedule.OnAir(channel, now.Add(startsSoonWindow)) {
+		return eit.RunningStatusStartsSoon
+	}
+	return eit.RunningStatusNotRunning
+}
+
+// Sections builds the SDT actual sub-table of a transport stream carrying
+// services, which must share the same transport_stream_id and original_network_id.
+// Sections are returned with version 0, see dvb.VersionTracker.
+func Sections(services []Service) ([]dvb.Section, error) {
+	if len(services) == 0 {
+		return nil, nil
+	}
+	tsid, onid := services[0].TransportStreamID, services[0].OriginalNetworkID
+
+	header := make([]byte, 0, tableHeaderSize)
+	header = binary.BigEndian.AppendUint16(header, onid)
+	header = append(header, 0xFF)
+	maxPayload := dvb.MaxPayload(MaxSectionSize)
+
+	var sections []dvb.Section
+	payload := header
+	for i := range services {
+		svc := &services[i]
+		if svc.TransportStreamID != tsid || svc.OriginalNetworkID != onid {
+			return nil, fmt.Errorf("service %d is not in transport stream %d/%d", svc.ServiceID, onid, tsid)
+		}
+		encoded, err := svc.encode()
+		if err != nil {
+			return nil, err
+		}
+		if len(header)+len(encoded) > maxPayload {
+			return nil, fmt.Errorf("service %d is %d bytes, exceeds an SDT section", svc.ServiceID, len(encoded))
+		}
+		if len(payload)+len(encoded) > maxPayload {
+			sections = append(sections, dvb.Section{Payload: payload})
+			payload = header
+		}
+		payload = append(payload[:len(payload):len(payload)], encoded...)
+	}
+	sections = append(sections, dvb.Section{Payload: payload})
+	if len(sections) > 256 {
+		return nil, fmt.Errorf("SDT of transport stream %d/%d needs %d sections, exceeds 256", onid, tsid, len(sections))
+	}
+
+	for i := range sections {
+		sections[i].TableID = TableIDActual
+		sections[i].TableIDExtension = tsid
+		sections[i].CurrentNext = true
+		sections[i].SectionNumber = uint8(i)
+		sections[i].LastSectionNumber = uint8(len(sections) - 1)
+	}
+	return sections, nil
+}
+
+// SubTableKey returns the version tracking key of the SDT actual of a transport stream.
+func SubTableKey(transportStreamID, originalNetworkID uint16) dvb.SubTableKey {
+	return dvb.SubTableKey{TableID: TableIDActual, TableIDExtension: transportStreamID, Extra: uint32(originalNetworkID)}
+}
+
+// encode encodes one entry of the SDT service loop.
+func (svc *Service) encode() ([]byte, error) {
+	descriptors, err := svc.descriptors()
+	if err != nil {
+		return nil, err
+	}
+	if len(descriptors) > maxDescriptorsLength {
+		return nil, fmt.Errorf("service %d descriptors are %d bytes, exceeds %d", svc.ServiceID, len(descriptors), maxDescriptorsLength)
+	}
+
+	// reserved_future_use(6) EIT_schedule_flag(1) EIT_present_following_flag(1)
+	flags := byte(0xFC)
+	if svc.EITSchedule {
+		flags |= 0x02
+	}
+	if svc.EITPresentFollowing {
+		flags |= 0x01
+	}
+
+	buf := make([]byte, 0, serviceHeaderSize+len(descriptors))
+	buf = binary.BigEndian.AppendUint16(buf, svc.ServiceID)
+	buf = append(buf, flags)
+	// running_status(3) free_CA_mode(1)=0 descriptors_loop_length(12)
+	buf = binary.BigEndian.AppendUint16(buf, uint16(svc.RunningStatus)<<13|uint16(len(descriptors)))
+	return append(buf, descriptors...), nil
+}
+
+// descriptors returns the service_descriptor followed by the
+// default_authority_descriptor when the service has a CRID authority.
+func (svc *Service) descriptors() ([]byte, error) {
+	charset := dvb.LookupCountry(svc.CountryCode).Charset
+	budget := dvb.MaxDescriptorLength - serviceFixedSize
+	provider, _ := dvb.EncodeTextLimit(svc.ProviderName, charset, budget)
+	name, _ := dvb.EncodeTextLimit(svc.ServiceName, charset, budget-len(provider))
+
+	body := make([]byte, 0, serviceFixedSize+len(provider)+len(name))
+	body = append(body, svc.ServiceType, byte(len(provider)))
+	body = append(body, provider...)
+	body = append(body, byte(len(name)))
+	body = append(body, name...)
+	buf, err := dvb.AppendDescriptor(nil, dvb.TagService, body)
+	if err != nil {
+		return nil, err
+	}
+
+	if svc.DefaultAuthority != "" {
+		buf, err = dvb.AppendDefaultAuthority(buf, svc.DefaultAuthority)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return buf, nil
+}
@@ -0,0 +1,116 @@
+// long form PSI/SI section encoding
+package dvb
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+const (
+	// MaxPSISectionSize is the largest PSI section (PAT, PMT, ...) in bytes.
+	MaxPSISectionSize = 1024
+	// MaxPrivateSectionSize is the largest SI section (EIT, SDT, NIT, ...) in bytes.
+	MaxPrivateSectionSize = 4096
+	// longHeaderSize is the number of bytes before the payload of a long section.
+	longHeaderSize = 8
+	// shortHeaderSize is the number of bytes before the payload of a short section.
+	shortHeaderSize = 3
+	// crcSize is the size of the trailing CRC32.
+	crcSize = 4
+)
+
+// Section represents a long form section (section_syntax_indicator set)
+// before it is serialised. Payload holds everything after last_section_number
+// and before the CRC32.
+type Section struct {
+	TableID           uint8
+	TableIDExtension  uint16
+	Version           uint8
+	CurrentNext       bool
+	SectionNumber     uint8
+	LastSectionNumber uint8
+	Payload           []byte
+}
+
+// MaxPayload returns the largest payload that fits into a section of maxSize bytes.
+func MaxPayload(maxSize int) int {
+	return maxSize - longHeaderSize - crcSize
+}
+
+// Bytes serialises the section, filling in section_length and the CRC32.
+func (s *Section) Bytes() ([]byte, error) {
+	// PSI tables (below table_id 0x40) carry a '0' after section_syntax_indicator
+	// and are limited to 1024 bytes, DVB SI tables carry reserved_future_use '1'.
+	flags, maxSize := uint16(0xB000), MaxPSISectionSize
+	if s.TableID >= 0x40 {
+		flags, maxSize = 0xF000, MaxPrivateSectionSize
+	}
+	size := longHeaderSize + len(s.Payload) + crcSize
+	if size > maxSize {
+		return nil, fmt.Errorf("section table_id 0x%02X is %d bytes, exceeds %d", s.TableID, size, maxSize)
+	}
+	sectionLength := size - 3
+
+	buf := make([]byte, 0, size)
+	buf = append(buf, s.TableID)
+	buf = binary.BigEndian.AppendUint16(buf, flags|uint16(sectionLength))
+	buf = binary.BigEndian.AppendUint16(buf, s.TableIDExtension)
+	currentNext := byte(0)
+	if s.CurrentNext {
+		currentNext = 1
+	}
+	buf = append(buf, 0xC0|(s.Version&0x1F)<<1|currentNext)
+	buf = append(buf, s.SectionNumber, s.LastSectionNumber)
+	buf = append(buf, s.Payload...)
+	buf = binary.BigEndian.AppendUint32(buf, CRC32(buf))
+	return buf, nil
+}
+
+// ShortSection serialises a section without the long form header (section_syntax_indicator
+// clear) around payload, with a trailing CRC32 when withCRC is set as the TOT has.
+func ShortSection(tableID uint8, payload []byte, withCRC bool) ([]byte, error) {
+	size := shortHeaderSize + len(payload)
+	if withCRC {
+		size += crcSize
+	}
+	if size > MaxPSISectionSize {
+		return nil, fmt.Errorf("section table_id 0x%02X is %d bytes, exceeds %d", tableID, size, MaxPSISectionSize)
+	}
+
+	buf := make([]byte, 0, size)
+	buf = append(buf, tableID)
+	// section_syntax_indicator(1)=0 reserved_future_use(1)=1 reserved(2) section_length(12)
+	buf = binary.BigEndian.AppendUint16(buf, 0x7000|uint16(size-shortHeaderSize))
+	buf = append(buf, payload...)
+	if withCRC {
+		buf = binary.BigEndian.AppendUint32(buf, CRC32(buf))
+	}
+	return buf, nil
+}
+
+// ParseSection parses a serialised long form section, checking its
+// section_length and CRC32.
+func ParseSection(b []byte) (Section, error) {
+	if len(b) < longHeaderSize+crcSize {
+		return Section{}, fmt.Errorf("section of %d bytes is too short", len(b))
+	}
+	if b[1]&0x80 == 0 {
+		return Section{}, fmt.Errorf("section table_id 0x%02X is not a long form section", b[0])
+	}
+	if size := 3 + int(binary.BigEndian.Uint16(b[1:])&0x0FFF); size != len(b) {
+		return Section{}, fmt.Errorf("section table_id 0x%02X section_length gives %d bytes, got %d", b[0], size, len(b))
+	}
+	// The CRC32 over a section including its CRC is zero.
+	if CRC32(b) != 0 {
+		return Section{}, fmt.Errorf("section table_id 0x%02X fails its CRC32", b[0])
+	}
+	return Section{
+		TableID:           b[0],
+		TableIDExtension:  binary.BigEndian.Uint16(b[3:]),
+		Version:           b[5] >> 1 & 0x1F,
+		CurrentNext:       b[5]&0x01 != 0,
+		SectionNumber:     b[6],
+		LastSectionNumber: b[7],
+		Payload:           b[longHeaderSize : len(b)-crcSize],
+	}, nil
+}
@@ -0,0 +1,141 @@
+package dvb
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSectionBytes(t *testing.T) {
+	tests := []struct {
+		name    string
+		section Section
+		// header is the serialised section up to last_section_number.
+		header []byte
+	}{
+		{
+			"PSI",
+			Section{TableID: 0x02, TableIDExtension: 1000, Version: 3, CurrentNext: true, SectionNumber: 0, LastSectionNumber: 0, Payload: []byte{0xE1, 0x00, 0xF0, 0x00}},
+			[]byte{0x02, 0xB0, 0x0D, 0x03, 0xE8, 0xC7, 0x00, 0x00},
+		},
+		{
+			"SI",
+			Section{TableID: 0x4E, TableIDExtension: 0xFFFF, Version: 31, CurrentNext: true, SectionNumber: 1, LastSectionNumber: 1, Payload: []byte("payload")},
+			[]byte{0x4E, 0xF0, 0x10, 0xFF, 0xFF, 0xFF, 0x01, 0x01},
+		},
+		{
+			"next",
+			Section{TableID: 0x42, TableIDExtension: 7, Version: 0, CurrentNext: false, SectionNumber: 2, LastSectionNumber: 5},
+			[]byte{0x42, 0xF0, 0x09, 0x00, 0x07, 0xC0, 0x02, 0x05},
+		},
+		{
+			"largest SI",
+			Section{TableID: 0x50, Payload: make([]byte, MaxPayload(MaxPrivateSectionSize))},
+			[]byte{0x50, 0xFF, 0xFD, 0x00, 0x00, 0xC0, 0x00, 0x00},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := tt.section.Bytes()
+			if err != nil {
+				t.Fatalf("Bytes() error = %v", err)
+			}
+			if want := longHeaderSize + len(tt.section.Payload) + crcSize; len(b) != want {
+				t.Fatalf("Bytes() is %d bytes, want %d", len(b), want)
+			}
+			if !bytes.Equal(b[:longHeaderSize], tt.header) {
+				t.Errorf("Bytes() header = % X, want % X", b[:longHeaderSize], tt.header)
+			}
+			if payload := b[longHeaderSize : len(b)-crcSize]; !bytes.Equal(payload, tt.section.Payload) {
+				t.Errorf("Bytes() payload = % X, want % X", payload, tt.section.Payload)
+			}
+			if crc := CRC32(b); crc != 0 {
+				t.Errorf("CRC32 over the section = 0x%08X, want 0", crc)
+			}
+		})
+	}
+}
+
+func TestSectionTooLarge(t *testing.T) {
+	tests := []struct {
+		name    string
+		section Section
+	}{
+		{"PSI", Section{TableID: 0x02, Payload: make([]byte, MaxPayload(MaxPSISectionSize)+1)}},
+		{"SI", Section{TableID: 0x4E, Payload: make([]byte, MaxPayload(MaxPrivateSectionSize)+1)}},
+	}
+	for _, tt := range tests {
+		if _, err := tt.section.Bytes(); err == nil {
+			t.Errorf("%s: Bytes() of %d payload bytes succeeded, want an error", tt.name, len(tt.section.Payload))
+		}
+	}
+}
+
+func TestSectionRoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		section Section
+		// flags is the second byte of the serialised section without the
+		// section_length bits.
+		flags byte
+	}{
+		{"PSI", Section{TableID: 0x02, TableIDExtension: 1000, Version: 3, CurrentNext: true, SectionNumber: 0, LastSectionNumber: 0, Payload: []byte{0xE1, 0x00, 0xF0, 0x00}}, 0xB0},
+		{"SI", Section{TableID: 0x4E, TableIDExtension: 0xFFFF, Version: 31, CurrentNext: true, SectionNumber: 1, LastSectionNumber: 1, Payload: []byte("payload")}, 0xF0},
+		{"next", Section{TableID: 0x42, TableIDExtension: 7, Version: 0, CurrentNext: false, SectionNumber: 2, LastSectionNumber: 5}, 0xF0},
+		{"largest SI", Section{TableID: 0x50, Payload: make([]byte, MaxPayload(MaxPrivateSectionSize))}, 0xF0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := tt.section.Bytes()
+			if err != nil {
+				t.Fatalf("Bytes() error = %v", err)
+			}
+			if want := longHeaderSize + len(tt.section.Payload) + crcSize; len(b) != want {
+				t.Fatalf("Bytes() is %d bytes, want %d", len(b), want)
+			}
+			if b[1]&0xF0 != tt.flags {
+				t.Errorf("Bytes() flags = 0x%02X, want 0x%02X", b[1]&0xF0, tt.flags)
+			}
+
+			got, err := ParseSection(b)
+			if err != nil {
+				t.Fatalf("ParseSection() error = %v", err)
+			}
+			if got.TableID != tt.section.TableID || got.TableIDExtension != tt.section.TableIDExtension ||
+				got.Version != tt.section.Version || got.CurrentNext != tt.section.CurrentNext ||
+				got.SectionNumber != tt.section.SectionNumber || got.LastSectionNumber != tt.section.LastSectionNumber {
+				t.Errorf("ParseSection() = %+v, want %+v", got, tt.section)
+			}
+			if !bytes.Equal(got.Payload, tt.section.Payload) {
+				t.Errorf("ParseSection() payload = % X, want % X", got.Payload, tt.section.Payload)
+			}
+		})
+	}
+}
+
+func TestParseSectionErrors(t *testing.T) {
+	section := Section{TableID: 0x4E, TableIDExtension: 1000, CurrentNext: true, Payload: []byte("payload")}
+	valid, err := section.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	corrupt := func(f func(b []byte) []byte) []byte {
+		return f(append([]byte{}, valid...))
+	}
+
+	tests := []struct {
+		name string
+		b    []byte
+	}{
+		{"too short", valid[:longHeaderSize+crcSize-1]},
+		{"short form", corrupt(func(b []byte) []byte { b[1] &^= 0x80; return b })},
+		{"truncated", valid[:len(valid)-1]},
+		{"trailing byte", append(append([]byte{}, valid...), 0xFF)},
+		{"bad CRC", corrupt(func(b []byte) []byte { b[len(b)-1] ^= 0x01; return b })},
+		{"bad payload", corrupt(func(b []byte) []byte { b[longHeaderSize] ^= 0x80; return b })},
+	}
+	for _, tt := range tests {
+		if _, err := ParseSection(tt.b); err == nil {
+			t.Errorf("%s: ParseSection() succeeded, want an error", tt.name)
+		}
+	}
+}
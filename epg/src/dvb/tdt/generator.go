@@ -0,0 +1,80 @@
+// TDT and TOT generation from the networks in the database
+package tdt
+
+import (
+	"log"
+	"time"
+
+	"epg/src/dvb"
+	"epg/src/model"
+
+	"gorm.io/gorm"
+)
+
+// Generator builds the TDT and the TOT with the local time offsets of every
+// country our networks broadcast in.
+type Generator struct {
+	db *gorm.DB
+	// warned holds the timezones already logged as disagreeing with the
+	// system tz database.
+	warned map[string]bool
+}
+
+// NewGenerator ...
+func NewGenerator(db *gorm.DB) *Generator {
+	return &Generator{db: db, warned: map[string]bool{}}
+}
+
+// Sections returns the TDT and TOT sections at now.
+func (g *Generator) Sections(now time.Time) ([][]byte, error) {
+	offsets, err := g.LocalTimeOffsets(now)
+	if err != nil {
+		return nil, err
+	}
+	tdt, err := TDT(now)
+	if err != nil {
+		return nil, err
+	}
+	tot, err := TOT(now, offsets)
+	if err != nil {
+		return nil, err
+	}
+	return [][]byte{tdt, tot}, nil
+}
+
+// LocalTimeOffsets returns the offset of each country with a network at now,
+// taken from the timezone of the country's first network.
+func (g *Generator) LocalTimeOffsets(now time.Time) ([]LocalTimeOffset, error) {
+	networks := []model.Network{}
+	err := g.db.Preload("Country").Preload("Timezone").Order("network_id").Find(&networks).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var offsets []LocalTimeOffset
+	seen := map[string]bool{}
+	for _, network := range networks {
+		country := dvb.LookupCountry(network.Country.CountryCode).Alpha3
+		if country == "" || seen[country] {
+			continue
+		}
+		seen[country] = true
+		if err := network.Timezone.CheckTZ(now); err != nil && !g.warned[network.Timezone.TimezoneName] {
+			g.warned[network.Timezone.TimezoneName] = true
+			log.Printf("tot: %v, following the timezones table", err)
+		}
+		offsets = append(offsets, NewLocalTimeOffset(country, &network.Timezone, now))
+	}
+	return offsets, nil
+}
+
+// NewLocalTimeOffset returns the offset of tz at now in the country with
+// ISO 3166 alpha-3 code country.
+func NewLocalTimeOffset(country string, tz *model.Timezone, now time.Time) LocalTimeOffset {
+	offset := tz.OffsetAt(now)
+	o := LocalTimeOffset{CountryCode: country, Offset: offset, NextOffset: offset}
+	if change, next, ok := tz.NextChange(now); ok {
+		o.TimeOfChange, o.NextOffset = change, next
+	}
+	return o
+}
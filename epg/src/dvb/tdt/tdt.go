@@ -0,0 +1,104 @@
+// TDT (Time and Date Table) and TOT (Time Offset Table) encoders, ETSI EN 300 468 clauses 5.2.5 and 5.2.6
+package tdt
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"epg/src/dvb"
+)
+
+const (
+	// PID carrying TDT and TOT sections.
+	PID = 0x14
+
+	// TableIDTDT is the table_id of the TDT.
+	TableIDTDT = 0x70
+	// TableIDTOT is the table_id of the TOT.
+	TableIDTOT = 0x73
+
+	// localTimeOffsetSize is the size of each region of a local_time_offset_descriptor.
+	localTimeOffsetSize = 13
+)
+
+// LocalTimeOffset describes the UTC offset of one region for the
+// local_time_offset_descriptor.
+type LocalTimeOffset struct {
+	// CountryCode is the ISO 3166 alpha-3 code of the country.
+	CountryCode string
+	RegionID    uint8
+	Offset      time.Duration
+	// TimeOfChange is the next change of offset to NextOffset, zero when the
+	// region has none.
+	TimeOfChange time.Time
+	NextOffset   time.Duration
+}
+
+// TDT returns the TDT section carrying now.
+func TDT(now time.Time) ([]byte, error) {
+	utc := dvb.EncodeMJDTime(now)
+	return dvb.ShortSection(TableIDTDT, utc[:], false)
+}
+
+// TOT returns the TOT section carrying now and the offsets of each region.
+func TOT(now time.Time, offsets []LocalTimeOffset) ([]byte, error) {
+	var descriptors []byte
+	// A descriptor holds up to 19 regions, further ones go in another descriptor.
+	perDescriptor := dvb.MaxDescriptorLength / localTimeOffsetSize
+	for len(offsets) > 0 {
+		n := min(len(offsets), perDescriptor)
+		var body []byte
+		for _, o := range offsets[:n] {
+			encoded, err := o.encode()
+			if err != nil {
+				return nil, err
+			}
+			body = append(body, encoded...)
+		}
+		var err error
+		descriptors, err = dvb.AppendDescriptor(descriptors, dvb.TagLocalTimeOffset, body)
+		if err != nil {
+			return nil, err
+		}
+		offsets = offsets[n:]
+	}
+
+	utc := dvb.EncodeMJDTime(now)
+	payload := append(utc[:], 0, 0)
+	// reserved(4) descriptors_loop_length(12)
+	binary.BigEndian.PutUint16(payload[5:], 0xF000|uint16(len(descriptors)))
+	payload = append(payload, descriptors...)
+	return dvb.ShortSection(TableIDTOT, payload, true)
+}
+
+// encode encodes one region of the local_time_offset_descriptor.
+func (o LocalTimeOffset) encode() ([]byte, error) {
+	if len(o.CountryCode) != 3 {
+		return nil, fmt.Errorf("country code %q is not ISO 3166 alpha-3", o.CountryCode)
+	}
+	if o.RegionID > 0x3F {
+		return nil, fmt.Errorf("country region %d exceeds 63", o.RegionID)
+	}
+
+	// The polarity bit applies to both offsets, a change crossing UTC can't be
+	// signalled and keeps the current polarity.
+	polarity := byte(0)
+	if o.Offset < 0 {
+		polarity = 1
+	}
+	offset := dvb.EncodeBCDHourMinute(o.Offset)
+	next := dvb.EncodeBCDHourMinute(o.NextOffset)
+	var timeOfChange [5]byte
+	if !o.TimeOfChange.IsZero() {
+		timeOfChange = dvb.EncodeMJDTime(o.TimeOfChange)
+	}
+
+	buf := make([]byte, 0, localTimeOffsetSize)
+	buf = append(buf, o.CountryCode...)
+	// country_region_id(6) reserved(1) local_time_offset_polarity(1)
+	buf = append(buf, o.RegionID<<2|0x02|polarity)
+	buf = append(buf, offset[:]...)
+	buf = append(buf, timeOfChange[:]...)
+	return append(buf, next[:]...), nil
+}
@@ -0,0 +1,117 @@
+// DVB string encoding (EN 300 468 Annex A)
+package dvb
+
+import (
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// Charset is a DVB character table together with the selection bytes that
+// prefix a text field encoded in it.
+type Charset struct {
+	Name   string
+	prefix []byte
+	table  *charmap.Charmap
+}
+
+// Character tables selectable by the first byte(s) of a text field (EN 300 468 table A.3/A.4).
+var (
+	Latin1     = Charset{"ISO-8859-1", []byte{0x10, 0x00, 0x01}, charmap.ISO8859_1}
+	ISO8859_2  = Charset{"ISO-8859-2", []byte{0x10, 0x00, 0x02}, charmap.ISO8859_2}
+	ISO8859_5  = Charset{"ISO-8859-5", []byte{0x01}, charmap.ISO8859_5}
+	ISO8859_6  = Charset{"ISO-8859-6", []byte{0x02}, charmap.ISO8859_6}
+	ISO8859_7  = Charset{"ISO-8859-7", []byte{0x03}, charmap.ISO8859_7}
+	ISO8859_8  = Charset{"ISO-8859-8", []byte{0x04}, charmap.ISO8859_8}
+	ISO8859_9  = Charset{"ISO-8859-9", []byte{0x05}, charmap.ISO8859_9}
+	ISO8859_13 = Charset{"ISO-8859-13", []byte{0x09}, charmap.ISO8859_13}
+	ISO8859_15 = Charset{"ISO-8859-15", []byte{0x0B}, charmap.ISO8859_15}
+	UTF8       = Charset{"UTF-8", []byte{0x15}, nil}
+)
+
+// DVB control codes for a line break in single byte tables and in UTF-8.
+const (
+	crlfByte = 0x8A
+	crlfRune = '\uE08A'
+)
+
+// EncodeText encodes s for a DVB text field. Plain ASCII is written without a
+// table selection, anything else in cs when every character is representable
+// and in UTF-8 otherwise.
+func EncodeText(s string, cs Charset) []byte {
+	encoded, _ := EncodeTextLimit(s, cs, len(s)*3+len(cs.prefix)+len(UTF8.prefix))
+	return encoded
+}
+
+// EncodeTextLimit encodes as many leading characters of s as fit into maxBytes
+// including the table selection, and returns the characters left over.
+// Text is never split inside a character.
+func EncodeTextLimit(s string, cs Charset, maxBytes int) ([]byte, string) {
+	cs = selectCharset(s, cs)
+	if maxBytes <= len(cs.prefix) {
+		return nil, s
+	}
+
+	buf := make([]byte, 0, maxBytes)
+	buf = append(buf, cs.prefix...)
+	var char [utf8.UTFMax]byte
+	for i, r := range s {
+		if r == '\r' {
+			continue
+		}
+		n := encodeRune(char[:], r, cs)
+		if len(buf)+n > maxBytes {
+			return buf, s[i:]
+		}
+		buf = append(buf, char[:n]...)
+	}
+	return buf, ""
+}
+
+// selectCharset picks the table text is written in.
+func selectCharset(s string, cs Charset) Charset {
+	ascii := true
+	representable := cs.table != nil
+	for _, r := range s {
+		if r == '\r' || r == '\n' {
+			continue
+		}
+		if r < 0x20 || r > 0x7E {
+			ascii = false
+		}
+		if representable {
+			if _, ok := cs.table.EncodeRune(r); !ok {
+				representable = false
+			}
+		}
+	}
+	switch {
+	case ascii:
+		return Charset{Name: "default"}
+	case representable:
+		return cs
+	default:
+		return UTF8
+	}
+}
+
+// encodeRune writes r in cs into buf and returns the number of bytes used.
+func encodeRune(buf []byte, r rune, cs Charset) int {
+	if cs.table == nil && len(cs.prefix) > 0 {
+		if r == '\n' {
+			r = crlfRune
+		}
+		return utf8.EncodeRune(buf, r)
+	}
+	if r == '\n' {
+		buf[0] = crlfByte
+		return 1
+	}
+	if cs.table == nil {
+		buf[0] = byte(r)
+		return 1
+	}
+	b, _ := cs.table.EncodeRune(r)
+	buf[0] = b
+	return 1
+}
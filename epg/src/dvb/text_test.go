@@ -0,0 +1,77 @@
+package dvb
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEncodeText(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		cs   Charset
+		want []byte
+	}{
+		{"ASCII", "News", Latin1, []byte("News")},
+		{"line break", "a\r\nb", Latin1, []byte{'a', 0x8A, 'b'}},
+		{"Latin-1", "Café", Latin1, []byte{0x10, 0x00, 0x01, 'C', 'a', 'f', 0xE9}},
+		{"single byte table", "Москва", ISO8859_5, []byte{0x01, 0xBC, 0xDE, 0xE1, 0xDA, 0xD2, 0xD0}},
+		{"not in the table", "Café", ISO8859_5, []byte{0x15, 'C', 'a', 'f', 0xC3, 0xA9}},
+		{"UTF-8 line break", "日\n", UTF8, []byte{0x15, 0xE6, 0x97, 0xA5, 0xEE, 0x82, 0x8A}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EncodeText(tt.s, tt.cs); !bytes.Equal(got, tt.want) {
+				t.Errorf("EncodeText(%q, %s) = % X, want % X", tt.s, tt.cs.Name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodeTextLimit(t *testing.T) {
+	tests := []struct {
+		name     string
+		s        string
+		cs       Charset
+		maxBytes int
+		want     []byte
+		rest     string
+	}{
+		{"fits", "News", Latin1, 4, []byte("News"), ""},
+		{"split", "News", Latin1, 3, []byte("New"), "s"},
+		{"table selection counted", "Café!", Latin1, 6, []byte{0x10, 0x00, 0x01, 'C', 'a', 'f'}, "é!"},
+		{"whole characters", "日本", UTF8, 5, []byte{0x15, 0xE6, 0x97, 0xA5}, "本"},
+		{"no room after the table selection", "Café", Latin1, 3, nil, "Café"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, rest := EncodeTextLimit(tt.s, tt.cs, tt.maxBytes)
+			if !bytes.Equal(got, tt.want) || rest != tt.rest {
+				t.Errorf("EncodeTextLimit(%q, %s, %d) = % X, %q, want % X, %q", tt.s, tt.cs.Name, tt.maxBytes, got, rest, tt.want, tt.rest)
+			}
+		})
+	}
+}
+
+func TestDecodeText(t *testing.T) {
+	tests := []struct {
+		name string
+		b    []byte
+		want string
+	}{
+		{"empty", nil, ""},
+		{"default table", []byte{'C', 'a', 'f', 0xC2, 'e', ' ', 0xA4, '5'}, "Café €5"},
+		{"line break", []byte{'a', 0x8A, 'b', 0x86}, "a\nb"},
+		{"Latin-1", []byte{0x10, 0x00, 0x01, 'C', 'a', 'f', 0xE9}, "Café"},
+		{"single byte table", []byte{0x01, 0xBC, 0xDE, 0xE1, 0xDA, 0xD2, 0xD0}, "Москва"},
+		{"UCS-2", []byte{0x11, 0x65, 0xE5, 0xE0, 0x8A}, "日\n"},
+		{"UTF-8 line break", []byte{0x15, 0xE6, 0x97, 0xA5, 0xEE, 0x82, 0x8A}, "日\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DecodeText(tt.b); got != tt.want {
+				t.Errorf("DecodeText(% X) = %q, want %q", tt.b, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+// DVB string decoding (EN 300 468 Annex A)
+package dvb
+
+import (
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/unicode/norm"
+)
+
+// iso8859 maps the ISO 8859 part numbers to their tables, parts missing here
+// are decoded as ISO 8859-1.
+var iso8859 = map[int]*charmap.Charmap{
+	1: charmap.ISO8859_1, 2: charmap.ISO8859_2, 3: charmap.ISO8859_3, 4: charmap.ISO8859_4,
+	5: charmap.ISO8859_5, 6: charmap.ISO8859_6, 7: charmap.ISO8859_7, 8: charmap.ISO8859_8,
+	9: charmap.ISO8859_9, 10: charmap.ISO8859_10, 13: charmap.ISO8859_13, 14: charmap.ISO8859_14,
+	15: charmap.ISO8859_15, 16: charmap.ISO8859_16,
+}
+
+// DecodeText decodes a DVB text field to UTF-8, following its table selection.
+// Line breaks become '\n' and other control codes are dropped. The multi byte
+// Asian tables are not supported and decode as ISO 8859-1.
+func DecodeText(b []byte) string {
+	if len(b) == 0 {
+		return ""
+	}
+	switch first := b[0]; {
+	case first >= 0x20:
+		return decodeISO6937(b)
+	case first >= 0x01 && first <= 0x0B:
+		return decodeSingleByte(b[1:], int(first)+4)
+	case first == 0x10 && len(b) >= 3:
+		return decodeSingleByte(b[3:], int(b[1])<<8|int(b[2]))
+	case first == 0x11:
+		return decodeUCS2(b[1:])
+	case first == 0x15:
+		return decodeUTF8(b[1:])
+	default:
+		return decodeSingleByte(b[1:], 1)
+	}
+}
+
+// decodeSingleByte decodes b in ISO 8859 part.
+func decodeSingleByte(b []byte, part int) string {
+	table, ok := iso8859[part]
+	if !ok {
+		table = charmap.ISO8859_1
+	}
+	var sb strings.Builder
+	for _, c := range b {
+		switch {
+		case c == crlfByte:
+			sb.WriteByte('\n')
+		case c >= 0x80 && c <= 0x9F:
+		default:
+			sb.WriteRune(table.DecodeByte(c))
+		}
+	}
+	return sb.String()
+}
+
+// decodeUTF8 decodes UTF-8 text, mapping the DVB control codes of the private use area.
+func decodeUTF8(b []byte) string {
+	var sb strings.Builder
+	for len(b) > 0 {
+		r, n := utf8.DecodeRune(b)
+		b = b[n:]
+		writeRune(&sb, r)
+	}
+	return sb.String()
+}
+
+// decodeUCS2 decodes big endian ISO/IEC 10646 Basic Multilingual Plane text.
+func decodeUCS2(b []byte) string {
+	units := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		units = append(units, uint16(b[i])<<8|uint16(b[i+1]))
+	}
+	var sb strings.Builder
+	for _, r := range utf16.Decode(units) {
+		writeRune(&sb, r)
+	}
+	return sb.String()
+}
+
+// writeRune writes r, turning the DVB line break into '\n' and dropping the
+// other DVB control codes.
+func writeRune(sb *strings.Builder, r rune) {
+	switch {
+	case r == crlfRune:
+		sb.WriteByte('\n')
+	case r >= 0xE080 && r <= 0xE09F, r >= 0x80 && r <= 0x9F:
+	default:
+		sb.WriteRune(r)
+	}
+}
+
+// iso6937Diacritics maps the non-spacing diacritical marks of ISO/IEC 6937, which
+// precede the letter they modify, to Unicode combining characters.
+var iso6937Diacritics = map[byte]rune{
+	0xC1: '\u0300', 0xC2: '\u0301', 0xC3: '\u0302', 0xC4: '\u0303', 0xC5: '\u0304',
+	0xC6: '\u0306', 0xC7: '\u0307', 0xC8: '\u0308', 0xCA: '\u030A', 0xCB: '\u0327',
+	0xCD: '\u030B', 0xCE: '\u0328', 0xCF: '\u030C',
+}
+
+// iso6937 holds the spacing characters of ISO/IEC 6937 from 0xA0 up, 0 where unassigned.
+var iso6937 = [96]rune{
+	'\u00A0', '¡', '¢', '£', 0, '¥', 0, '§', '¤', '‘', '“', '«', '←', '↑', '→', '↓',
+	'°', '±', '²', '³', '×', 'µ', '¶', '·', '÷', '’', '”', '»', '¼', '½', '¾', '¿',
+	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+	'―', '¹', '®', '©', '™', '♪', '¬', '¦', 0, 0, 0, 0, '⅛', '⅜', '⅝', '⅞',
+	'Ω', 'Æ', 'Đ', 'ª', 'Ħ', 0, 'Ĳ', 'Ŀ', 'Ł', 'Ø', 'Œ', 'º', 'Þ', 'Ŧ', 'Ŋ', 'ŉ',
+	'ĸ', 'æ', 'đ', 'ð', 'ħ', 'ı', 'ĳ', 'ŀ', 'ł', 'ø', 'œ', 'ß', 'þ', 'ŧ', 'ŋ', '\u00AD',
+}
+
+// decodeISO6937 decodes text in the default DVB table, ISO/IEC 6937 with the
+// Euro sign at 0xA4.
+func decodeISO6937(b []byte) string {
+	var sb strings.Builder
+	for i := 0; i < len(b); i++ {
+		c := b[i]
+		switch {
+		case c < 0x80:
+			sb.WriteByte(c)
+		case c == crlfByte:
+			sb.WriteByte('\n')
+		case c < 0xA0:
+		case c == 0xA4:
+			sb.WriteRune('€')
+		default:
+			if mark, ok := iso6937Diacritics[c]; ok {
+				if i+1 < len(b) && b[i+1] >= 0x20 && b[i+1] < 0x80 {
+					sb.WriteByte(b[i+1])
+					i++
+				}
+				sb.WriteRune(mark)
+			} else if r := iso6937[c-0xA0]; r != 0 {
+				sb.WriteRune(r)
+			}
+		}
+	}
+	return norm.NFC.String(sb.String())
+}
@@ -0,0 +1,75 @@
+// DVB time encodings (EN 300 468 Annex C)
+package dvb
+
+import "time"
+
+// mjdUnixEpoch is the Modified Julian Date of 1970-01-01.
+const mjdUnixEpoch = 40587
+
+// EncodeMJDTime encodes t as the 40-bit UTC_time field: 16 bits of Modified Julian
+// Date followed by hours, minutes and seconds in BCD.
+func EncodeMJDTime(t time.Time) [5]byte {
+	t = t.UTC()
+	mjd := uint16(t.Unix()/86400 + mjdUnixEpoch)
+	return [5]byte{
+		byte(mjd >> 8),
+		byte(mjd),
+		toBCD(t.Hour()),
+		toBCD(t.Minute()),
+		toBCD(t.Second()),
+	}
+}
+
+// EncodeBCDDuration encodes d as the 24-bit hh:mm:ss BCD duration field.
+// Durations beyond 99:59:59 are clamped.
+func EncodeBCDDuration(d time.Duration) [3]byte {
+	if d < 0 {
+		d = 0
+	}
+	seconds := int(d / time.Second)
+	if seconds > 99*3600+59*60+59 {
+		seconds = 99*3600 + 59*60 + 59
+	}
+	return [3]byte{
+		toBCD(seconds / 3600),
+		toBCD(seconds / 60 % 60),
+		toBCD(seconds % 60),
+	}
+}
+
+// EncodeBCDHourMinute encodes the magnitude of d as 16-bit hhmm BCD, as in
+// the local_time_offset fields. Seconds are dropped.
+func EncodeBCDHourMinute(d time.Duration) [2]byte {
+	if d < 0 {
+		d = -d
+	}
+	m := int(d / time.Minute)
+	return [2]byte{toBCD(m / 60 % 100), toBCD(m % 60)}
+}
+
+// DecodeMJDTime decodes a 40-bit UTC_time field, see EncodeMJDTime.
+func DecodeMJDTime(b [5]byte) time.Time {
+	mjd := int64(b[0])<<8 | int64(b[1])
+	day := time.Unix((mjd-mjdUnixEpoch)*86400, 0).UTC()
+	return day.Add(decodeBCDTime(b[2], b[3], b[4]))
+}
+
+// DecodeBCDDuration decodes a 24-bit hh:mm:ss BCD duration field.
+func DecodeBCDDuration(b [3]byte) time.Duration {
+	return decodeBCDTime(b[0], b[1], b[2])
+}
+
+// decodeBCDTime returns the duration of BCD hours, minutes and seconds.
+func decodeBCDTime(h, m, s byte) time.Duration {
+	return time.Duration(fromBCD(h))*time.Hour + time.Duration(fromBCD(m))*time.Minute + time.Duration(fromBCD(s))*time.Second
+}
+
+// fromBCD unpacks two BCD digits.
+func fromBCD(b byte) int {
+	return int(b>>4)*10 + int(b&0x0F)
+}
+
+// toBCD packs a value between 0 and 99 into two BCD digits.
+func toBCD(v int) byte {
+	return byte(v/10<<4 | v%10)
+}
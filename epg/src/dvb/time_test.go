@@ -0,0 +1,78 @@
+package dvb
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMJDTime(t *testing.T) {
+	tests := []struct {
+		name string
+		time time.Time
+		want [5]byte
+	}{
+		// The example of EN 300 468 Annex C.
+		{"annex C", time.Date(1993, 10, 13, 12, 45, 0, 0, time.UTC), [5]byte{0xC0, 0x79, 0x12, 0x45, 0x00}},
+		{"unix epoch", time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC), [5]byte{0x9E, 0x8B, 0x00, 0x00, 0x00}},
+		{"end of day", time.Date(2026, 10, 18, 23, 59, 59, 0, time.UTC), [5]byte{0xEF, 0x93, 0x23, 0x59, 0x59}},
+		{"other zone", time.Date(2026, 10, 19, 10, 59, 59, 0, time.FixedZone("AEDT", 11*3600)), [5]byte{0xEF, 0x93, 0x23, 0x59, 0x59}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := EncodeMJDTime(tt.time)
+			if got != tt.want {
+				t.Errorf("EncodeMJDTime(%v) = % X, want % X", tt.time, got, tt.want)
+			}
+			if decoded := DecodeMJDTime(got); !decoded.Equal(tt.time) {
+				t.Errorf("DecodeMJDTime(% X) = %v, want %v", got, decoded, tt.time)
+			}
+		})
+	}
+}
+
+func TestBCDDuration(t *testing.T) {
+	tests := []struct {
+		name     string
+		duration time.Duration
+		want     [3]byte
+		decoded  time.Duration
+	}{
+		// The example of EN 300 468 Annex C.
+		{"annex C", time.Hour + 45*time.Minute + 30*time.Second, [3]byte{0x01, 0x45, 0x30}, time.Hour + 45*time.Minute + 30*time.Second},
+		{"zero", 0, [3]byte{0x00, 0x00, 0x00}, 0},
+		{"negative", -time.Minute, [3]byte{0x00, 0x00, 0x00}, 0},
+		{"fraction dropped", 90*time.Second + 500*time.Millisecond, [3]byte{0x00, 0x01, 0x30}, 90 * time.Second},
+		{"clamped", 120 * time.Hour, [3]byte{0x99, 0x59, 0x59}, 99*time.Hour + 59*time.Minute + 59*time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := EncodeBCDDuration(tt.duration)
+			if got != tt.want {
+				t.Errorf("EncodeBCDDuration(%v) = % X, want % X", tt.duration, got, tt.want)
+			}
+			if decoded := DecodeBCDDuration(got); decoded != tt.decoded {
+				t.Errorf("DecodeBCDDuration(% X) = %v, want %v", got, decoded, tt.decoded)
+			}
+		})
+	}
+}
+
+func TestBCDHourMinute(t *testing.T) {
+	tests := []struct {
+		name     string
+		duration time.Duration
+		want     [2]byte
+	}{
+		{"UTC", 0, [2]byte{0x00, 0x00}},
+		{"east", 10*time.Hour + 30*time.Minute, [2]byte{0x10, 0x30}},
+		{"west", -3 * time.Hour, [2]byte{0x03, 0x00}},
+		{"seconds dropped", 5*time.Hour + 45*time.Minute + 59*time.Second, [2]byte{0x05, 0x45}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EncodeBCDHourMinute(tt.duration); got != tt.want {
+				t.Errorf("EncodeBCDHourMinute(%v) = % X, want % X", tt.duration, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+// section reassembly from transport stream packets
+package ts
+
+// Demux reassembles the sections carried on a set of PIDs.
+type Demux struct {
+	pids map[uint16]*sectionBuffer
+	// Discontinuities counts the continuity_counter errors seen, each losing
+	// the section in progress.
+	Discontinuities int
+}
+
+// sectionBuffer collects the bytes of a PID's section in progress.
+type sectionBuffer struct {
+	data       []byte
+	synced     bool
+	continuity int
+}
+
+// NewDemux returns a Demux collecting sections from pids.
+func NewDemux(pids ...uint16) *Demux {
+	d := &Demux{pids: map[uint16]*sectionBuffer{}}
+	for _, pid := range pids {
+		d.pids[pid] = &sectionBuffer{continuity: -1}
+	}
+	return d
+}
+
+// Push adds a packet and returns the sections it completes. Sections are
+// returned whole but unchecked, see dvb.ParseSection.
+func (d *Demux) Push(pkt *Packet) [][]byte {
+	buf, ok := d.pids[pkt.PID()]
+	if !ok {
+		return nil
+	}
+	// transport_error_indicator
+	if pkt[1]&0x80 != 0 {
+		buf.synced = false
+		return nil
+	}
+
+	adaptation := pkt[3] >> 4 & 0x03
+	if adaptation&0x01 == 0 {
+		return nil
+	}
+	continuity := int(pkt[3] & 0x0F)
+	if continuity == buf.continuity {
+		// A repeated packet.
+		return nil
+	}
+	if buf.continuity >= 0 && continuity != (buf.continuity+1)&0x0F && buf.synced {
+		d.Discontinuities++
+		buf.synced = false
+	}
+	buf.continuity = continuity
+
+	start := 4
+	if adaptation&0x02 != 0 {
+		start += 1 + int(pkt[4])
+	}
+	if start >= PacketSize {
+		return nil
+	}
+	payload := pkt[start:]
+
+	var sections [][]byte
+	if pkt[1]&0x40 == 0 {
+		if buf.synced {
+			buf.data = append(buf.data, payload...)
+			sections = buf.extract()
+		}
+		return sections
+	}
+
+	// payload_unit_start_indicator: a pointer_field gives where the first new section starts.
+	pointer := int(payload[0])
+	payload = payload[1:]
+	if pointer > len(payload) {
+		buf.synced = false
+		return nil
+	}
+	if buf.synced {
+		buf.data = append(buf.data, payload[:pointer]...)
+		sections = buf.extract()
+	}
+	buf.data = append(buf.data[:0], payload[pointer:]...)
+	buf.synced = true
+	return append(sections, buf.extract()...)
+}
+
+// extract removes and returns the complete sections at the start of the buffer.
+func (b *sectionBuffer) extract() [][]byte {
+	var sections [][]byte
+	for len(b.data) >= 3 {
+		if b.data[0] == 0xFF {
+			// Stuffing up to the end of the packet.
+			b.data = b.data[:0]
+			break
+		}
+		size := 3 + (int(b.data[1]&0x0F)<<8 | int(b.data[2]))
+		if len(b.data) < size {
+			break
+		}
+		section := make([]byte, size)
+		copy(section, b.data)
+		sections = append(sections, section)
+		b.data = b.data[size:]
+	}
+	return sections
+}
@@ -0,0 +1,226 @@
+// PSI/SI injection into a running transport stream
+package ts
+
+import (
+	"context"
+	"io"
+	"log"
+	"time"
+)
+
+// Source is a repeatedly transmitted set of sections on a PID.
+type Source struct {
+	// Name identifies the source in log messages.
+	Name string
+	PID  uint16
+	// Interval between the starts of two transmissions of the sections.
+	Interval time.Duration
+	// Sections returns the sections to transmit at now.
+	Sections func(now time.Time) ([][]byte, error)
+
+	next    time.Time
+	busy    bool
+	results chan sourceResult
+}
+
+type sourceResult struct {
+	sections [][]byte
+	err      error
+}
+
+// pidQueue holds the sections waiting to be sent on one PID. Batches are
+// packetized whole, when their turn comes, so sections of different sources
+// never interleave on the PID and superseded batches cost no continuity_counter values.
+type pidQueue struct {
+	packetizer *Packetizer
+	current    []Packet
+	// pending holds the latest waiting batch per source, indexed like Injector.sources.
+	pending map[int][][]byte
+}
+
+// Injector replaces the packets of the PIDs its sources own in a transport
+// stream and fills stuffing and those freed packets with the sources' sections.
+// The stream's packet count never changes, so the sources' bitrate must come
+// out of the null packets already present in the input.
+type Injector struct {
+	// MaxBitrate caps the bitrate of all injected packets in bits/s, 0 means no limit.
+	MaxBitrate int
+	// TSBitrate is the bitrate of the input in bits/s. When set the injector
+	// clocks itself off the packet count, which suits file input, otherwise
+	// it follows the wall clock.
+	TSBitrate int
+
+	sources []*Source
+	queues  map[uint16]*pidQueue
+	pids    []uint16
+
+	start    time.Time
+	packets  int64
+	tokens   float64
+	lastTick time.Time
+
+	// Injected counts the packets inserted and Removed the input packets
+	// dropped from PIDs the sources own.
+	Injected int64
+	Removed  int64
+}
+
+// AddSource registers a source. Sources added first have priority when
+// several are waiting on the same PID.
+func (inj *Injector) AddSource(src *Source) {
+	if inj.queues == nil {
+		inj.queues = map[uint16]*pidQueue{}
+	}
+	src.results = make(chan sourceResult, 1)
+	inj.sources = append(inj.sources, src)
+	if _, ok := inj.queues[src.PID]; !ok {
+		inj.queues[src.PID] = &pidQueue{packetizer: NewPacketizer(src.PID), pending: map[int][][]byte{}}
+		inj.pids = append(inj.pids, src.PID)
+	}
+}
+
+// Run copies packets from in to out until in ends or ctx is cancelled.
+func (inj *Injector) Run(ctx context.Context, in io.Reader, out io.Writer) error {
+	reader := NewReader(in)
+	null := NullPacket()
+	buf := make([]byte, 0, udpPackets*PacketSize)
+	inj.start = time.Now()
+	inj.lastTick = inj.start
+
+	var pkt Packet
+	for ctx.Err() == nil {
+		err := reader.ReadPacket(&pkt)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		now := inj.clock()
+		inj.poll(now)
+
+		pid := pkt.PID()
+		_, owned := inj.queues[pid]
+		if owned {
+			inj.Removed++
+		}
+		if owned || pid == NullPID {
+			if injected, ok := inj.nextPacket(now); ok {
+				pkt = injected
+				inj.Injected++
+			} else if owned {
+				pkt = null
+			}
+		}
+
+		buf = append(buf, pkt[:]...)
+		if len(buf) == cap(buf) {
+			if _, err := out.Write(buf); err != nil {
+				return err
+			}
+			buf = buf[:0]
+		}
+		inj.packets++
+	}
+
+	if len(buf) > 0 {
+		if _, err := out.Write(buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// clock returns the stream time of the packet being processed.
+func (inj *Injector) clock() time.Time {
+	if inj.TSBitrate > 0 {
+		elapsed := time.Duration(float64(inj.packets*PacketSize*8) / float64(inj.TSBitrate) * float64(time.Second))
+		return inj.start.Add(elapsed)
+	}
+	return time.Now()
+}
+
+// poll starts the generation of due sources and queues finished ones.
+func (inj *Injector) poll(now time.Time) {
+	for i, src := range inj.sources {
+		inj.collect(i, src)
+
+		if !src.busy && !now.Before(src.next) {
+			src.busy = true
+			src.next = now.Add(src.Interval)
+			generate := func(src *Source, now time.Time) {
+				sections, err := src.Sections(now)
+				src.results <- sourceResult{sections, err}
+			}
+			// A live stream must not stall while the sections are built, a
+			// stream clocked off its packet count waits for them instead.
+			if inj.TSBitrate > 0 {
+				generate(src, now)
+				inj.collect(i, src)
+			} else {
+				go generate(src, now)
+			}
+		}
+	}
+}
+
+// collect queues the sections of source i if its generation has finished.
+func (inj *Injector) collect(i int, src *Source) {
+	select {
+	case result := <-src.results:
+		src.busy = false
+		if result.err != nil {
+			log.Printf("inject: %s: %v", src.Name, result.err)
+			return
+		}
+		if len(result.sections) > 0 {
+			inj.queues[src.PID].pending[i] = result.sections
+		}
+	default:
+	}
+}
+
+// nextPacket returns the next packet to inject if one is waiting and the
+// bitrate limit allows it.
+func (inj *Injector) nextPacket(now time.Time) (Packet, bool) {
+	if inj.MaxBitrate > 0 {
+		elapsed := now.Sub(inj.lastTick).Seconds()
+		inj.lastTick = now
+		rate := float64(inj.MaxBitrate) / (PacketSize * 8)
+		// Allow a burst of a tenth of a second worth of packets.
+		inj.tokens = min(inj.tokens+elapsed*rate, max(1, rate/10))
+		if inj.tokens < 1 {
+			return Packet{}, false
+		}
+	}
+
+	for _, pid := range inj.pids {
+		queue := inj.queues[pid]
+		if len(queue.current) == 0 {
+			queue.current = queue.takePending(len(inj.sources))
+		}
+		if len(queue.current) == 0 {
+			continue
+		}
+		pkt := queue.current[0]
+		queue.current = queue.current[1:]
+		inj.tokens--
+		// Round robin between PIDs.
+		inj.pids = append(inj.pids[1:], inj.pids[0])
+		return pkt, true
+	}
+	return Packet{}, false
+}
+
+// takePending removes the waiting batch of the highest priority source and
+// returns its packets.
+func (q *pidQueue) takePending(sources int) []Packet {
+	for i := 0; i < sources; i++ {
+		if batch, ok := q.pending[i]; ok {
+			delete(q.pending, i)
+			return q.packetizer.Packetize(batch)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+// transport stream inputs and outputs: stdin/stdout, files and UDP
+package ts
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+)
+
+// udpPackets is the customary number of packets per UDP datagram (1316 bytes).
+const udpPackets = 7
+
+// OpenInput opens a transport stream source: "-" for stdin, "udp://[group]:port"
+// to receive UDP (joining group when it is multicast) or a file path.
+func OpenInput(spec string) (io.ReadCloser, error) {
+	switch {
+	case spec == "-" || spec == "":
+		return io.NopCloser(os.Stdin), nil
+	case strings.HasPrefix(spec, "udp://"):
+		addr, err := net.ResolveUDPAddr("udp", strings.TrimPrefix(strings.TrimPrefix(spec, "udp://"), "@"))
+		if err != nil {
+			return nil, err
+		}
+		var conn *net.UDPConn
+		if addr.IP != nil && addr.IP.IsMulticast() {
+			conn, err = net.ListenMulticastUDP("udp", nil, addr)
+		} else {
+			conn, err = net.ListenUDP("udp", addr)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &datagramReader{conn: conn, buf: make([]byte, 65536)}, nil
+	default:
+		return os.Open(spec)
+	}
+}
+
+// OpenOutput opens a transport stream destination: "-" for stdout,
+// "udp://host:port" to send UDP datagrams of 7 packets or a file path.
+func OpenOutput(spec string) (io.WriteCloser, error) {
+	switch {
+	case spec == "-" || spec == "":
+		return nopWriteCloser{os.Stdout}, nil
+	case strings.HasPrefix(spec, "udp://"):
+		addr, err := net.ResolveUDPAddr("udp", strings.TrimPrefix(spec, "udp://"))
+		if err != nil {
+			return nil, err
+		}
+		conn, err := net.DialUDP("udp", nil, addr)
+		if err != nil {
+			return nil, err
+		}
+		return &datagramWriter{conn: conn}, nil
+	default:
+		return os.Create(spec)
+	}
+}
+
+// datagramReader turns UDP datagrams into a byte stream.
+type datagramReader struct {
+	conn *net.UDPConn
+	buf  []byte
+	data []byte
+}
+
+func (dr *datagramReader) Read(p []byte) (int, error) {
+	for len(dr.data) == 0 {
+		n, err := dr.conn.Read(dr.buf)
+		if err != nil {
+			return 0, err
+		}
+		dr.data = dr.buf[:n]
+	}
+	n := copy(p, dr.data)
+	dr.data = dr.data[n:]
+	return n, nil
+}
+
+func (dr *datagramReader) Close() error {
+	return dr.conn.Close()
+}
+
+// datagramWriter sends whatever it is given as datagrams of at most 7 packets.
+type datagramWriter struct {
+	conn *net.UDPConn
+}
+
+func (dw *datagramWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		n := min(len(p), udpPackets*PacketSize)
+		if _, err := dw.conn.Write(p[:n]); err != nil {
+			return written, err
+		}
+		written += n
+		p = p[n:]
+	}
+	return written, nil
+}
+
+func (dw *datagramWriter) Close() error {
+	return dw.conn.Close()
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// Reader reads packets from a byte stream, resynchronising on the sync byte
+// whenever the stream is not packet aligned.
+type Reader struct {
+	r *bufio.Reader
+}
+
+// NewReader returns a Reader reading from r.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReaderSize(r, 64*PacketSize)}
+}
+
+// ReadPacket reads the next packet, it returns io.EOF at the end of the stream.
+func (pr *Reader) ReadPacket(pkt *Packet) error {
+	for {
+		head, err := pr.r.Peek(1)
+		if err != nil {
+			return err
+		}
+		if head[0] == SyncByte {
+			break
+		}
+		// Skip to the next candidate sync byte.
+		if _, err := pr.r.Discard(1); err != nil {
+			return err
+		}
+	}
+	_, err := io.ReadFull(pr.r, pkt[:])
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return io.EOF
+	}
+	if err != nil {
+		return fmt.Errorf("read packet: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+// MPEG transport stream packets (ISO/IEC 13818-1 clause 2.4.3)
+package ts
+
+const (
+	// PacketSize is the size of a transport stream packet.
+	PacketSize = 188
+	// SyncByte starts every packet.
+	SyncByte = 0x47
+	// NullPID carries stuffing packets.
+	NullPID = 0x1FFF
+	// headerSize is the packet header without adaptation field.
+	headerSize = 4
+	// payloadSize is the payload of a packet without adaptation field.
+	payloadSize = PacketSize - headerSize
+)
+
+// Packet is a single transport stream packet.
+type Packet [PacketSize]byte
+
+// PID returns the packet identifier.
+func (p *Packet) PID() uint16 {
+	return uint16(p[1]&0x1F)<<8 | uint16(p[2])
+}
+
+// NullPacket returns a stuffing packet.
+func NullPacket() Packet {
+	var p Packet
+	p[0] = SyncByte
+	p[1] = NullPID >> 8
+	p[2] = NullPID & 0xFF
+	p[3] = 0x10
+	for i := headerSize; i < PacketSize; i++ {
+		p[i] = 0xFF
+	}
+	return p
+}
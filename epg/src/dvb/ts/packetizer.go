@@ -0,0 +1,67 @@
+// section to packet conversion
+package ts
+
+// Packetizer carries sections in transport stream packets on one PID,
+// keeping the PID's continuity_counter across calls.
+type Packetizer struct {
+	PID        uint16
+	continuity uint8
+}
+
+// NewPacketizer returns a Packetizer for pid.
+func NewPacketizer(pid uint16) *Packetizer {
+	return &Packetizer{PID: pid}
+}
+
+// Packetize returns the packets carrying sections back to back, signalling each
+// section start with payload_unit_start_indicator and pointer_field. The last
+// packet is padded with 0xFF stuffing bytes.
+func (p *Packetizer) Packetize(sections [][]byte) []Packet {
+	var data []byte
+	var starts []int
+	for _, s := range sections {
+		starts = append(starts, len(data))
+		data = append(data, s...)
+	}
+
+	var packets []Packet
+	pos := 0
+	for pos < len(data) {
+		for len(starts) > 0 && starts[0] < pos {
+			starts = starts[1:]
+		}
+
+		var pkt Packet
+		pkt[0] = SyncByte
+		pkt[1] = byte(p.PID>>8) & 0x1F
+		pkt[2] = byte(p.PID)
+		// no scrambling, payload only
+		pkt[3] = 0x10 | p.continuity
+		p.continuity = (p.continuity + 1) & 0x0F
+
+		payload := pkt[headerSize:]
+		var end int
+		switch {
+		case len(starts) > 0 && starts[0]-pos < payloadSize-1:
+			// A section starts in this packet, point at it.
+			pkt[1] |= 0x40
+			payload[0] = byte(starts[0] - pos)
+			n := copy(payload[1:], data[pos:])
+			pos += n
+			end = 1 + n
+		case len(starts) > 0 && starts[0]-pos < payloadSize:
+			// The next section would start in the last byte, where no
+			// pointer_field can reach it, so finish the current one and stuff.
+			end = copy(payload, data[pos:starts[0]])
+			pos = starts[0]
+		default:
+			end = copy(payload, data[pos:])
+			pos += end
+		}
+		for i := end; i < len(payload); i++ {
+			payload[i] = 0xFF
+		}
+		packets = append(packets, pkt)
+	}
+	return packets
+}
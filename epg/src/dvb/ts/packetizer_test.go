@@ -0,0 +1,81 @@
+package ts
+
+import (
+	"bytes"
+	"testing"
+)
+
+// section returns a section of n bytes filled with b.
+func section(n int, b byte) []byte {
+	return bytes.Repeat([]byte{b}, n)
+}
+
+// expected is what the packetizer tests check of a packet.
+type expected struct {
+	start   bool
+	payload []byte
+}
+
+// stuffed pads a payload to a whole packet with 0xFF.
+func stuffed(parts ...[]byte) []byte {
+	payload := bytes.Join(parts, nil)
+	return append(payload, bytes.Repeat([]byte{0xFF}, payloadSize-len(payload))...)
+}
+
+func TestPacketize(t *testing.T) {
+	tests := []struct {
+		name     string
+		sections [][]byte
+		want     []expected
+	}{
+		{"one section", [][]byte{section(10, 1)}, []expected{{true, stuffed([]byte{0}, section(10, 1))}}},
+		{
+			"section starting in a packet",
+			[][]byte{section(200, 1), section(50, 2)},
+			[]expected{
+				{true, stuffed([]byte{0}, section(183, 1))},
+				{true, stuffed([]byte{17}, section(17, 1), section(50, 2))},
+			},
+		},
+		{
+			"section starting a packet",
+			[][]byte{section(183, 1), section(10, 2)},
+			[]expected{
+				{true, stuffed([]byte{0}, section(183, 1))},
+				{true, stuffed([]byte{0}, section(10, 2))},
+			},
+		},
+		{
+			// The second section would start in the last byte of the second packet.
+			"section start out of reach",
+			[][]byte{section(366, 1), section(10, 2)},
+			[]expected{
+				{true, stuffed([]byte{0}, section(183, 1))},
+				{false, stuffed(section(183, 1))},
+				{true, stuffed([]byte{0}, section(10, 2))},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewPacketizer(0x12)
+			// The continuity_counter carries on from earlier calls.
+			p.continuity = 0x0F
+			packets := p.Packetize(tt.sections)
+			if len(packets) != len(tt.want) {
+				t.Fatalf("Packetize() returned %d packets, want %d", len(packets), len(tt.want))
+			}
+			for i, pkt := range packets {
+				if pkt[0] != SyncByte || pkt.PID() != 0x12 || pkt[3] != 0x10|byte(i+0x0F)&0x0F {
+					t.Errorf("packet %d header = % X", i, pkt[:headerSize])
+				}
+				if start := pkt[1]&0x40 != 0; start != tt.want[i].start {
+					t.Errorf("packet %d payload_unit_start_indicator = %t, want %t", i, start, tt.want[i].start)
+				}
+				if !bytes.Equal(pkt[headerSize:], tt.want[i].payload) {
+					t.Errorf("packet %d payload = % X, want % X", i, pkt[headerSize:], tt.want[i].payload)
+				}
+			}
+		})
+	}
+}
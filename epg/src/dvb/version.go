@@ -0,0 +1,62 @@
+// version_number tracking for repeatedly generated sub-tables
+package dvb
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"sync"
+)
+
+// SubTableKey identifies a sub-table. Extra holds any identity carried outside the
+// section header, e.g. transport_stream_id and original_network_id for EIT and SDT.
+type SubTableKey struct {
+	TableID          uint8
+	TableIDExtension uint16
+	Extra            uint32
+}
+
+type versionState struct {
+	version uint8
+	digest  [sha256.Size]byte
+}
+
+// VersionTracker hands out version_number values, incrementing a sub-table's
+// version modulo 32 whenever its content changes between generations.
+type VersionTracker struct {
+	mu     sync.Mutex
+	tables map[SubTableKey]versionState
+}
+
+// NewVersionTracker returns an empty VersionTracker.
+func NewVersionTracker() *VersionTracker {
+	return &VersionTracker{tables: map[SubTableKey]versionState{}}
+}
+
+// Apply sets the version of every section of a sub-table, bumping it when the
+// sections differ from the previous call with the same key.
+func (vt *VersionTracker) Apply(key SubTableKey, sections []Section) {
+	h := sha256.New()
+	for _, s := range sections {
+		var header [8]byte
+		header[0] = s.SectionNumber
+		header[1] = s.LastSectionNumber
+		binary.BigEndian.PutUint32(header[4:], uint32(len(s.Payload)))
+		h.Write(header[:])
+		h.Write(s.Payload)
+	}
+	var digest [sha256.Size]byte
+	copy(digest[:], h.Sum(nil))
+
+	vt.mu.Lock()
+	state, ok := vt.tables[key]
+	if ok && state.digest != digest {
+		state.version = (state.version + 1) & 0x1F
+	}
+	state.digest = digest
+	vt.tables[key] = state
+	vt.mu.Unlock()
+
+	for i := range sections {
+		sections[i].Version = state.version
+	}
+}
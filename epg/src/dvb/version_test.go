@@ -0,0 +1,42 @@
+package dvb
+
+import "testing"
+
+func TestVersionTracker(t *testing.T) {
+	vt := NewVersionTracker()
+	key := SubTableKey{TableID: 0x4E, TableIDExtension: 1000}
+	apply := func(key SubTableKey, payload string) uint8 {
+		sections := []Section{{Payload: []byte(payload)}, {SectionNumber: 1, Payload: []byte(payload)}}
+		vt.Apply(key, sections)
+		if sections[0].Version != sections[1].Version {
+			t.Fatalf("Apply() versions %d and %d differ", sections[0].Version, sections[1].Version)
+		}
+		return sections[0].Version
+	}
+
+	tests := []struct {
+		name    string
+		key     SubTableKey
+		payload string
+		want    uint8
+	}{
+		{"first", key, "a", 0},
+		{"unchanged", key, "a", 0},
+		{"changed", key, "b", 1},
+		{"other sub-table", SubTableKey{TableID: 0x4E, TableIDExtension: 1001}, "a", 0},
+		{"changed back", key, "a", 2},
+	}
+	for _, tt := range tests {
+		if got := apply(tt.key, tt.payload); got != tt.want {
+			t.Errorf("%s: version = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+
+	// version_number is 5 bits, it wraps after 31.
+	for i := 3; i <= 32; i++ {
+		apply(key, string(rune('a'+i%2)))
+	}
+	if got := apply(key, "c"); got != 1 {
+		t.Errorf("version after wrapping = %d, want 1", got)
+	}
+}
@@ -11,19 +11,46 @@ import (
 
 // Channel represents a channel with endpoint responses.
 type Channel struct {
-	ChannelID           uint      `gorm:"primaryKey;autoIncrement" json:"channelID"`
-	NetworkID           uint      `gorm:"not null" json:"networkID"`
-	Description         string    `gorm:"type:text;not null" json:"description"`
-	BroadcastStartTime  time.Time `gorm:"not null" json:"broadcastStartTime"`
-	BroadcastFinishTime time.Time `gorm:"not null" json:"broadcastFinishTime"`
-	ServiceID           uint      `gorm:"not null" json:"serviceID"`
-	ServiceVPid         uint      `gorm:"not null" json:"serviceVPid"`
-	ServiceAPid         uint      `gorm:"not null" json:"serviceAPid"`
-	AuthorityMeta       *string   `gorm:"type:text" json:"authorityMeta"`
-	LogoName            *string   `gorm:"type:text" json:"logoName"`
-	Network             Network   `gorm:"foreignKey:NetworkID;constraint:OnDelete:CASCADE" json:"-"`
-	Events              []Event   `gorm:"foreignKey:ChannelID" json:"events"`
-	NetworkName         string    `gorm:"-" json:"networkName"`
+	ChannelID            uint               `gorm:"primaryKey;autoIncrement" json:"channelID"`
+	NetworkID            uint               `gorm:"not null" json:"networkID"`
+	Description          string             `gorm:"type:text;not null" json:"description"`
+	BroadcastStartTime   time.Time          `gorm:"not null" json:"broadcastStartTime"`
+	BroadcastFinishTime  time.Time          `gorm:"not null" json:"broadcastFinishTime"`
+	ServiceID            uint               `gorm:"not null" json:"serviceID"`
+	LogicalChannelNumber uint               `gorm:"not null;default:0" json:"logicalChannelNumber"`
+	ServiceVPid          uint               `gorm:"not null" json:"serviceVPid"`
+	ServiceAPid          uint               `gorm:"not null" json:"serviceAPid"`
+	PmtPid               uint               `gorm:"not null;default:0" json:"pmtPid"`
+	PcrPid               uint               `gorm:"not null;default:0" json:"pcrPid"`
+	VideoStreamType      uint8              `gorm:"not null;default:27" json:"videoStreamType"`
+	AudioStreamType      uint8              `gorm:"not null;default:3" json:"audioStreamType"`
+	AuthorityMeta        *string            `gorm:"type:text" json:"authorityMeta"`
+	LogoName             *string            `gorm:"type:text" json:"logoName"`
+	Network              Network            `gorm:"foreignKey:NetworkID;references:NetworkID;constraint:OnDelete:CASCADE" json:"-"`
+	Events               []Event            `gorm:"foreignKey:ChannelID" json:"events"`
+	ElementaryStreams    []ElementaryStream `gorm:"foreignKey:ChannelID" json:"elementaryStreams"`
+	NetworkName          string             `gorm:"-" json:"networkName"`
+}
+
+// Elementary stream kinds, they select the descriptors of the stream in the PMT.
+const (
+	StreamKindVideo    = "video"
+	StreamKindAudio    = "audio"
+	StreamKindSubtitle = "subtitle"
+	StreamKindTeletext = "teletext"
+	StreamKindData     = "data"
+)
+
+// ElementaryStream is an additional stream of a channel carried in its PMT,
+// such as DVB subtitles or a second audio track.
+type ElementaryStream struct {
+	ElementaryStreamID uint    `gorm:"primaryKey;autoIncrement" json:"elementaryStreamID"`
+	ChannelID          uint    `gorm:"not null" json:"channelID"`
+	Pid                uint    `gorm:"not null" json:"pid"`
+	StreamType         uint8   `gorm:"not null" json:"streamType"`
+	Kind               string  `gorm:"type:text;not null" json:"kind"`
+	Language           *string `gorm:"type:char(3)" json:"language"`
+	Channel            Channel `gorm:"foreignKey:ChannelID;references:ChannelID;constraint:OnDelete:CASCADE" json:"-"`
 }
 
 // PopulateInitialChannelValues populates the database with initial channel values called from init()
@@ -32,15 +59,31 @@ func PopulateInitialChannelValues(db *gorm.DB) error {
 	for _, channelConfig := range config.Config.Channels {
 		// Create a new channel instance
 		channel := &Channel{
-			NetworkID:           channelConfig.NetworkID,
-			Description:         channelConfig.Description,
-			BroadcastStartTime:  channelConfig.BroadcastStartTime,
-			BroadcastFinishTime: channelConfig.BroadcastFinishTime,
-			ServiceID:           channelConfig.ServiceID,
-			ServiceVPid:         channelConfig.ServiceVPid,
-			ServiceAPid:         channelConfig.ServiceAPid,
-			AuthorityMeta:       &channelConfig.AuthorityMeta,
-			LogoName:            &channelConfig.LogoName,
+			NetworkID:            channelConfig.NetworkID,
+			Description:          channelConfig.Description,
+			BroadcastStartTime:   channelConfig.BroadcastStartTime,
+			BroadcastFinishTime:  channelConfig.BroadcastFinishTime,
+			ServiceID:            channelConfig.ServiceID,
+			LogicalChannelNumber: channelConfig.LogicalChannelNumber,
+			ServiceVPid:          channelConfig.ServiceVPid,
+			ServiceAPid:          channelConfig.ServiceAPid,
+			PmtPid:               channelConfig.PmtPid,
+			PcrPid:               channelConfig.PcrPid,
+			VideoStreamType:      channelConfig.VideoStreamType,
+			AudioStreamType:      channelConfig.AudioStreamType,
+			AuthorityMeta:        &channelConfig.AuthorityMeta,
+			LogoName:             &channelConfig.LogoName,
+		}
+		for _, streamConfig := range channelConfig.ElementaryStreams {
+			stream := ElementaryStream{
+				Pid:        streamConfig.Pid,
+				StreamType: streamConfig.StreamType,
+				Kind:       streamConfig.Kind,
+			}
+			if streamConfig.Language != "" {
+				stream.Language = &streamConfig.Language
+			}
+			channel.ElementaryStreams = append(channel.ElementaryStreams, stream)
 		}
 
 		// Insert the channel instance into the database
@@ -52,3 +95,35 @@ func PopulateInitialChannelValues(db *gorm.DB) error {
 
 	return nil
 }
+
+// DeleteChannels removes channels for good with everything hanging off them:
+// their events with the events' ratings and genres, their elementary streams
+// and their schedule rules. It returns the number of events removed. SQLite
+// does not enforce the foreign keys, so the cascade is done here; call it in
+// a transaction.
+func DeleteChannels(tx *gorm.DB, channelIDs []uint) (int64, error) {
+	if len(channelIDs) == 0 {
+		return 0, nil
+	}
+	events := tx.Unscoped().Model(&Event{}).Select("event_id").Where("channel_id IN ?", channelIDs)
+	if err := tx.Where("event_id IN (?)", events).Delete(&EventRating{}).Error; err != nil {
+		return 0, err
+	}
+	if err := tx.Where("event_id IN (?)", events).Delete(&EventGenre{}).Error; err != nil {
+		return 0, err
+	}
+	result := tx.Unscoped().Where("channel_id IN ?", channelIDs).Delete(&Event{})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if err := tx.Where("channel_id IN ?", channelIDs).Delete(&ElementaryStream{}).Error; err != nil {
+		return 0, err
+	}
+	if err := tx.Where("channel_id IN ?", channelIDs).Delete(&ScheduleRule{}).Error; err != nil {
+		return 0, err
+	}
+	if err := tx.Where("channel_id IN ?", channelIDs).Delete(&Channel{}).Error; err != nil {
+		return 0, err
+	}
+	return result.RowsAffected, nil
+}
@@ -2,9 +2,7 @@
 package model
 
 import (
-	"encoding/csv"
-	"os"
-	"strconv"
+	"fmt"
 	"time"
 
 	"gorm.io/gorm"
@@ -15,18 +13,23 @@ type Event struct {
 	ChannelID           uint           `gorm:"not null;index:idx_events_channel_start"`
 	StartTime           time.Time      `gorm:"not null;index:idx_events_channel_start"`
 	EndTime             time.Time      `gorm:"not null"`
+	DVBEventID          uint16         `gorm:"column:dvb_event_id;not null;default:0"`
 	Title               string         `gorm:"type:text;not null;index:idx_events_title"`
 	ShortDescription    *string        `gorm:"column:short_description;type:text"`
 	ExtendedDescription *string        `gorm:"column:extended_description;type:text"`
+	ProgrammeCRID       *string        `gorm:"column:programme_crid;type:text"`
+	SeriesCRID          *string        `gorm:"column:series_crid;type:text"`
 	GenreID             uint           `gorm:"not null"`
 	CategoryID          uint           `gorm:"not null"`
+	ScheduleRuleID      *uint          `gorm:"index"`
 	CreatedAt           time.Time      `gorm:"type:datetime;default:current_timestamp;not null"`
 	UpdatedAt           time.Time      `gorm:"type:datetime;default:current_timestamp;not null"`
 	DeletedAt           gorm.DeletedAt `gorm:"index"`
-	Channel             Channel        `gorm:"foreignKey:ChannelID;constraint:OnDelete:CASCADE"`
-	Category            Category       `gorm:"foreignKey:CategoryID;constraint:OnDelete:CASCADE"`
-	Genre               Genre          `gorm:"foreignKey:GenreID;constraint:OnDelete:CASCADE"`
+	Channel             Channel        `gorm:"foreignKey:ChannelID;references:ChannelID;constraint:OnDelete:CASCADE"`
+	Category            Category       `gorm:"foreignKey:CategoryID;references:CategoryID;constraint:OnDelete:CASCADE"`
+	Genre               Genre          `gorm:"foreignKey:GenreID;references:GenreID;constraint:OnDelete:CASCADE"`
 	EventRatings        []EventRating  `gorm:"foreignKey:EventID"`
+	EventGenres         []EventGenre   `gorm:"foreignKey:EventID"`
 }
 
 type EventRating struct {
@@ -36,122 +39,82 @@ type EventRating struct {
 	RatingValue   RatingValue `gorm:"foreignKey:RatingValueID;constraint:OnDelete:CASCADE"`
 }
 
-// EventTemplate represents an event template
-type EventTemplate struct {
-	Title         string
-	StartMinute   int
-	CategoryID    uint
-	GenreID       uint
-	RatingValueID uint
+// EventGenre associates additional genres with an event beyond its primary GenreID
+type EventGenre struct {
+	EventID uint  `gorm:"primaryKey"`
+	GenreID uint  `gorm:"primaryKey"`
+	Event   Event `gorm:"foreignKey:EventID;constraint:OnDelete:CASCADE"`
+	Genre   Genre `gorm:"foreignKey:GenreID;constraint:OnDelete:CASCADE"`
 }
 
-// PopulateInitialEvents populates the database with initial events from CSV template
-func (e *Event) PopulateInitialEvents(db *gorm.DB, channelIDs []uint, startTime time.Time) error {
-	// Read events template from CSV file
-	eventsTemplate, err := readEventsTemplate("./csv/events_template.csv")
-	if err != nil {
-		return err
-	}
-
-	// Loop through each channel
-	for _, channelID := range channelIDs {
-		// Loop through each event in the template
-		for _, eventTemplate := range eventsTemplate {
-			// Calculate the start and finish times for the event
-			eventStartTime := startTime.Add(time.Duration(eventTemplate.StartMinute) * time.Minute)
-			eventFinishTime := eventStartTime.Add(15 * time.Minute)
-
-			// Create a new event instance
-			event := &Event{
-				ChannelID:  channelID,
-				StartTime:  eventStartTime,
-				EndTime:    eventFinishTime,
-				Title:      eventTemplate.Title,
-				CategoryID: eventTemplate.CategoryID,
-				GenreID:    eventTemplate.GenreID,
-			}
-
-			// Check if the event already exists
-			var existingEvent Event
-			err = db.Where("start_time = ? AND channel_id = ?", eventStartTime, channelID).First(&existingEvent).Error
-			if err == nil {
-				// Event already exists, skip it
-				continue
-			}
-
-			// Insert the event instance into the database
-			err = db.Create(event).Error
-			if err != nil {
-				return err
-			}
-
-			// Create a new event rating instance
-			eventRating := &EventRating{
-				EventID:       event.EventID,
-				RatingValueID: eventTemplate.RatingValueID,
-			}
-
-			// Insert the event rating instance into the database
-			err = db.Create(eventRating).Error
-			if err != nil {
-				return err
-			}
-		}
-	}
-
-	return nil
+// BeforeSave stores the times in UTC, SQLite compares them as text, and gives
+// the event a DVB event_id when it has none yet.
+func (e *Event) BeforeSave(tx *gorm.DB) error {
+	e.StartTime = e.StartTime.UTC()
+	e.EndTime = e.EndTime.UTC()
+	return e.AssignDVBEventID(tx.Session(&gorm.Session{NewDB: true}), time.Now())
 }
 
-// readEventsTemplate reads the events template from a CSV file
-func readEventsTemplate(filename string) ([]EventTemplate, error) {
-	// Open the CSV file
-	file, err := os.Open(filename)
+// AssignDVBEventID keeps the event's DVB event_id unless it is 0 or another
+// event of the channel that has not ended at now holds it, in which case it
+// takes the one after the highest held. An event_id is only handed out again
+// once its event has ended and left the EIT, past 0xFFFF the lowest free one
+// is taken.
+func (e *Event) AssignDVBEventID(db *gorm.DB, now time.Time) error {
+	var ids []uint16
+	err := db.Model(&Event{}).
+		Scopes(Overlapping(now, time.Time{})).
+		Where("channel_id = ? AND event_id <> ? AND dvb_event_id <> 0", e.ChannelID, e.EventID).
+		Pluck("dvb_event_id", &ids).Error
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer file.Close()
-
-	// Create a new CSV reader
-	reader := csv.NewReader(file)
-
-	// Read the header row and discard it
-	_, err = reader.Read()
-	if err != nil {
-		return nil, err
+	held := map[uint16]bool{}
+	last := uint16(0)
+	for _, id := range ids {
+		held[id] = true
+		last = max(last, id)
 	}
-
-	// Read the CSV records
-	records, err := reader.ReadAll()
-	if err != nil {
-		return nil, err
+	if e.DVBEventID != 0 && !held[e.DVBEventID] {
+		return nil
 	}
-
-	// Create event templates from the records
-	var eventTemplates []EventTemplate
-	for _, record := range records {
-		eventTemplate := EventTemplate{
-			Title:         record[0],
-			StartMinute:   atoi(record[1]),
-			CategoryID:    uint(atoi(record[2])),
-			GenreID:       uint(atoi(record[3])),
-			RatingValueID: uint(atoi(record[4])),
+	for id := last + 1; id != last; id++ {
+		if id != 0 && !held[id] {
+			e.DVBEventID = id
+			return nil
 		}
-		eventTemplates = append(eventTemplates, eventTemplate)
 	}
-
-	return eventTemplates, nil
+	return fmt.Errorf("channel %d has no free DVB event_id", e.ChannelID)
 }
 
-// atoi converts a string to an integer, if negative then return 0
-func atoi(s string) int {
-	i, err := strconv.Atoi(s)
-	if err != nil {
-		return 0
-	}
-
-	if i < 0 {
-		return 0
+// Overlapping scopes a query of events to those overlapping [from, to), a
+// zero to leaving it open ended. The bounds may be in any zone, they are
+// compared in UTC as the times are stored.
+func Overlapping(from, to time.Time) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		db = db.Where("end_time > ?", from.UTC())
+		if !to.IsZero() {
+			db = db.Where("start_time < ?", to.UTC())
+		}
+		return db
 	}
+}
 
-	return i
+// FindChannelEvents returns the events of a channel overlapping [from, to) ordered by start time,
+// preloaded with everything needed to describe them on air. A zero to reaches the last event.
+func FindChannelEvents(db *gorm.DB, channelID uint, from, to time.Time) ([]Event, error) {
+	events := []Event{}
+	err := db.Preload("Genre").
+		Preload("Category").
+		Preload("EventRatings").
+		Preload("EventRatings.RatingValue").
+		Preload("EventRatings.RatingValue.RatingSystem").
+		Preload("EventRatings.RatingValue.RatingSystem.Country").
+		Preload("EventGenres").
+		Preload("EventGenres.Genre").
+		Scopes(Overlapping(from, to)).
+		Where("channel_id = ?", channelID).
+		Order("start_time").
+		Find(&events).Error
+	return events, err
 }
@@ -0,0 +1,69 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestDVBEventIDs(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = db.AutoMigrate(&Event{}); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	create := func(channelID uint, dvbEventID uint16, start time.Time) *Event {
+		t.Helper()
+		e := &Event{ChannelID: channelID, DVBEventID: dvbEventID, StartTime: start, EndTime: start.Add(time.Hour), Title: "Event"}
+		if err := db.Create(e).Error; err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		return e
+	}
+	tests := []struct {
+		name  string
+		event func() *Event
+		want  uint16
+	}{
+		{"first", func() *Event { return create(1, 0, now) }, 1},
+		{"next", func() *Event { return create(1, 0, now.Add(time.Hour)) }, 2},
+		{"other channel", func() *Event { return create(2, 0, now) }, 1},
+		{"kept", func() *Event { return create(1, 7, now.Add(2*time.Hour)) }, 7},
+		{"after the highest", func() *Event { return create(1, 0, now.Add(3*time.Hour)) }, 8},
+		{"held", func() *Event { return create(1, 2, now.Add(4*time.Hour)) }, 9},
+		{"ended", func() *Event { return create(1, 0xFFFF, now.Add(-2*time.Hour)) }, 0xFFFF},
+		{"free once ended", func() *Event { return create(1, 0xFFFF, now.Add(5*time.Hour)) }, 0xFFFF},
+		{"lowest free past 0xFFFF", func() *Event { return create(1, 0, now.Add(6*time.Hour)) }, 3},
+		{
+			"kept on update",
+			func() *Event {
+				var e Event
+				if err := db.First(&e, "channel_id = 1 AND dvb_event_id = 7").Error; err != nil {
+					t.Fatal(err)
+				}
+				e.Title = "Renamed"
+				if err := db.Save(&e).Error; err != nil {
+					t.Fatalf("Save() error = %v", err)
+				}
+				return &e
+			},
+			7,
+		},
+	}
+	for _, tt := range tests {
+		e := tt.event()
+		var stored Event
+		if err := db.First(&stored, e.EventID).Error; err != nil {
+			t.Fatal(err)
+		}
+		if e.DVBEventID != tt.want || stored.DVBEventID != tt.want {
+			t.Errorf("%s: DVB event_id = %d, stored %d, want %d", tt.name, e.DVBEventID, stored.DVBEventID, tt.want)
+		}
+	}
+}
@@ -15,10 +15,16 @@ type RatingValue struct {
 	Value          string        `gorm:"not null;type:text" json:"Value"`
 	MinAge         uint          `gorm:"not null" json:"MinAge"`
 	Description    string        `gorm:"type:text" json:"Description"`
-	RatingSystem   RatingSystem  `gorm:"foreignKey:RatingSystemID"`
+	RatingSystem   RatingSystem  `gorm:"foreignKey:RatingSystemID;references:RatingSystemID"`
 	EventRatings   []EventRating `gorm:"foreignKey:RatingValueID"`
 }
 
+// IconFile returns the file name of the value's icon under
+// static/img/ratings/<country>/, named without the spaces of values such as "MA 15+".
+func (rv *RatingValue) IconFile() string {
+	return strings.ReplaceAll(rv.Value, " ", "") + ".png"
+}
+
 // RatingSystem represents a rating system
 type RatingSystem struct {
 	RatingSystemID uint          `gorm:"primaryKey;autoIncrement" json:"RatingSystemID"`
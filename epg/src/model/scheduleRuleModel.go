@@ -0,0 +1,35 @@
+// schedule rule model
+package model
+
+import (
+	"time"
+)
+
+// ScheduleRule is a recurring programme slot of a channel, expanded into
+// events by the schedule package.
+type ScheduleRule struct {
+	ScheduleRuleID uint `gorm:"primaryKey;autoIncrement" json:"scheduleRuleID"`
+	ChannelID      uint `gorm:"not null;index" json:"channelID"`
+	// Recurrence is an RRULE subset such as "FREQ=WEEKLY;BYDAY=MO,WE" or
+	// "FREQ=MONTHLY;BYDAY=-1FR", see schedule.ParseRecurrence.
+	Recurrence string `gorm:"type:text;not null" json:"recurrence"`
+	// StartTime is the "15:04" start in the network's timezone.
+	StartTime       string     `gorm:"type:char(5);not null" json:"startTime"`
+	DurationMinutes uint       `gorm:"not null" json:"durationMinutes"`
+	ValidFrom       time.Time  `gorm:"not null" json:"validFrom"`
+	ValidUntil      *time.Time `json:"validUntil"`
+	// Title and DescriptionTemplate are text/templates of schedule.TemplateData.
+	Title               string       `gorm:"type:text;not null" json:"title"`
+	DescriptionTemplate *string      `gorm:"type:text" json:"descriptionTemplate"`
+	SeriesCRID          *string      `gorm:"column:series_crid;type:text" json:"seriesCRID"`
+	GenreID             uint         `gorm:"not null" json:"genreID"`
+	CategoryID          uint         `gorm:"not null" json:"categoryID"`
+	RatingValueID       *uint        `json:"ratingValueID"`
+	Disabled            bool         `gorm:"not null;default:false" json:"disabled"`
+	CreatedAt           time.Time    `gorm:"type:datetime;default:current_timestamp;not null" json:"createdAt"`
+	UpdatedAt           time.Time    `gorm:"type:datetime;default:current_timestamp;not null" json:"updatedAt"`
+	Channel             Channel      `gorm:"foreignKey:ChannelID;references:ChannelID;constraint:OnDelete:CASCADE" json:"-"`
+	Genre               Genre        `gorm:"foreignKey:GenreID;references:GenreID" json:"-"`
+	Category            Category     `gorm:"foreignKey:CategoryID;references:CategoryID" json:"-"`
+	RatingValue         *RatingValue `gorm:"foreignKey:RatingValueID;references:RatingValueID" json:"-"`
+}
@@ -2,6 +2,8 @@
 package model
 
 import (
+	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -26,6 +28,157 @@ type Timezone struct {
 	Country        Country   `gorm:"foreignKey:CountryCode;references:CountryCode"`
 }
 
+// Location returns the timezone as a time.Location, falling back to a fixed zone
+// at the standard offset when the name is unknown to the system tz database.
+func (t *Timezone) Location() *time.Location {
+	if t.TimezoneName != "" {
+		if loc, err := time.LoadLocation(t.TimezoneName); err == nil {
+			return loc
+		}
+	}
+	return time.FixedZone(t.TimezoneName, t.StandardOffset*60)
+}
+
+// HasDST reports whether the timezone observes daylight saving time.
+func (t *Timezone) HasDST() bool {
+	return t.DSTStartMonth != 0 && t.DSTEndMonth != 0 && t.DSTOffset != t.StandardOffset
+}
+
+// CheckTZ reports whether the timezones table agrees with the system tz
+// database on the timezone in the year of at: the name must be known to it
+// and its standard and daylight offsets must be those of the table. The tz
+// database has the real dates of each DST change, the table only a day and
+// month repeated every year, so OffsetAt and NextChange follow the tz
+// transitions when CheckTZ returns nil and the table's DST rule otherwise.
+func (t *Timezone) CheckTZ(at time.Time) error {
+	if t.TimezoneName == "" {
+		return fmt.Errorf("timezone of %s has no name", t.CountryCode)
+	}
+	loc, err := time.LoadLocation(t.TimezoneName)
+	if err != nil {
+		return err
+	}
+	standard, daylight := tzOffsets(loc, at.UTC().Year())
+	want := t.StandardOffset
+	if t.HasDST() {
+		want = t.DSTOffset
+	}
+	if standard != t.StandardOffset*60 || daylight != want*60 {
+		return fmt.Errorf("timezone %s offsets are %d/%d minutes in the tz database and %d/%d in the timezones table",
+			t.TimezoneName, standard/60, daylight/60, t.StandardOffset, want)
+	}
+	return nil
+}
+
+// tzLocation returns the timezone from the system tz database when CheckTZ
+// accepts it at at.
+func (t *Timezone) tzLocation(at time.Time) (loc *time.Location, ok bool) {
+	if t.CheckTZ(at) != nil {
+		return nil, false
+	}
+	loc, err := time.LoadLocation(t.TimezoneName)
+	return loc, err == nil
+}
+
+// tzOffsets returns the lowest and the highest UTC offset in seconds loc has
+// during year, its standard and daylight offsets.
+func tzOffsets(loc *time.Location, year int) (standard, daylight int) {
+	t := time.Date(year, time.January, 1, 0, 0, 0, 0, loc)
+	end := t.AddDate(1, 0, 0)
+	_, standard = t.Zone()
+	daylight = standard
+	for t.Before(end) {
+		_, offset := t.Zone()
+		standard, daylight = min(standard, offset), max(daylight, offset)
+		_, next := t.ZoneBounds()
+		if next.IsZero() {
+			break
+		}
+		t = next
+	}
+	return standard, daylight
+}
+
+// OffsetAt returns the UTC offset of the timezone at the instant at, from the
+// system tz database or, when CheckTZ rejects it, the timezones table.
+func (t *Timezone) OffsetAt(at time.Time) time.Duration {
+	if loc, ok := t.tzLocation(at); ok {
+		_, offset := at.In(loc).Zone()
+		return time.Duration(offset) * time.Second
+	}
+	offset := minutes(t.StandardOffset)
+	for _, change := range t.dstChanges(at.UTC().Year()) {
+		if change.at.After(at) {
+			break
+		}
+		offset = change.offset
+	}
+	return offset
+}
+
+// NextChange returns the first change of UTC offset after the instant at and
+// the offset that applies from then on, from the system tz database or, when
+// CheckTZ rejects it, the DST rule of the timezones table. ok is false when
+// the offset does not change again.
+func (t *Timezone) NextChange(at time.Time) (change time.Time, offset time.Duration, ok bool) {
+	if loc, found := t.tzLocation(at); found {
+		local := at.In(loc)
+		_, current := local.Zone()
+		// Transitions that only rename the zone keep the offset, look past them.
+		for i := 0; i < 8; i++ {
+			_, end := local.ZoneBounds()
+			if end.IsZero() {
+				return time.Time{}, 0, false
+			}
+			if _, next := end.Zone(); next != current {
+				return end.UTC(), time.Duration(next) * time.Second, true
+			}
+			local = end
+		}
+		return time.Time{}, 0, false
+	}
+	for _, c := range t.dstChanges(at.UTC().Year()) {
+		if c.at.After(at) {
+			return c.at, c.offset, true
+		}
+	}
+	return time.Time{}, 0, false
+}
+
+// dstChange is the instant a new UTC offset takes effect.
+type dstChange struct {
+	at     time.Time
+	offset time.Duration
+}
+
+// dstChanges returns the DST changes from the year before year to the year
+// after, in order. The timezones table only holds the day and month of each
+// change so the same dates repeat every year, which real rules such as "the
+// first Sunday of October" do not, see CheckTZ. DST starts at DSTStartTime in
+// standard time and ends at DSTEndTime in daylight time.
+func (t *Timezone) dstChanges(year int) []dstChange {
+	if !t.HasDST() {
+		return nil
+	}
+	standard, daylight := minutes(t.StandardOffset), minutes(t.DSTOffset)
+	var changes []dstChange
+	for y := year - 1; y <= year+1; y++ {
+		start := time.Date(y, time.Month(t.DSTStartMonth), t.DSTStartDay, t.DSTStartTime.Hour(), t.DSTStartTime.Minute(), 0, 0, time.UTC)
+		end := time.Date(y, time.Month(t.DSTEndMonth), t.DSTEndDay, t.DSTEndTime.Hour(), t.DSTEndTime.Minute(), 0, 0, time.UTC)
+		changes = append(changes,
+			dstChange{at: start.Add(-standard), offset: daylight},
+			dstChange{at: end.Add(-daylight), offset: standard},
+		)
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].at.Before(changes[j].at) })
+	return changes
+}
+
+// minutes converts an offset in minutes from the timezones table to a duration.
+func minutes(m int) time.Duration {
+	return time.Duration(m) * time.Minute
+}
+
 // LoadFromCSV loads timezones from a CSV file
 func (t *Timezone) LoadFromCSV(db *gorm.DB, filename string) error {
 	// Load the CSV records
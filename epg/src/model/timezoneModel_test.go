@@ -0,0 +1,84 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+// sydney returns the Australia/Sydney row of the timezones table.
+func sydney() Timezone {
+	return Timezone{
+		CountryCode:    "AU",
+		TimezoneName:   "Australia/Sydney",
+		StandardOffset: 600,
+		DSTOffset:      660,
+		DSTStartMonth:  10,
+		DSTStartDay:    6,
+		DSTStartTime:   time.Date(0, 1, 1, 2, 0, 0, 0, time.UTC),
+		DSTEndMonth:    4,
+		DSTEndDay:      7,
+		DSTEndTime:     time.Date(0, 1, 1, 3, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestCheckTZ(t *testing.T) {
+	at := time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)
+	noDST := sydney()
+	noDST.DSTOffset = noDST.StandardOffset
+	unknown := sydney()
+	unknown.TimezoneName = "Australia/Nowhere"
+	tests := []struct {
+		name    string
+		tz      Timezone
+		wantErr bool
+	}{
+		{"matching", sydney(), false},
+		{"DST missing from the table", noDST, true},
+		{"unknown to the tz database", unknown, true},
+	}
+	for _, tt := range tests {
+		if err := tt.tz.CheckTZ(at); (err != nil) != tt.wantErr {
+			t.Errorf("%s: CheckTZ() error = %v, wantErr %t", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestOffsetChanges(t *testing.T) {
+	// DST started on 4 October 2026 in Sydney, the table has 6 October.
+	at := time.Date(2026, 10, 5, 0, 0, 0, 0, time.UTC)
+	unknown := sydney()
+	unknown.TimezoneName = "Australia/Nowhere"
+	tests := []struct {
+		name       string
+		tz         Timezone
+		offset     time.Duration
+		change     time.Time
+		nextOffset time.Duration
+	}{
+		{
+			"tz database",
+			sydney(),
+			11 * time.Hour,
+			// 03:00 daylight time on 4 April 2027.
+			time.Date(2027, 4, 3, 16, 0, 0, 0, time.UTC),
+			10 * time.Hour,
+		},
+		{
+			"timezones table",
+			unknown,
+			10 * time.Hour,
+			// 02:00 standard time on 6 October.
+			time.Date(2026, 10, 5, 16, 0, 0, 0, time.UTC),
+			11 * time.Hour,
+		},
+	}
+	for _, tt := range tests {
+		if got := tt.tz.OffsetAt(at); got != tt.offset {
+			t.Errorf("%s: OffsetAt() = %v, want %v", tt.name, got, tt.offset)
+		}
+		change, next, ok := tt.tz.NextChange(at)
+		if !ok || !change.Equal(tt.change) || next != tt.nextOffset {
+			t.Errorf("%s: NextChange() = %v, %v, %t, want %v, %v", tt.name, change, next, ok, tt.change, tt.nextOffset)
+		}
+	}
+}
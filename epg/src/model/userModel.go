@@ -0,0 +1,49 @@
+// user model
+package model
+
+import (
+	"time"
+)
+
+// Roles of users, each may do everything the roles before it may.
+const (
+	RoleViewer    = "viewer"
+	RoleScheduler = "scheduler"
+	RoleAdmin     = "admin"
+)
+
+// roleRanks orders the roles.
+var roleRanks = map[string]int{RoleViewer: 1, RoleScheduler: 2, RoleAdmin: 3}
+
+// ValidRole reports whether role is one of the roles.
+func ValidRole(role string) bool {
+	return roleRanks[role] > 0
+}
+
+// User is an account managing the EPG.
+type User struct {
+	UserID   uint   `gorm:"primaryKey;autoIncrement" json:"userID"`
+	Username string `gorm:"type:varchar(50);not null;unique" json:"username"`
+	// PasswordHash is the bcrypt hash of the password.
+	PasswordHash string    `gorm:"type:text;not null" json:"-"`
+	Role         string    `gorm:"type:varchar(20);not null" json:"role"`
+	Disabled     bool      `gorm:"not null;default:false" json:"disabled"`
+	CreatedAt    time.Time `gorm:"type:datetime;default:current_timestamp;not null" json:"createdAt"`
+	UpdatedAt    time.Time `gorm:"type:datetime;default:current_timestamp;not null" json:"updatedAt"`
+}
+
+// Can reports whether the user has role or a role above it.
+func (u *User) Can(role string) bool {
+	return !u.Disabled && roleRanks[u.Role] >= roleRanks[role]
+}
+
+// Session is a login of a user, found by the hash of its cookie's token.
+type Session struct {
+	// SessionID is the hex SHA-256 of the token, the token itself is only
+	// known to the browser.
+	SessionID string    `gorm:"type:char(64);primaryKey"`
+	UserID    uint      `gorm:"not null;index"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+	CreatedAt time.Time `gorm:"type:datetime;default:current_timestamp;not null"`
+	User      User      `gorm:"foreignKey:UserID;references:UserID;constraint:OnDelete:CASCADE"`
+}
@@ -0,0 +1,186 @@
+// Push of now/next changes to subscribers
+package nownext
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// settle is how long the broker waits after an edit before looking, so a
+	// burst of edits such as an import gives one update.
+	settle = 250 * time.Millisecond
+	// minWait keeps a change due in the past from spinning the broker.
+	minWait = 100 * time.Millisecond
+	// maxWait bounds the time between looks when no change is due, catching
+	// edits made behind the broker's back.
+	maxWait = 5 * time.Minute
+)
+
+// tables are the tables whose edits can change the now/next state.
+var tables = map[string]bool{"events": true, "event_ratings": true, "event_genres": true, "channels": true}
+
+// Broker recomputes the now/next state when an event boundary is crossed or
+// an event is edited, and sends the new state to its subscribers.
+type Broker struct {
+	db      *gorm.DB
+	changed chan struct{}
+
+	mu          sync.Mutex
+	subscribers map[chan []byte]bool
+	// last is the last state sent and lastChannels its channels, as JSON.
+	last         []byte
+	lastChannels []byte
+	closed       bool
+}
+
+// NewBroker returns a Broker notified of edits through GORM callbacks on db.
+func NewBroker(db *gorm.DB) (*Broker, error) {
+	b := &Broker{
+		db:          db,
+		changed:     make(chan struct{}, 1),
+		subscribers: map[chan []byte]bool{},
+	}
+	notify := func(tx *gorm.DB) {
+		if tx.Error == nil && tx.Statement.Schema != nil && tables[tx.Statement.Schema.Table] {
+			b.notify()
+		}
+	}
+	callbacks := db.Callback()
+	if err := callbacks.Create().After("gorm:create").Register("nownext:notify", notify); err != nil {
+		return nil, err
+	}
+	if err := callbacks.Update().After("gorm:update").Register("nownext:notify", notify); err != nil {
+		return nil, err
+	}
+	if err := callbacks.Delete().After("gorm:delete").Register("nownext:notify", notify); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// notify wakes the broker without blocking the edit.
+func (b *Broker) notify() {
+	select {
+	case b.changed <- struct{}{}:
+	default:
+	}
+}
+
+// Subscribe returns a channel receiving each new state as JSON, starting
+// with the current one. It is closed when the broker stops.
+func (b *Broker) Subscribe() chan []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	ch := make(chan []byte, 1)
+	if b.closed {
+		close(ch)
+		return ch
+	}
+	if b.last != nil {
+		ch <- b.last
+	}
+	b.subscribers[ch] = true
+	return ch
+}
+
+// Unsubscribe stops sending to ch.
+func (b *Broker) Unsubscribe(ch chan []byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.subscribers[ch] {
+		delete(b.subscribers, ch)
+		close(ch)
+	}
+}
+
+// Run looks at the now/next state until ctx is done, at every change due,
+// after edits and at least every maxWait. It closes the subscriptions when it
+// returns.
+func (b *Broker) Run(ctx context.Context) {
+	defer b.close()
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-b.changed:
+			// Let the edit commit and the rest of a burst arrive.
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(settle):
+			}
+		case <-timer.C:
+		}
+
+		wait := maxWait
+		snapshot, err := Find(b.db.WithContext(ctx), 0, time.Now())
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("Now/next: %v", err)
+			}
+		} else {
+			b.publish(snapshot)
+			if !snapshot.NextChange.IsZero() {
+				if until := time.Until(snapshot.NextChange); until < wait {
+					wait = max(until, minWait)
+				}
+			}
+		}
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(wait)
+	}
+}
+
+// publish sends snapshot to the subscribers when the channels changed.
+func (b *Broker) publish(snapshot *Snapshot) {
+	channels, err := json.Marshal(snapshot.Channels)
+	if err != nil {
+		log.Printf("Now/next: %v", err)
+		return
+	}
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		log.Printf("Now/next: %v", err)
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.last != nil && bytes.Equal(b.lastChannels, channels) {
+		return
+	}
+	b.last, b.lastChannels = data, channels
+	for ch := range b.subscribers {
+		// A slow subscriber gets the latest state rather than every one.
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- data
+	}
+}
+
+// close ends every subscription.
+func (b *Broker) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for ch := range b.subscribers {
+		delete(b.subscribers, ch)
+		close(ch)
+	}
+}
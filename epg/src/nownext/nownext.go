@@ -0,0 +1,118 @@
+// Present and following events of the channels, as receivers show them
+package nownext
+
+import (
+	"time"
+
+	"epg/src/dvb/eit"
+	"epg/src/model"
+
+	"gorm.io/gorm"
+)
+
+// lookahead bounds how far ahead the following event is looked for.
+const lookahead = 7 * 24 * time.Hour
+
+// Event is an event as shown on a now/next board.
+type Event struct {
+	EventID          uint      `json:"eventID"`
+	Title            string    `json:"title"`
+	ShortDescription *string   `json:"shortDescription"`
+	StartTime        time.Time `json:"startTime"`
+	EndTime          time.Time `json:"endTime"`
+	Genre            string    `json:"genre"`
+	Ratings          []string  `json:"ratings"`
+}
+
+// Channel is the present and following event of a channel.
+type Channel struct {
+	ChannelID            uint    `json:"channelID"`
+	Description          string  `json:"description"`
+	LogicalChannelNumber uint    `json:"logicalChannelNumber"`
+	LogoName             *string `json:"logoName"`
+	Present              *Event  `json:"present"`
+	Following            *Event  `json:"following"`
+}
+
+// Snapshot is the now/next state of the channels at a time.
+type Snapshot struct {
+	Time     time.Time `json:"time"`
+	Channels []Channel `json:"channels"`
+	// NextChange is when the present or following event of a channel next
+	// changes with time, zero when none will.
+	NextChange time.Time `json:"-"`
+}
+
+// Find returns the present and following events at now of channelID, or of
+// every channel when it is zero. The events are the ones the present/following
+// EIT carries.
+func Find(db *gorm.DB, channelID uint, now time.Time) (*Snapshot, error) {
+	channels := []model.Channel{}
+	query := db.Preload("Network").Preload("Network.Timezone").Order("logical_channel_number, channel_id")
+	if channelID != 0 {
+		query = query.Where("channel_id = ?", channelID)
+	}
+	if err := query.Find(&channels).Error; err != nil {
+		return nil, err
+	}
+	if channelID != 0 && len(channels) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	snapshot := &Snapshot{Time: now, Channels: []Channel{}}
+	for _, channel := range channels {
+		events, err := model.FindChannelEvents(db, channel.ChannelID, now, now.Add(lookahead))
+		if err != nil {
+			return nil, err
+		}
+		present, following := eit.FindPresentFollowing(events, now)
+		location := channel.Network.Timezone.Location()
+		snapshot.Channels = append(snapshot.Channels, Channel{
+			ChannelID:            channel.ChannelID,
+			Description:          channel.Description,
+			LogicalChannelNumber: channel.LogicalChannelNumber,
+			LogoName:             channel.LogoName,
+			Present:              newEvent(present, location),
+			Following:            newEvent(following, location),
+		})
+		for _, change := range changes(present, following) {
+			if snapshot.NextChange.IsZero() || change.Before(snapshot.NextChange) {
+				snapshot.NextChange = change
+			}
+		}
+	}
+	return snapshot, nil
+}
+
+// changes returns when the present and following events stop being so: at
+// the end of the present event and the start of the following one.
+func changes(present, following *model.Event) []time.Time {
+	var times []time.Time
+	if present != nil {
+		times = append(times, present.EndTime)
+	}
+	if following != nil {
+		times = append(times, following.StartTime)
+	}
+	return times
+}
+
+// newEvent returns the board entry of an event with times in location.
+func newEvent(e *model.Event, location *time.Location) *Event {
+	if e == nil {
+		return nil
+	}
+	event := &Event{
+		EventID:          e.EventID,
+		Title:            e.Title,
+		ShortDescription: e.ShortDescription,
+		StartTime:        e.StartTime.In(location),
+		EndTime:          e.EndTime.In(location),
+		Genre:            e.Genre.Description,
+		Ratings:          []string{},
+	}
+	for _, er := range e.EventRatings {
+		event.Ratings = append(event.Ratings, er.RatingValue.Value)
+	}
+	return event
+}
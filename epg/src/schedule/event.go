@@ -0,0 +1,109 @@
+// Validation of single events before they are stored
+package schedule
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"epg/src/api"
+	"epg/src/dvb/eit"
+	"epg/src/model"
+
+	"gorm.io/gorm"
+)
+
+// ValidateEvent checks an event about to be created or updated: it must have
+// a title, end after it starts, fall inside its channel's broadcast window,
+// not overlap another event of the channel, refer to a known genre and
+// category, and have texts that fit the EIT descriptors uncut. It returns an
+// *api.ValidationError listing every violation, or the error of a failed lookup.
+func ValidateEvent(db *gorm.DB, event *model.Event) error {
+	verr := &api.ValidationError{Resource: "event"}
+
+	if strings.TrimSpace(event.Title) == "" {
+		verr.Add("Title", api.ViolationRequired, "title is required")
+	}
+	if event.StartTime.IsZero() {
+		verr.Add("StartTime", api.ViolationRequired, "start time is required")
+	}
+	if event.EndTime.IsZero() {
+		verr.Add("EndTime", api.ViolationRequired, "end time is required")
+	}
+	ordered := event.EndTime.After(event.StartTime)
+	if !event.StartTime.IsZero() && !event.EndTime.IsZero() && !ordered {
+		verr.Add("EndTime", api.ViolationInvalid, "end time %s is not after start time %s",
+			event.EndTime.Format(time.RFC3339), event.StartTime.Format(time.RFC3339))
+	}
+
+	found, err := exists(db, &model.Genre{}, "genre_id", event.GenreID)
+	if err != nil {
+		return err
+	}
+	if !found {
+		verr.Add("GenreID", api.ViolationUnknown, "genre %d does not exist", event.GenreID)
+	}
+	found, err = exists(db, &model.Category{}, "category_id", event.CategoryID)
+	if err != nil {
+		return err
+	}
+	if !found {
+		verr.Add("CategoryID", api.ViolationUnknown, "category %d does not exist", event.CategoryID)
+	}
+
+	channel := model.Channel{}
+	err = db.Preload("Network").Preload("Network.Country").Preload("Network.Timezone").
+		Limit(1).Find(&channel, "channel_id = ?", event.ChannelID).Error
+	if err != nil {
+		return err
+	}
+	if channel.ChannelID == 0 {
+		verr.Add("ChannelID", api.ViolationUnknown, "channel %d does not exist", event.ChannelID)
+	} else {
+		if ordered && !onAir(channel, event.StartTime, event.EndTime) {
+			verr.Add("StartTime", api.ViolationOffAir, "event is outside the broadcast window %s to %s of %s",
+				channel.BroadcastStartTime.Format("15:04"), channel.BroadcastFinishTime.Format("15:04"), channel.Description)
+		}
+
+		title, short, extended := eit.TextOverflow(event, channel.Network.Country.CountryCode)
+		if title {
+			verr.Add("Title", api.ViolationTooLong, "title does not fit the EIT short event descriptor")
+		}
+		if short {
+			verr.Add("ShortDescription", api.ViolationTooLong, "title and short description together do not fit the EIT short event descriptor")
+		}
+		if extended {
+			verr.Add("ExtendedDescription", api.ViolationTooLong, "extended description does not fit the EIT extended event descriptors")
+		}
+
+		if ordered {
+			overlapping, err := FindOverlapping(db, event.ChannelID, event.StartTime, event.EndTime, event.EventID)
+			if err != nil {
+				return err
+			}
+			for _, other := range overlapping {
+				verr.Violations = append(verr.Violations, api.Violation{
+					Field:   "StartTime",
+					Code:    api.ViolationOverlap,
+					Message: fmt.Sprintf("event overlaps %q from %s to %s", other.Title, other.StartTime.Format(time.RFC3339), other.EndTime.Format(time.RFC3339)),
+					EventID: other.EventID,
+				})
+			}
+		}
+	}
+
+	return verr.Err()
+}
+
+// onAir reports whether [start, end) lies within one broadcast window of a channel.
+func onAir(channel model.Channel, start, end time.Time) bool {
+	windows := BroadcastWindows(channel, start, end)
+	return len(windows) == 1 && windows[0].Start.Equal(start) && windows[0].End.Equal(end)
+}
+
+// exists reports whether the table of model has a row with column id.
+func exists(db *gorm.DB, model interface{}, column string, id uint) (bool, error) {
+	var count int64
+	err := db.Model(model).Where(column+" = ?", id).Count(&count).Error
+	return count > 0, err
+}
@@ -0,0 +1,270 @@
+// Materialisation of schedule rules into events
+package schedule
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+	"time"
+
+	"epg/src/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TemplateData is what the title and description templates of a rule see,
+// e.g. "Episode {{.Episode}}" or "{{.Start.Format \"Monday 2 January\"}}".
+type TemplateData struct {
+	// Title is the expanded title, empty while the title is expanded.
+	Title string
+	// Start is the start of the occurrence in the network's timezone.
+	Start time.Time
+	// Episode counts the occurrences of the rule from 1.
+	Episode int
+}
+
+// Occurrence is one expansion of a rule.
+type Occurrence struct {
+	Start   time.Time
+	End     time.Time
+	Episode int
+}
+
+// Rule is a schedule rule ready for expansion.
+type Rule struct {
+	model.ScheduleRule
+	Recurrence         Recurrence
+	Location           *time.Location
+	hour, minute       int
+	title, description *template.Template
+}
+
+// Compile parses the recurrence, start time and templates of a rule whose
+// channel's network and timezone are loaded.
+func Compile(rule model.ScheduleRule) (*Rule, error) {
+	recurrence, err := ParseRecurrence(rule.Recurrence)
+	if err != nil {
+		return nil, err
+	}
+	start, err := time.Parse("15:04", rule.StartTime)
+	if err != nil {
+		return nil, fmt.Errorf("start time %q is not HH:MM", rule.StartTime)
+	}
+	if rule.DurationMinutes == 0 {
+		return nil, fmt.Errorf("duration must be at least one minute")
+	}
+	if rule.ValidUntil != nil && rule.ValidUntil.Before(rule.ValidFrom) {
+		return nil, fmt.Errorf("validUntil is before validFrom")
+	}
+	title, err := template.New("title").Parse(rule.Title)
+	if err != nil {
+		return nil, fmt.Errorf("title: %w", err)
+	}
+	description, err := template.New("description").Parse(optionalValue(rule.DescriptionTemplate))
+	if err != nil {
+		return nil, fmt.Errorf("description: %w", err)
+	}
+	return &Rule{
+		ScheduleRule: rule,
+		Recurrence:   recurrence,
+		Location:     rule.Channel.Network.Timezone.Location(),
+		hour:         start.Hour(),
+		minute:       start.Minute(),
+		title:        title,
+		description:  description,
+	}, nil
+}
+
+// Occurrences returns the occurrences of the rule starting in [from, to),
+// numbered from the rule's first valid date.
+func (r *Rule) Occurrences(from, to time.Time) []Occurrence {
+	first := r.ValidFrom.In(r.Location)
+	end := to.In(r.Location)
+	if r.ValidUntil != nil && r.ValidUntil.Before(end) {
+		end = r.ValidUntil.In(r.Location)
+	}
+	duration := time.Duration(r.DurationMinutes) * time.Minute
+
+	var occurrences []Occurrence
+	episode := 0
+	for date := startOfDay(first); !date.After(end); date = date.AddDate(0, 0, 1) {
+		if !r.Recurrence.On(date, first) {
+			continue
+		}
+		// time.Date moves a start in a DST gap forward by the gap.
+		start := time.Date(date.Year(), date.Month(), date.Day(), r.hour, r.minute, 0, 0, r.Location)
+		if start.Before(r.ValidFrom) {
+			continue
+		}
+		if r.ValidUntil != nil && start.After(*r.ValidUntil) {
+			break
+		}
+		episode++
+		if start.Before(from) || !start.Before(to) {
+			continue
+		}
+		occurrences = append(occurrences, Occurrence{Start: start, End: start.Add(duration), Episode: episode})
+	}
+	return occurrences
+}
+
+// Event returns the event of an occurrence.
+func (r *Rule) Event(o Occurrence) (*model.Event, error) {
+	data := TemplateData{Start: o.Start, Episode: o.Episode}
+	title, err := execute(r.title, data)
+	if err != nil {
+		return nil, err
+	}
+	data.Title = title
+	description, err := execute(r.description, data)
+	if err != nil {
+		return nil, err
+	}
+	ruleID := r.ScheduleRuleID
+	event := &model.Event{
+		ChannelID:      r.ChannelID,
+		StartTime:      o.Start.UTC(),
+		EndTime:        o.End.UTC(),
+		Title:          title,
+		SeriesCRID:     r.SeriesCRID,
+		GenreID:        r.GenreID,
+		CategoryID:     r.CategoryID,
+		ScheduleRuleID: &ruleID,
+	}
+	if description != "" {
+		event.ShortDescription = &description
+	}
+	return event, nil
+}
+
+// Result reports what a materialisation did.
+type Result struct {
+	Created int `json:"created"`
+	// Existing counts occurrences already materialised, including ones whose
+	// event has since been deleted.
+	Existing int `json:"existing"`
+	// Conflicts counts occurrences left out because they overlap another event.
+	Conflicts int `json:"conflicts"`
+}
+
+// Materializer expands the schedule rules into events.
+type Materializer struct {
+	db *gorm.DB
+}
+
+// NewMaterializer ...
+func NewMaterializer(db *gorm.DB) *Materializer {
+	return &Materializer{db: db}
+}
+
+// Materialize creates the events of every enabled rule starting in [from, to),
+// only of the rules of channelIDs when given. Occurrences overlapping an event
+// of the channel are skipped, so it can run repeatedly over a rolling window.
+func (m *Materializer) Materialize(from, to time.Time, channelIDs ...uint) (*Result, error) {
+	rules := []model.ScheduleRule{}
+	query := m.db.Preload("Channel").Preload("Channel.Network").Preload("Channel.Network.Timezone").
+		Where("disabled = ?", false)
+	if len(channelIDs) > 0 {
+		query = query.Where("channel_id IN ?", channelIDs)
+	}
+	err := query.Order("schedule_rule_id").Find(&rules).Error
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{}
+	for _, rule := range rules {
+		compiled, err := Compile(rule)
+		if err != nil {
+			return nil, fmt.Errorf("schedule rule %d: %w", rule.ScheduleRuleID, err)
+		}
+		if err := m.materializeRule(compiled, from, to, result); err != nil {
+			return nil, fmt.Errorf("schedule rule %d: %w", rule.ScheduleRuleID, err)
+		}
+	}
+	return result, nil
+}
+
+// materializeRule creates the events of one rule in a transaction.
+func (m *Materializer) materializeRule(rule *Rule, from, to time.Time, result *Result) error {
+	return m.db.Transaction(func(tx *gorm.DB) error {
+		for _, o := range rule.Occurrences(from, to) {
+			// Events are stored in UTC and compared as text.
+			start, end := o.Start.UTC(), o.End.UTC()
+
+			var count int64
+			err := tx.Unscoped().Model(&model.Event{}).
+				Where("schedule_rule_id = ? AND start_time = ?", rule.ScheduleRuleID, start).
+				Count(&count).Error
+			if err != nil {
+				return err
+			}
+			if count > 0 {
+				result.Existing++
+				continue
+			}
+			overlapping, err := FindOverlapping(tx, rule.ChannelID, start, end, 0)
+			if err != nil {
+				return err
+			}
+			if len(overlapping) > 0 {
+				result.Conflicts++
+				continue
+			}
+
+			event, err := rule.Event(o)
+			if err != nil {
+				return err
+			}
+			if err := tx.Omit(clause.Associations).Create(event).Error; err != nil {
+				return err
+			}
+			if rule.RatingValueID != nil {
+				rating := &model.EventRating{EventID: event.EventID, RatingValueID: *rule.RatingValueID}
+				if err := tx.Omit(clause.Associations).Create(rating).Error; err != nil {
+					return err
+				}
+			}
+			result.Created++
+		}
+		return nil
+	})
+}
+
+// Clear deletes the events of a rule starting at or after from, so a changed
+// rule is materialised afresh.
+func (m *Materializer) Clear(ruleID uint, from time.Time) (int64, error) {
+	var deleted int64
+	err := m.db.Transaction(func(tx *gorm.DB) error {
+		events := tx.Unscoped().Model(&model.Event{}).Select("event_id").
+			Where("schedule_rule_id = ? AND start_time >= ?", ruleID, from.UTC())
+		if err := tx.Where("event_id IN (?)", events).Delete(&model.EventRating{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("event_id IN (?)", events).Delete(&model.EventGenre{}).Error; err != nil {
+			return err
+		}
+		result := tx.Unscoped().Where("schedule_rule_id = ? AND start_time >= ?", ruleID, from.UTC()).Delete(&model.Event{})
+		deleted = result.RowsAffected
+		return result.Error
+	})
+	return deleted, err
+}
+
+// execute runs a template into a string.
+func execute(t *template.Template, data TemplateData) (string, error) {
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// optionalValue returns the value of an optional text, or empty.
+func optionalValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
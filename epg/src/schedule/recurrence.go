@@ -0,0 +1,198 @@
+// Recurrence rules of schedule rules, a subset of RFC 5545 RRULE
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frequencies of a recurrence.
+const (
+	Daily   = "DAILY"
+	Weekly  = "WEEKLY"
+	Monthly = "MONTHLY"
+)
+
+// weekdays maps the RRULE day codes to weekdays.
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// shorthands are the recurrences accepted by name.
+var shorthands = map[string]string{
+	"DAILY":    "FREQ=DAILY",
+	"WEEKDAYS": "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR",
+	"WEEKENDS": "FREQ=WEEKLY;BYDAY=SA,SU",
+	"WEEKLY":   "FREQ=WEEKLY",
+	"MONTHLY":  "FREQ=MONTHLY",
+}
+
+// Day is a BYDAY entry. Nth selects the nth weekday of the month, counted
+// from the end when negative; zero selects every one.
+type Day struct {
+	Weekday time.Weekday
+	Nth     int
+}
+
+// Recurrence is a parsed recurrence rule.
+type Recurrence struct {
+	Freq     string
+	Interval int
+	ByDay    []Day
+}
+
+// ParseRecurrence parses an RRULE such as "FREQ=DAILY", "FREQ=WEEKLY;BYDAY=MO,WE",
+// "FREQ=WEEKLY;INTERVAL=2;BYDAY=SA" or "FREQ=MONTHLY;BYDAY=2TU", or one of the
+// shorthands DAILY, WEEKDAYS, WEEKENDS, WEEKLY and MONTHLY. Weekly rules
+// without BYDAY fall on the weekday and monthly ones on the day of the month
+// of the rule's first date. The end of a rule is its ValidUntil, so COUNT and
+// UNTIL are not accepted.
+func ParseRecurrence(s string) (Recurrence, error) {
+	s = strings.ToUpper(strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")))
+	if full, ok := shorthands[s]; ok {
+		s = full
+	}
+
+	r := Recurrence{Interval: 1}
+	for _, part := range strings.Split(s, ";") {
+		name, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return Recurrence{}, fmt.Errorf("recurrence %q: bad part %q", s, part)
+		}
+		switch name {
+		case "FREQ":
+			if value != Daily && value != Weekly && value != Monthly {
+				return Recurrence{}, fmt.Errorf("recurrence %q: unsupported frequency %q", s, value)
+			}
+			r.Freq = value
+		case "INTERVAL":
+			interval, err := strconv.Atoi(value)
+			if err != nil || interval < 1 {
+				return Recurrence{}, fmt.Errorf("recurrence %q: bad interval %q", s, value)
+			}
+			r.Interval = interval
+		case "BYDAY":
+			for _, code := range strings.Split(value, ",") {
+				day, err := parseDay(code)
+				if err != nil {
+					return Recurrence{}, fmt.Errorf("recurrence %q: %w", s, err)
+				}
+				r.ByDay = append(r.ByDay, day)
+			}
+		default:
+			return Recurrence{}, fmt.Errorf("recurrence %q: unsupported part %q", s, name)
+		}
+	}
+	if r.Freq == "" {
+		return Recurrence{}, fmt.Errorf("recurrence %q: FREQ is missing", s)
+	}
+	for _, day := range r.ByDay {
+		if day.Nth != 0 && r.Freq != Monthly {
+			return Recurrence{}, fmt.Errorf("recurrence %q: numbered BYDAY needs FREQ=MONTHLY", s)
+		}
+	}
+	return r, nil
+}
+
+// parseDay parses a BYDAY entry, "MO" or a numbered "2TU" or "-1FR".
+func parseDay(code string) (Day, error) {
+	code = strings.TrimSpace(code)
+	if len(code) < 2 {
+		return Day{}, fmt.Errorf("bad day %q", code)
+	}
+	weekday, ok := weekdays[code[len(code)-2:]]
+	if !ok {
+		return Day{}, fmt.Errorf("bad day %q", code)
+	}
+	day := Day{Weekday: weekday}
+	if prefix := code[:len(code)-2]; prefix != "" {
+		nth, err := strconv.Atoi(prefix)
+		if err != nil || nth == 0 || nth < -5 || nth > 5 {
+			return Day{}, fmt.Errorf("bad day %q", code)
+		}
+		day.Nth = nth
+	}
+	return day, nil
+}
+
+// On reports whether the recurrence falls on date, for a rule first
+// valid on first. Only the calendar dates of both are used.
+func (r Recurrence) On(date, first time.Time) bool {
+	days := civilDays(date) - civilDays(first)
+	if days < 0 {
+		return false
+	}
+
+	switch r.Freq {
+	case Daily:
+		return days%int64(r.Interval) == 0 && r.onWeekday(date)
+	case Weekly:
+		// Weeks start on Monday as in RRULE's default WKST.
+		weeks := (civilDays(date) - mondayOffset(date) - civilDays(first) + mondayOffset(first)) / 7
+		if weeks%int64(r.Interval) != 0 {
+			return false
+		}
+		if len(r.ByDay) == 0 {
+			return date.Weekday() == first.Weekday()
+		}
+		return r.onWeekday(date)
+	case Monthly:
+		months := (date.Year()-first.Year())*12 + int(date.Month()-first.Month())
+		if months%r.Interval != 0 {
+			return false
+		}
+		if len(r.ByDay) == 0 {
+			return date.Day() == first.Day()
+		}
+		for _, day := range r.ByDay {
+			if day.Weekday == date.Weekday() && nthMatches(day.Nth, date) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// onWeekday reports whether date is one of the BYDAY weekdays, any day when
+// there are none.
+func (r Recurrence) onWeekday(date time.Time) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	for _, day := range r.ByDay {
+		if day.Weekday == date.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+// nthMatches reports whether date is the nth of its weekday in its month.
+func nthMatches(nth int, date time.Time) bool {
+	switch {
+	case nth > 0:
+		return (date.Day()-1)/7+1 == nth
+	case nth < 0:
+		last := time.Date(date.Year(), date.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+		return (last-date.Day())/7+1 == -nth
+	}
+	return true
+}
+
+// civilDays returns the number of days from the epoch to the calendar date of t.
+func civilDays(t time.Time) int64 {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC).Unix() / 86400
+}
+
+// mondayOffset returns the days since the Monday starting t's week.
+func mondayOffset(t time.Time) int64 {
+	return int64((t.Weekday() + 6) % 7)
+}
@@ -0,0 +1,75 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecurrenceOn(t *testing.T) {
+	date := func(day int) time.Time { return time.Date(2026, 10, day, 0, 0, 0, 0, time.UTC) }
+	tests := []struct {
+		rule  string
+		first time.Time
+		date  time.Time
+		want  bool
+	}{
+		{"DAILY", date(1), date(1), true},
+		{"DAILY", date(1), date(18), true},
+		{"DAILY", date(18), date(17), false},
+		{"FREQ=DAILY;INTERVAL=2", date(1), date(3), true},
+		{"FREQ=DAILY;INTERVAL=2", date(1), date(2), false},
+		{"FREQ=DAILY;BYDAY=SA,SU", date(1), date(18), true},
+		{"FREQ=DAILY;BYDAY=SA,SU", date(1), date(16), false},
+		{"WEEKDAYS", date(1), date(16), true},
+		{"WEEKDAYS", date(1), date(17), false},
+		{"WEEKENDS", date(1), date(18), true},
+		// Without BYDAY a weekly rule falls on the weekday of its first date.
+		{"WEEKLY", date(1), date(8), true},
+		{"WEEKLY", date(1), date(9), false},
+		// Weeks start on Monday, 1 October 2026 is a Thursday.
+		{"FREQ=WEEKLY;INTERVAL=2;BYDAY=SA", date(1), date(3), true},
+		{"FREQ=WEEKLY;INTERVAL=2;BYDAY=SA", date(1), date(10), false},
+		{"FREQ=WEEKLY;INTERVAL=2;BYDAY=SA", date(1), date(17), true},
+		{"FREQ=WEEKLY;INTERVAL=2;BYDAY=MO", date(1), date(12), true},
+		{"FREQ=WEEKLY;INTERVAL=2;BYDAY=MO", date(1), date(5), false},
+		{"MONTHLY", time.Date(2026, 9, 15, 0, 0, 0, 0, time.UTC), date(15), true},
+		{"MONTHLY", time.Date(2026, 9, 15, 0, 0, 0, 0, time.UTC), date(16), false},
+		{"FREQ=MONTHLY;INTERVAL=2", time.Date(2026, 9, 15, 0, 0, 0, 0, time.UTC), date(15), false},
+		{"FREQ=MONTHLY;BYDAY=2TU", date(1), date(13), true},
+		{"FREQ=MONTHLY;BYDAY=2TU", date(1), date(6), false},
+		{"FREQ=MONTHLY;BYDAY=-1FR", date(1), date(30), true},
+		{"FREQ=MONTHLY;BYDAY=-1FR", date(1), date(23), false},
+		// Only the calendar dates count, not the times or zones.
+		{"FREQ=DAILY;INTERVAL=2", time.Date(2026, 10, 1, 23, 0, 0, 0, time.UTC), time.Date(2026, 10, 3, 1, 0, 0, 0, time.FixedZone("AEDT", 11*3600)), true},
+	}
+	for _, tt := range tests {
+		r, err := ParseRecurrence(tt.rule)
+		if err != nil {
+			t.Errorf("ParseRecurrence(%q) error = %v", tt.rule, err)
+			continue
+		}
+		if got := r.On(tt.date, tt.first); got != tt.want {
+			t.Errorf("%s from %s: On(%s) = %t, want %t", tt.rule, tt.first.Format(time.DateOnly), tt.date.Format(time.DateOnly), got, tt.want)
+		}
+	}
+}
+
+func TestParseRecurrenceErrors(t *testing.T) {
+	for _, rule := range []string{
+		"",
+		"YEARLY",
+		"FREQ=YEARLY",
+		"INTERVAL=2",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=DAILY;COUNT=3",
+		"FREQ=DAILY;UNTIL=20261231",
+		"FREQ=WEEKLY;BYDAY=XX",
+		"FREQ=WEEKLY;BYDAY=2TU",
+		"FREQ=MONTHLY;BYDAY=6MO",
+		"FREQ=MONTHLY;BYDAY=0MO",
+	} {
+		if r, err := ParseRecurrence(rule); err == nil {
+			t.Errorf("ParseRecurrence(%q) = %+v, want an error", rule, r)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+// Background upkeep of the events table
+package schedule
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"epg/src/model"
+
+	"gorm.io/gorm"
+)
+
+// Scheduler defaults, used for zero settings.
+const (
+	DefaultHorizonDays   = 7
+	DefaultInterval      = time.Hour
+	DefaultRetentionDays = 7
+)
+
+// Scheduler keeps every network's events materialised from its schedule
+// rules up to a horizon and purges the events that have aged out.
+type Scheduler struct {
+	db *gorm.DB
+	// HorizonDays is how many days past today's midnight events are kept
+	// materialised, midnight being in the network's timezone.
+	HorizonDays int
+	// Interval is the time between runs.
+	Interval time.Duration
+	// RetentionDays is how many days before today's midnight ended events
+	// are kept. Older ones are soft deleted, and soft deleted events older
+	// still are removed for good.
+	RetentionDays int
+}
+
+// NewScheduler ...
+func NewScheduler(db *gorm.DB, horizonDays int, interval time.Duration, retentionDays int) *Scheduler {
+	if horizonDays <= 0 {
+		horizonDays = DefaultHorizonDays
+	}
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	if retentionDays <= 0 {
+		retentionDays = DefaultRetentionDays
+	}
+	return &Scheduler{
+		db:            db,
+		HorizonDays:   horizonDays,
+		Interval:      interval,
+		RetentionDays: retentionDays,
+	}
+}
+
+// Run runs the scheduler now and then every Interval until ctx is done.
+// A failed run is logged and retried on the next one.
+func (s *Scheduler) Run(ctx context.Context) {
+	log.Printf("Scheduler: %d day horizon, %d day retention, every %s", s.HorizonDays, s.RetentionDays, s.Interval)
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+	for {
+		if err := s.RunOnce(ctx, time.Now()); err != nil {
+			log.Printf("Scheduler: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			log.Println("Scheduler: stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce materialises and purges the events of each network as at now.
+func (s *Scheduler) RunOnce(ctx context.Context, now time.Time) error {
+	networks := []model.Network{}
+	if err := s.db.WithContext(ctx).Preload("Timezone").Order("network_id").Find(&networks).Error; err != nil {
+		return err
+	}
+	for _, network := range networks {
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err := s.runNetwork(ctx, network, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runNetwork looks after the events of one network's channels.
+func (s *Scheduler) runNetwork(ctx context.Context, network model.Network, now time.Time) error {
+	db := s.db.WithContext(ctx)
+	channels := []model.Channel{}
+	if err := db.Where("network_id = ?", network.NetworkID).Order("channel_id").Find(&channels).Error; err != nil {
+		return err
+	}
+	if len(channels) == 0 {
+		return nil
+	}
+	channelIDs := make([]uint, len(channels))
+	for i, channel := range channels {
+		channelIDs[i] = channel.ChannelID
+	}
+
+	midnight := startOfDay(now.In(network.Timezone.Location()))
+	horizon := midnight.AddDate(0, 0, s.HorizonDays)
+	cutoff := midnight.AddDate(0, 0, -s.RetentionDays)
+
+	result, err := NewMaterializer(db).Materialize(now, horizon, channelIDs...)
+	if err != nil {
+		return err
+	}
+	expired, removed, err := purge(db, channelIDs, cutoff)
+	if err != nil {
+		return err
+	}
+	log.Printf("Scheduler: %s: %d events created up to %s (%d conflicts), %d expired and %d removed before %s",
+		network.Description, result.Created, horizon.Format(time.RFC3339), result.Conflicts,
+		expired, removed, cutoff.Format(time.RFC3339))
+
+	// Channels without rules covering the horizon are only reported, their
+	// events come from imports or by hand.
+	for _, channel := range channels {
+		var last model.Event
+		err := db.Where("channel_id = ?", channel.ChannelID).Order("end_time DESC").Limit(1).Find(&last).Error
+		if err != nil {
+			return err
+		}
+		if last.EventID == 0 {
+			log.Printf("Scheduler: %s has no events", channel.Description)
+		} else if last.EndTime.Before(horizon) {
+			log.Printf("Scheduler: %s has events only up to %s", channel.Description, last.EndTime.In(horizon.Location()).Format(time.RFC3339))
+		}
+	}
+	return nil
+}
+
+// purge soft deletes the events of channelIDs that ended before cutoff and
+// removes for good those that were already soft deleted before it, with
+// their ratings and genres.
+func purge(db *gorm.DB, channelIDs []uint, cutoff time.Time) (expired, removed int64, err error) {
+	// Events are stored in UTC and compared as text.
+	cutoff = cutoff.UTC()
+	err = db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("channel_id IN ? AND end_time < ?", channelIDs, cutoff).Delete(&model.Event{})
+		if result.Error != nil {
+			return result.Error
+		}
+		expired = result.RowsAffected
+
+		stale := tx.Unscoped().Model(&model.Event{}).Select("event_id").
+			Where("channel_id IN ? AND end_time < ? AND deleted_at IS NOT NULL AND deleted_at < ?", channelIDs, cutoff, cutoff)
+		if err := tx.Where("event_id IN (?)", stale).Delete(&model.EventRating{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("event_id IN (?)", stale).Delete(&model.EventGenre{}).Error; err != nil {
+			return err
+		}
+		result = tx.Unscoped().
+			Where("channel_id IN ? AND end_time < ? AND deleted_at IS NOT NULL AND deleted_at < ?", channelIDs, cutoff, cutoff).
+			Delete(&model.Event{})
+		removed = result.RowsAffected
+		return result.Error
+	})
+	return expired, removed, err
+}
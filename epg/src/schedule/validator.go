@@ -0,0 +1,218 @@
+// Overlap and gap checks of the events of a channel
+package schedule
+
+import (
+	"fmt"
+	"time"
+
+	"epg/src/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DefaultFillerTitle is the title of filler events when none is configured.
+const DefaultFillerTitle = "Repeater ident / Test card"
+
+// Overlap is a stretch of time two events of a channel both claim.
+type Overlap struct {
+	EventID      uint      `json:"eventID"`
+	Title        string    `json:"title"`
+	OtherEventID uint      `json:"otherEventID"`
+	OtherTitle   string    `json:"otherTitle"`
+	Start        time.Time `json:"start"`
+	End          time.Time `json:"end"`
+}
+
+// Gap is a stretch of a channel's broadcast window without events.
+type Gap struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// ChannelReport lists the overlaps and gaps of a channel's events.
+type ChannelReport struct {
+	ChannelID uint      `json:"channelID"`
+	Channel   string    `json:"channel"`
+	Overlaps  []Overlap `json:"overlaps"`
+	Gaps      []Gap     `json:"gaps"`
+	// Filled lists the filler events created by Fill.
+	Filled []model.Event `json:"filled,omitempty"`
+}
+
+// Filler describes the events Fill puts in gaps.
+type Filler struct {
+	Title       string
+	Description string
+	GenreID     uint
+	CategoryID  uint
+	// MinGap is the shortest gap filled, shorter ones are left alone.
+	MinGap time.Duration
+}
+
+// Validator checks the events of channels against each other and against
+// the channels' broadcast windows.
+type Validator struct {
+	db *gorm.DB
+}
+
+// NewValidator ...
+func NewValidator(db *gorm.DB) *Validator {
+	return &Validator{db: db}
+}
+
+// Check reports the overlaps and the gaps of the events in [from, to) of
+// channelID, or of every channel when it is zero. Gaps are only looked for
+// in the daily broadcast window of the channel, see BroadcastWindows.
+func (v *Validator) Check(channelID uint, from, to time.Time) ([]ChannelReport, error) {
+	channels := []model.Channel{}
+	query := v.db.Preload("Network").Preload("Network.Timezone").Order("channel_id")
+	if channelID != 0 {
+		query = query.Where("channel_id = ?", channelID)
+	}
+	if err := query.Find(&channels).Error; err != nil {
+		return nil, err
+	}
+	if channelID != 0 && len(channels) == 0 {
+		return nil, fmt.Errorf("channel %d: %w", channelID, gorm.ErrRecordNotFound)
+	}
+
+	reports := []ChannelReport{}
+	for _, channel := range channels {
+		events, err := FindOverlapping(v.db, channel.ChannelID, from, to, 0)
+		if err != nil {
+			return nil, err
+		}
+		report := ChannelReport{
+			ChannelID: channel.ChannelID,
+			Channel:   channel.Description,
+			Overlaps:  Overlaps(events),
+			Gaps:      Gaps(events, BroadcastWindows(channel, from, to)),
+		}
+		// Report the times in the network's timezone.
+		location := channel.Network.Timezone.Location()
+		for i := range report.Overlaps {
+			report.Overlaps[i].Start = report.Overlaps[i].Start.In(location)
+			report.Overlaps[i].End = report.Overlaps[i].End.In(location)
+		}
+		for i := range report.Gaps {
+			report.Gaps[i].Start = report.Gaps[i].Start.In(location)
+			report.Gaps[i].End = report.Gaps[i].End.In(location)
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+// Fill checks like Check and creates a filler event in each gap of at least
+// filler.MinGap.
+func (v *Validator) Fill(channelID uint, from, to time.Time, filler Filler) ([]ChannelReport, error) {
+	if filler.Title == "" {
+		filler.Title = DefaultFillerTitle
+	}
+	if err := v.db.First(&model.Genre{}, filler.GenreID).Error; err != nil {
+		return nil, fmt.Errorf("filler genre %d: %w", filler.GenreID, err)
+	}
+	if err := v.db.First(&model.Category{}, filler.CategoryID).Error; err != nil {
+		return nil, fmt.Errorf("filler category %d: %w", filler.CategoryID, err)
+	}
+
+	reports, err := v.Check(channelID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	err = v.db.Transaction(func(tx *gorm.DB) error {
+		for i := range reports {
+			report := &reports[i]
+			for _, gap := range report.Gaps {
+				if gap.End.Sub(gap.Start) < filler.MinGap {
+					continue
+				}
+				event := model.Event{
+					ChannelID:  report.ChannelID,
+					StartTime:  gap.Start.UTC(),
+					EndTime:    gap.End.UTC(),
+					Title:      filler.Title,
+					GenreID:    filler.GenreID,
+					CategoryID: filler.CategoryID,
+				}
+				if filler.Description != "" {
+					description := filler.Description
+					event.ShortDescription = &description
+				}
+				if err := tx.Omit(clause.Associations).Create(&event).Error; err != nil {
+					return err
+				}
+				report.Filled = append(report.Filled, event)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reports, nil
+}
+
+// Overlaps returns where events, ordered by start time, overlap. Each event
+// is compared with the latest ending event before it.
+func Overlaps(events []model.Event) []Overlap {
+	overlaps := []Overlap{}
+	var last *model.Event
+	for i := range events {
+		event := &events[i]
+		if last != nil && event.StartTime.Before(last.EndTime) {
+			end := event.EndTime
+			if last.EndTime.Before(end) {
+				end = last.EndTime
+			}
+			overlaps = append(overlaps, Overlap{
+				EventID:      last.EventID,
+				Title:        last.Title,
+				OtherEventID: event.EventID,
+				OtherTitle:   event.Title,
+				Start:        event.StartTime,
+				End:          end,
+			})
+		}
+		if last == nil || event.EndTime.After(last.EndTime) {
+			last = event
+		}
+	}
+	return overlaps
+}
+
+// Gaps returns the parts of windows, ordered and not overlapping, that events
+// ordered by start time leave uncovered.
+func Gaps(events []model.Event, windows []Window) []Gap {
+	gaps := []Gap{}
+	next := 0
+	for _, window := range windows {
+		cursor := window.Start
+		for next < len(events) && !events[next].EndTime.After(window.Start) {
+			next++
+		}
+		for i := next; i < len(events) && events[i].StartTime.Before(window.End); i++ {
+			if events[i].StartTime.After(cursor) {
+				gaps = append(gaps, Gap{Start: cursor, End: events[i].StartTime})
+			}
+			if events[i].EndTime.After(cursor) {
+				cursor = events[i].EndTime
+			}
+		}
+		if cursor.Before(window.End) {
+			gaps = append(gaps, Gap{Start: cursor, End: window.End})
+		}
+	}
+	return gaps
+}
+
+// FindOverlapping returns the events of a channel overlapping [start, end),
+// leaving out the event exceptID.
+func FindOverlapping(db *gorm.DB, channelID uint, start, end time.Time, exceptID uint) ([]model.Event, error) {
+	events := []model.Event{}
+	err := db.Scopes(model.Overlapping(start, end)).
+		Where("channel_id = ? AND event_id <> ?", channelID, exceptID).
+		Order("start_time, event_id").Find(&events).Error
+	return events, err
+}
@@ -0,0 +1,98 @@
+package schedule
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"epg/src/model"
+)
+
+// event returns an event of id from start to end.
+func event(id uint, start, end time.Time) model.Event {
+	return model.Event{EventID: id, StartTime: start, EndTime: end}
+}
+
+// equalGaps reports whether two lists of gaps cover the same instants.
+func equalGaps(a, b []Gap) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Start.Equal(b[i].Start) || !a[i].End.Equal(b[i].End) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestOverlaps(t *testing.T) {
+	tests := []struct {
+		name   string
+		events []model.Event
+		want   []Overlap
+	}{
+		{"none", nil, []Overlap{}},
+		{"back to back", []model.Event{event(1, at(18, 6, 0), at(18, 7, 0)), event(2, at(18, 7, 0), at(18, 8, 0))}, []Overlap{}},
+		{
+			"partial",
+			[]model.Event{event(1, at(18, 6, 0), at(18, 7, 0)), event(2, at(18, 6, 30), at(18, 8, 0))},
+			[]Overlap{{EventID: 1, OtherEventID: 2, Start: at(18, 6, 30), End: at(18, 7, 0)}},
+		},
+		{
+			"contained",
+			[]model.Event{event(1, at(18, 6, 0), at(18, 9, 0)), event(2, at(18, 7, 0), at(18, 8, 0)), event(3, at(18, 8, 30), at(18, 10, 0))},
+			[]Overlap{
+				{EventID: 1, OtherEventID: 2, Start: at(18, 7, 0), End: at(18, 8, 0)},
+				// Event 3 is compared with event 1, which ends last.
+				{EventID: 1, OtherEventID: 3, Start: at(18, 8, 30), End: at(18, 9, 0)},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Overlaps(tt.events); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Overlaps() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGaps(t *testing.T) {
+	windows := []Window{{Start: at(18, 6, 0), End: at(18, 12, 0)}, {Start: at(18, 18, 0), End: at(18, 23, 0)}}
+	uncovered := []Gap{{Start: at(18, 6, 0), End: at(18, 12, 0)}, {Start: at(18, 18, 0), End: at(18, 23, 0)}}
+	tests := []struct {
+		name   string
+		events []model.Event
+		want   []Gap
+	}{
+		{"no events", nil, uncovered},
+		{
+			"covered",
+			[]model.Event{event(1, at(18, 5, 0), at(18, 12, 0)), event(2, at(18, 18, 0), at(18, 23, 30))},
+			[]Gap{},
+		},
+		{
+			"between and after",
+			[]model.Event{event(1, at(18, 6, 0), at(18, 7, 0)), event(2, at(18, 8, 0), at(18, 12, 0)), event(3, at(18, 18, 0), at(18, 20, 0))},
+			[]Gap{{Start: at(18, 7, 0), End: at(18, 8, 0)}, {Start: at(18, 20, 0), End: at(18, 23, 0)}},
+		},
+		{
+			"overlapping events",
+			[]model.Event{event(1, at(18, 6, 0), at(18, 10, 0)), event(2, at(18, 7, 0), at(18, 8, 0)), event(3, at(18, 11, 0), at(18, 19, 0))},
+			[]Gap{{Start: at(18, 10, 0), End: at(18, 11, 0)}, {Start: at(18, 19, 0), End: at(18, 23, 0)}},
+		},
+		{
+			"outside the windows",
+			[]model.Event{event(1, at(18, 1, 0), at(18, 2, 0)), event(2, at(18, 13, 0), at(18, 14, 0))},
+			uncovered,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Gaps(tt.events, windows); !equalGaps(got, tt.want) {
+				t.Errorf("Gaps() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+// Daily broadcast windows of channels
+package schedule
+
+import (
+	"time"
+
+	"epg/src/model"
+)
+
+// Window is a stretch of time a channel is on air.
+type Window struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// BroadcastWindows returns the times in [from, to) a channel is on air. Its
+// broadcast start and finish times are a daily window in the network's
+// timezone, running over midnight when the finish is before the start. Equal
+// times mean the channel is always on air.
+func BroadcastWindows(channel model.Channel, from, to time.Time) []Window {
+	if !from.Before(to) {
+		return nil
+	}
+	start, finish := channel.BroadcastStartTime, channel.BroadcastFinishTime
+	if clock(start) == clock(finish) {
+		return []Window{{Start: from, End: to}}
+	}
+
+	location := channel.Network.Timezone.Location()
+	windows := []Window{}
+	// A window opening the day before from may still be open at from.
+	day := startOfDay(from.In(location)).AddDate(0, 0, -1)
+	for ; day.Before(to); day = day.AddDate(0, 0, 1) {
+		open := atClock(day, start)
+		close := atClock(day, finish)
+		if !close.After(open) {
+			close = atClock(day.AddDate(0, 0, 1), finish)
+		}
+		if open.Before(from) {
+			open = from
+		}
+		if close.After(to) {
+			close = to
+		}
+		if open.Before(close) {
+			windows = append(windows, Window{Start: open, End: close})
+		}
+	}
+	return windows
+}
+
+// OnAir reports whether a channel is on air at t, within one of its
+// BroadcastWindows.
+func OnAir(channel model.Channel, t time.Time) bool {
+	return len(BroadcastWindows(channel, t, t.Add(time.Nanosecond))) > 0
+}
+
+// clock returns the time of day of t on its own clock.
+func clock(t time.Time) time.Duration {
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+}
+
+// atClock returns day at the time of day of t.
+func atClock(day, t time.Time) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), t.Hour(), t.Minute(), t.Second(), 0, day.Location())
+}
+
+// startOfDay returns midnight of t's date in its location.
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
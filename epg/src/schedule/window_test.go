@@ -0,0 +1,112 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"epg/src/model"
+)
+
+var sydney = func() *time.Location {
+	loc, err := time.LoadLocation("Australia/Sydney")
+	if err != nil {
+		panic(err)
+	}
+	return loc
+}()
+
+// at returns a time of October 2026 in Sydney.
+func at(day, hour, minute int) time.Time {
+	return time.Date(2026, 10, day, hour, minute, 0, 0, sydney)
+}
+
+// channel returns a channel of a Sydney network broadcasting daily from
+// start to finish, given as hours.
+func channel(start, finish int) model.Channel {
+	return model.Channel{
+		BroadcastStartTime:  time.Date(0, 1, 1, start, 0, 0, 0, time.UTC),
+		BroadcastFinishTime: time.Date(0, 1, 1, finish, 0, 0, 0, time.UTC),
+		Network:             model.Network{Timezone: model.Timezone{TimezoneName: "Australia/Sydney"}},
+	}
+}
+
+// equalWindows reports whether two lists of windows cover the same instants.
+func equalWindows(a, b []Window) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Start.Equal(b[i].Start) || !a[i].End.Equal(b[i].End) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestBroadcastWindows(t *testing.T) {
+	tests := []struct {
+		name     string
+		channel  model.Channel
+		from, to time.Time
+		want     []Window
+	}{
+		{"empty range", channel(6, 23), at(18, 12, 0), at(18, 12, 0), nil},
+		{"always on air", channel(0, 0), at(18, 0, 0), at(20, 0, 0), []Window{{Start: at(18, 0, 0), End: at(20, 0, 0)}}},
+		{
+			"daytime",
+			channel(6, 23), at(18, 0, 0), at(20, 0, 0),
+			[]Window{{Start: at(18, 6, 0), End: at(18, 23, 0)}, {Start: at(19, 6, 0), End: at(19, 23, 0)}},
+		},
+		{
+			"cut by the range",
+			channel(6, 23), at(18, 12, 0), at(19, 7, 0),
+			[]Window{{Start: at(18, 12, 0), End: at(18, 23, 0)}, {Start: at(19, 6, 0), End: at(19, 7, 0)}},
+		},
+		{
+			"over midnight",
+			channel(18, 2), at(18, 1, 0), at(19, 1, 0),
+			[]Window{{Start: at(18, 1, 0), End: at(18, 2, 0)}, {Start: at(18, 18, 0), End: at(19, 1, 0)}},
+		},
+		{
+			// Clocks go forward at 02:00 on 4 October 2026, the window lasts 3 hours.
+			"DST change",
+			channel(1, 5), at(4, 0, 0), at(5, 0, 0),
+			[]Window{{Start: at(4, 1, 0), End: at(4, 5, 0)}},
+		},
+		{
+			"range in UTC",
+			channel(6, 23), at(18, 0, 0).UTC(), at(19, 0, 0).UTC(),
+			[]Window{{Start: at(18, 6, 0), End: at(18, 23, 0)}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := BroadcastWindows(tt.channel, tt.from, tt.to); !equalWindows(got, tt.want) {
+				t.Errorf("BroadcastWindows() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOnAir(t *testing.T) {
+	tests := []struct {
+		name    string
+		channel model.Channel
+		at      time.Time
+		want    bool
+	}{
+		{"always on air", channel(0, 0), at(18, 3, 0), true},
+		{"opening", channel(6, 23), at(18, 6, 0), true},
+		{"before opening", channel(6, 23), at(18, 5, 59), false},
+		{"closing", channel(6, 23), at(18, 23, 0), false},
+		{"before closing", channel(6, 23), at(18, 22, 59), true},
+		{"after midnight", channel(18, 2), at(18, 1, 0), true},
+		{"after closing over midnight", channel(18, 2), at(18, 2, 0), false},
+		{"in UTC", channel(6, 23), at(18, 22, 0).UTC(), true},
+	}
+	for _, tt := range tests {
+		if got := OnAir(tt.channel, tt.at); got != tt.want {
+			t.Errorf("%s: OnAir(%v) = %t, want %t", tt.name, tt.at, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+// XMLTV export of the events table
+package xmltv
+
+import (
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"epg/src/dvb"
+	"epg/src/dvb/eit"
+	"epg/src/model"
+
+	"gorm.io/gorm"
+)
+
+// staticDir is where the web server serves /static/ from.
+const staticDir = "./static"
+
+// GeneratorName is written as the generator-info-name of exported documents.
+const GeneratorName = "epg"
+
+// Episode numbering systems of the CRIDs of exported programmes.
+const (
+	EpisodeSystemCRID       = "crid"
+	EpisodeSystemSeriesCRID = "series-crid"
+)
+
+// Exporter renders events as XMLTV programmes.
+type Exporter struct {
+	db *gorm.DB
+	// Channels maps XMLTV channel ids to channel IDs, the same mapping the
+	// importer uses. Channels missing from it get an id made from their
+	// description and network authority.
+	Channels map[string]uint
+	// BaseURL is prefixed to the icon paths, e.g. "http://epg.example.org".
+	BaseURL string
+}
+
+// Filter selects the events to export. A zero ChannelID selects all channels
+// and a zero To leaves the end open.
+type Filter struct {
+	ChannelID uint
+	From      time.Time
+	To        time.Time
+}
+
+// NewExporter ...
+func NewExporter(db *gorm.DB, channels map[string]uint, baseURL string) *Exporter {
+	return &Exporter{db: db, Channels: channels, BaseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+// Export returns the channels and the events overlapping the filter's window.
+func (ex *Exporter) Export(filter Filter) (*TV, error) {
+	channels := []model.Channel{}
+	query := ex.db.Preload("Network").Preload("Network.Timezone").Order("logical_channel_number, channel_id")
+	if filter.ChannelID != 0 {
+		query = query.Where("channel_id = ?", filter.ChannelID)
+	}
+	if err := query.Find(&channels).Error; err != nil {
+		return nil, err
+	}
+
+	ids := map[uint]string{}
+	for id, channelID := range ex.Channels {
+		// Prefer the smallest id when several map to one channel so the
+		// output is stable.
+		if current, ok := ids[channelID]; !ok || id < current {
+			ids[channelID] = id
+		}
+	}
+
+	tv := &TV{GeneratorInfoName: GeneratorName}
+	for _, channel := range channels {
+		id, ok := ids[channel.ChannelID]
+		if !ok {
+			id = ChannelID(channel)
+		}
+		tv.Channels = append(tv.Channels, ex.channel(id, channel))
+
+		events, err := model.FindChannelEvents(ex.db, channel.ChannelID, filter.From, filter.To)
+		if err != nil {
+			return nil, err
+		}
+		location := channel.Network.Timezone.Location()
+		authority := eit.DefaultAuthority(channel)
+		for _, event := range events {
+			tv.Programmes = append(tv.Programmes, ex.programme(id, event, location, authority))
+		}
+	}
+	return tv, nil
+}
+
+// nonIDChars are the runes replaced when making a channel id from a description.
+var nonIDChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// ChannelID returns the default XMLTV id of a channel, its description
+// qualified by the network's CRID authority in the style of RFC 2838,
+// e.g. "vk3rgl-hd1.vk3atl.org", or just the channel ID without an authority.
+func ChannelID(channel model.Channel) string {
+	name := strings.Trim(nonIDChars.ReplaceAllString(strings.ToLower(channel.Description), "-"), "-")
+	authority := eit.NetworkAuthority(channel.Network)
+	if name == "" || authority == "" {
+		return strconv.FormatUint(uint64(channel.ChannelID), 10)
+	}
+	return name + "." + authority
+}
+
+// channel returns the XMLTV channel of a channel.
+func (ex *Exporter) channel(id string, channel model.Channel) Channel {
+	c := Channel{ID: id, DisplayNames: []Text{{Value: channel.Description}}}
+	if channel.LogicalChannelNumber != 0 {
+		c.DisplayNames = append(c.DisplayNames, Text{Value: strconv.FormatUint(uint64(channel.LogicalChannelNumber), 10)})
+	}
+	if channel.LogoName != nil && *channel.LogoName != "" {
+		c.Icons = []Icon{{Src: ex.iconURL("img", "logos", *channel.LogoName)}}
+	}
+	return c
+}
+
+// programme returns the XMLTV programme of an event with times in location
+// and relative CRIDs resolved against authority.
+func (ex *Exporter) programme(channelID string, event model.Event, location *time.Location, authority string) Programme {
+	p := Programme{
+		Start:   FormatTime(event.StartTime.In(location)),
+		Stop:    FormatTime(event.EndTime.In(location)),
+		Channel: channelID,
+		Titles:  []Text{{Value: event.Title}},
+	}
+	if text := optionalValue(event.ShortDescription); text != "" {
+		p.SubTitles = []Text{{Value: text}}
+	}
+	if text := optionalValue(event.ExtendedDescription); text != "" {
+		p.Descs = []Text{{Value: text}}
+	}
+
+	seen := map[uint]bool{}
+	genres := []model.Genre{event.Genre}
+	for _, eg := range event.EventGenres {
+		genres = append(genres, eg.Genre)
+	}
+	for _, genre := range genres {
+		if genre.GenreID == 0 || seen[genre.GenreID] {
+			continue
+		}
+		seen[genre.GenreID] = true
+		p.Categories = append(p.Categories, Text{Lang: "en", Value: genre.Description})
+	}
+
+	// The episode is identified by its CRIDs, resolved against the default
+	// authority the EIT leaves out of relative ones.
+	if crid := absoluteCRID(event.ProgrammeCRID, authority); crid != "" {
+		p.EpisodeNums = append(p.EpisodeNums, EpisodeNum{System: EpisodeSystemCRID, Value: crid})
+	}
+	if crid := absoluteCRID(event.SeriesCRID, authority); crid != "" {
+		p.EpisodeNums = append(p.EpisodeNums, EpisodeNum{System: EpisodeSystemSeriesCRID, Value: crid})
+	}
+
+	for _, er := range event.EventRatings {
+		p.Ratings = append(p.Ratings, ex.rating(er.RatingValue))
+	}
+	return p
+}
+
+// rating returns the XMLTV rating of a rating value, with the icon under
+// static/img/ratings/<country>/ when there is one.
+func (ex *Exporter) rating(value model.RatingValue) Rating {
+	system := value.RatingSystem
+	r := Rating{System: initials(system.Description), Value: value.Value}
+	if r.System == "" {
+		r.System = system.Description
+	}
+	country := strings.ToLower(system.Country.CountryCode)
+	if country == "" {
+		return r
+	}
+	file := value.IconFile()
+	if _, err := os.Stat(path.Join(staticDir, "img", "ratings", country, file)); err == nil {
+		r.Icons = []Icon{{Src: ex.iconURL("img", "ratings", country, file)}}
+	}
+	return r
+}
+
+// iconURL returns the URL of a file served under /static/.
+func (ex *Exporter) iconURL(elem ...string) string {
+	for i, e := range elem {
+		elem[i] = url.PathEscape(e)
+	}
+	return ex.BaseURL + "/static/" + path.Join(elem...)
+}
+
+// absoluteCRID returns a CRID in its crid:// form, or empty when it is
+// malformed or relative without a default authority.
+func absoluteCRID(s *string, defaultAuthority string) string {
+	if optionalValue(s) == "" {
+		return ""
+	}
+	crid, err := dvb.ParseCRID(*s)
+	if err != nil {
+		return ""
+	}
+	if crid.Authority == "" {
+		if defaultAuthority == "" {
+			return ""
+		}
+		crid.Authority = defaultAuthority
+	}
+	return crid.String()
+}
+
+// optionalValue returns the value of an optional text, or empty.
+func optionalValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return strings.TrimSpace(*s)
+}
@@ -0,0 +1,404 @@
+// XMLTV import into the events table
+package xmltv
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"epg/src/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Importer stores XMLTV programmes as events of the channels they map to.
+type Importer struct {
+	db *gorm.DB
+	// Channels maps XMLTV channel ids to channel IDs. Ids missing from it are
+	// matched on a display name equal to a channel's description.
+	Channels map[string]uint
+	// Replace deletes the events an imported programme overlaps instead of
+	// reporting a conflict.
+	Replace bool
+}
+
+// Result reports what an import did.
+type Result struct {
+	Created int `json:"created"`
+	// Duplicates counts programmes already present with the same title and times.
+	Duplicates int        `json:"duplicates"`
+	Replaced   int        `json:"replaced"`
+	Conflicts  []Conflict `json:"conflicts"`
+	// Unmapped lists the XMLTV channel ids no channel matches.
+	Unmapped []string `json:"unmappedChannels"`
+	// Invalid lists the programmes skipped for bad or missing times.
+	Invalid []string `json:"invalid"`
+}
+
+// Conflict is a programme not imported because it overlaps an existing event.
+type Conflict struct {
+	ChannelID  uint      `json:"channelID"`
+	Title      string    `json:"title"`
+	Start      time.Time `json:"start"`
+	Stop       time.Time `json:"stop"`
+	EventID    uint      `json:"eventID"`
+	EventTitle string    `json:"eventTitle"`
+}
+
+// NewImporter ...
+func NewImporter(db *gorm.DB, channels map[string]uint) *Importer {
+	return &Importer{db: db, Channels: channels}
+}
+
+// importContext holds the lookup tables of one import.
+type importContext struct {
+	channels     map[uint]model.Channel
+	genres       []model.Genre
+	categories   []model.Category
+	ratingValues []model.RatingValue
+}
+
+// Import stores the programmes of tv in one transaction.
+func (im *Importer) Import(tv *TV) (*Result, error) {
+	result := &Result{}
+	err := im.db.Transaction(func(tx *gorm.DB) error {
+		ctx, err := loadContext(tx)
+		if err != nil {
+			return err
+		}
+		mapping := im.mapChannels(tv, ctx, result)
+
+		for _, p := range sortProgrammes(tv.Programmes) {
+			channelID, ok := mapping[p.Channel]
+			if !ok {
+				continue
+			}
+			if err := im.importProgramme(tx, ctx, ctx.channels[channelID], p, result); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// loadContext reads the tables programmes are matched against.
+func loadContext(tx *gorm.DB) (*importContext, error) {
+	ctx := &importContext{channels: map[uint]model.Channel{}}
+	channels := []model.Channel{}
+	if err := tx.Preload("Network").Preload("Network.Country").Find(&channels).Error; err != nil {
+		return nil, err
+	}
+	for _, channel := range channels {
+		ctx.channels[channel.ChannelID] = channel
+	}
+	if err := tx.Order("genre_id").Find(&ctx.genres).Error; err != nil {
+		return nil, err
+	}
+	if len(ctx.genres) == 0 {
+		return nil, fmt.Errorf("xmltv: the genres table is empty")
+	}
+	if err := tx.Order("category_id").Find(&ctx.categories).Error; err != nil {
+		return nil, err
+	}
+	if len(ctx.categories) == 0 {
+		return nil, fmt.Errorf("xmltv: the categories table is empty")
+	}
+	err := tx.Preload("RatingSystem").Preload("RatingSystem.Country").Order("rating_value_id").Find(&ctx.ratingValues).Error
+	return ctx, err
+}
+
+// mapChannels returns the channel ID of each XMLTV channel id used by the
+// programmes, listing those without one in result.
+func (im *Importer) mapChannels(tv *TV, ctx *importContext, result *Result) map[string]uint {
+	displayNames := map[string][]Text{}
+	for _, c := range tv.Channels {
+		displayNames[c.ID] = c.DisplayNames
+	}
+
+	mapping := map[string]uint{}
+	unmapped := map[string]bool{}
+	for _, p := range tv.Programmes {
+		if _, ok := mapping[p.Channel]; ok || unmapped[p.Channel] {
+			continue
+		}
+		if channelID, ok := im.Channels[p.Channel]; ok {
+			if _, exists := ctx.channels[channelID]; exists {
+				mapping[p.Channel] = channelID
+				continue
+			}
+		}
+		if channelID, ok := matchDisplayName(displayNames[p.Channel], ctx.channels); ok {
+			mapping[p.Channel] = channelID
+			continue
+		}
+		unmapped[p.Channel] = true
+		result.Unmapped = append(result.Unmapped, p.Channel)
+	}
+	sort.Strings(result.Unmapped)
+	return mapping
+}
+
+// matchDisplayName returns the channel whose description equals one of names.
+func matchDisplayName(names []Text, channels map[uint]model.Channel) (uint, bool) {
+	for _, name := range names {
+		for id, channel := range channels {
+			if strings.EqualFold(strings.TrimSpace(name.Value), strings.TrimSpace(channel.Description)) {
+				return id, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// timedProgramme is a programme with its parsed times.
+type timedProgramme struct {
+	Programme
+	start, stop time.Time
+	err         error
+}
+
+// sortProgrammes parses the programme times and orders them by channel and
+// start, taking a missing stop time from the next programme on the channel.
+func sortProgrammes(programmes []Programme) []timedProgramme {
+	timed := make([]timedProgramme, len(programmes))
+	for i, p := range programmes {
+		timed[i].Programme = p
+		timed[i].start, timed[i].err = ParseTime(p.Start)
+		if timed[i].err == nil && p.Stop != "" {
+			timed[i].stop, timed[i].err = ParseTime(p.Stop)
+		}
+	}
+	sort.SliceStable(timed, func(i, j int) bool {
+		if timed[i].Channel != timed[j].Channel {
+			return timed[i].Channel < timed[j].Channel
+		}
+		return timed[i].start.Before(timed[j].start)
+	})
+	for i := range timed {
+		if timed[i].err != nil || !timed[i].stop.IsZero() {
+			continue
+		}
+		if i+1 < len(timed) && timed[i+1].Channel == timed[i].Channel && timed[i+1].err == nil {
+			timed[i].stop = timed[i+1].start
+		}
+	}
+	return timed
+}
+
+// importProgramme stores one programme unless it duplicates or conflicts with existing events.
+func (im *Importer) importProgramme(tx *gorm.DB, ctx *importContext, channel model.Channel, p timedProgramme, result *Result) error {
+	title := First(p.Titles, "")
+	switch {
+	case p.err != nil:
+		result.Invalid = append(result.Invalid, fmt.Sprintf("%s %q: %v", p.Channel, title, p.err))
+		return nil
+	case p.stop.IsZero():
+		result.Invalid = append(result.Invalid, fmt.Sprintf("%s %q: no stop time", p.Channel, title))
+		return nil
+	case !p.stop.After(p.start):
+		result.Invalid = append(result.Invalid, fmt.Sprintf("%s %q: stop is not after start", p.Channel, title))
+		return nil
+	case title == "":
+		result.Invalid = append(result.Invalid, fmt.Sprintf("%s %s: no title", p.Channel, p.Start))
+		return nil
+	}
+	start, stop := p.start.UTC(), p.stop.UTC()
+
+	overlapping := []model.Event{}
+	err := tx.Scopes(model.Overlapping(start, stop)).Where("channel_id = ?", channel.ChannelID).
+		Order("start_time").Find(&overlapping).Error
+	if err != nil {
+		return err
+	}
+	for _, e := range overlapping {
+		if e.StartTime.Equal(start) && e.EndTime.Equal(stop) && e.Title == title {
+			result.Duplicates++
+			return nil
+		}
+	}
+	if len(overlapping) > 0 {
+		if !im.Replace {
+			result.Conflicts = append(result.Conflicts, Conflict{
+				ChannelID:  channel.ChannelID,
+				Title:      title,
+				Start:      start,
+				Stop:       stop,
+				EventID:    overlapping[0].EventID,
+				EventTitle: overlapping[0].Title,
+			})
+			return nil
+		}
+		if err := tx.Delete(&overlapping).Error; err != nil {
+			return err
+		}
+		result.Replaced += len(overlapping)
+	}
+
+	genres := ctx.matchGenres(p.Categories)
+	event := model.Event{
+		ChannelID:           channel.ChannelID,
+		StartTime:           start,
+		EndTime:             stop,
+		Title:               title,
+		ShortDescription:    optionalText(First(p.SubTitles, "")),
+		ExtendedDescription: optionalText(First(p.Descs, "")),
+		GenreID:             genres[0],
+		CategoryID:          ctx.matchCategory(p.Categories),
+	}
+	if err := tx.Omit(clause.Associations).Create(&event).Error; err != nil {
+		return err
+	}
+	result.Created++
+
+	for _, genreID := range genres[1:] {
+		err := tx.Omit(clause.Associations).Create(&model.EventGenre{EventID: event.EventID, GenreID: genreID}).Error
+		if err != nil {
+			return err
+		}
+	}
+	seen := map[uint]bool{}
+	for _, r := range p.Ratings {
+		ratingValueID, ok := ctx.matchRating(r, channel.Network.Country.CountryCode)
+		if !ok || seen[ratingValueID] {
+			continue
+		}
+		seen[ratingValueID] = true
+		err := tx.Omit(clause.Associations).Create(&model.EventRating{EventID: event.EventID, RatingValueID: ratingValueID}).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matchGenres returns the genres of the categories, the first being the
+// primary genre. Categories match a genre named like them or like one of its
+// "/" separated parts. Without any match the undefined content genre is used.
+func (ctx *importContext) matchGenres(categories []Text) []uint {
+	var ids []uint
+	seen := map[uint]bool{}
+	for _, c := range categories {
+		name := normalise(c.Value)
+		for _, g := range ctx.genres {
+			if g.NibbleLevel1 == 0 || !genreMatches(g.Description, name) {
+				continue
+			}
+			if !seen[g.GenreID] {
+				seen[g.GenreID] = true
+				ids = append(ids, g.GenreID)
+			}
+			break
+		}
+	}
+	if len(ids) == 0 {
+		for _, g := range ctx.genres {
+			if g.NibbleLevel1 == 0 && g.NibbleLevel2 == 0 {
+				return []uint{g.GenreID}
+			}
+		}
+		return []uint{ctx.genres[0].GenreID}
+	}
+	return ids
+}
+
+// genreMatches reports whether a category names a genre description such as
+// "Movie/Drama (general)".
+func genreMatches(description, category string) bool {
+	description, _, _ = strings.Cut(description, "(")
+	if normalise(description) == category {
+		return true
+	}
+	for _, part := range strings.Split(description, "/") {
+		if normalise(part) == category {
+			return true
+		}
+	}
+	return false
+}
+
+// matchCategory returns the category named like one of the XMLTV categories,
+// or else the first category.
+func (ctx *importContext) matchCategory(categories []Text) uint {
+	for _, c := range categories {
+		for _, category := range ctx.categories {
+			if normalise(category.Description) == normalise(c.Value) {
+				return category.CategoryID
+			}
+		}
+	}
+	return ctx.categories[0].CategoryID
+}
+
+// matchRating returns the rating value of an XMLTV rating. The value must
+// match, spaces and case aside, in a rating system of the channel's country
+// or one whose initials contain the XMLTV system, e.g. "ACB" for the
+// Australian Classification Review Board.
+func (ctx *importContext) matchRating(r Rating, countryCode string) (uint, bool) {
+	value := compact(r.Value)
+	if value == "" {
+		return 0, false
+	}
+	best, bestScore := uint(0), 0
+	for _, rv := range ctx.ratingValues {
+		if compact(rv.Value) != value {
+			continue
+		}
+		score := 0
+		if r.System != "" && isSubsequence(compact(r.System), initials(rv.RatingSystem.Description)) {
+			score += 2
+		}
+		if strings.EqualFold(rv.RatingSystem.Country.CountryCode, countryCode) {
+			score++
+		}
+		if score > bestScore {
+			best, bestScore = rv.RatingValueID, score
+		}
+	}
+	return best, bestScore > 0
+}
+
+// initials returns the upper case initials of the capitalised words in s.
+func initials(s string) string {
+	var b strings.Builder
+	for _, word := range strings.Fields(s) {
+		if r := []rune(word)[0]; unicode.IsUpper(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// isSubsequence reports whether the letters of sub appear in s in order.
+func isSubsequence(sub, s string) bool {
+	for _, r := range s {
+		if len(sub) > 0 && rune(sub[0]) == r {
+			sub = sub[1:]
+		}
+	}
+	return len(sub) == 0
+}
+
+// normalise lower cases s and collapses its spaces.
+func normalise(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
+
+// compact upper cases s and drops its spaces.
+func compact(s string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(s), ""))
+}
+
+// optionalText returns nil for empty text.
+func optionalText(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
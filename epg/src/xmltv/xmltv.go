@@ -0,0 +1,147 @@
+// XMLTV document parsing, see https://github.com/XMLTV/xmltv/blob/master/xmltv.dtd
+package xmltv
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// TV is the root element of an XMLTV document.
+type TV struct {
+	XMLName           xml.Name    `xml:"tv"`
+	GeneratorInfoName string      `xml:"generator-info-name,attr,omitempty"`
+	Channels          []Channel   `xml:"channel"`
+	Programmes        []Programme `xml:"programme"`
+}
+
+// Channel is an XMLTV <channel>.
+type Channel struct {
+	ID           string `xml:"id,attr"`
+	DisplayNames []Text `xml:"display-name"`
+	Icons        []Icon `xml:"icon"`
+}
+
+// Programme is an XMLTV <programme>.
+type Programme struct {
+	Start       string       `xml:"start,attr"`
+	Stop        string       `xml:"stop,attr,omitempty"`
+	Channel     string       `xml:"channel,attr"`
+	Titles      []Text       `xml:"title"`
+	SubTitles   []Text       `xml:"sub-title"`
+	Descs       []Text       `xml:"desc"`
+	Categories  []Text       `xml:"category"`
+	EpisodeNums []EpisodeNum `xml:"episode-num"`
+	Ratings     []Rating     `xml:"rating"`
+}
+
+// Text is an element holding text in a language.
+type Text struct {
+	Lang  string `xml:"lang,attr,omitempty"`
+	Value string `xml:",chardata"`
+}
+
+// Icon is an XMLTV <icon> image.
+type Icon struct {
+	Src string `xml:"src,attr"`
+}
+
+// EpisodeNum is an XMLTV <episode-num>.
+type EpisodeNum struct {
+	System string `xml:"system,attr,omitempty"`
+	Value  string `xml:",chardata"`
+}
+
+// Rating is an XMLTV <rating>, e.g. <rating system="ACB"><value>M</value></rating>.
+type Rating struct {
+	System string `xml:"system,attr,omitempty"`
+	Value  string `xml:"value"`
+	Icons  []Icon `xml:"icon"`
+}
+
+// Parse decodes an XMLTV document.
+func Parse(r io.Reader) (*TV, error) {
+	tv := &TV{}
+	decoder := xml.NewDecoder(r)
+	decoder.CharsetReader = charsetReader
+	if err := decoder.Decode(tv); err != nil {
+		return nil, fmt.Errorf("xmltv: %w", err)
+	}
+	return tv, nil
+}
+
+// Write encodes tv as an indented UTF-8 XMLTV document.
+func Write(w io.Writer, tv *TV) error {
+	if _, err := io.WriteString(w, xml.Header+"<!DOCTYPE tv SYSTEM \"xmltv.dtd\">\n"); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(tv); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// charsets are the single byte encodings XMLTV files come in besides UTF-8.
+var charsets = map[string]*charmap.Charmap{
+	"iso-8859-1":   charmap.ISO8859_1,
+	"latin1":       charmap.ISO8859_1,
+	"iso-8859-15":  charmap.ISO8859_15,
+	"windows-1252": charmap.Windows1252,
+}
+
+// charsetReader converts a document in charset to UTF-8 for the XML decoder.
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	if strings.EqualFold(charset, "utf-8") {
+		return input, nil
+	}
+	table, ok := charsets[strings.ToLower(charset)]
+	if !ok {
+		return nil, fmt.Errorf("unsupported charset %q", charset)
+	}
+	return table.NewDecoder().Reader(input), nil
+}
+
+// ParseTime parses an XMLTV date, "YYYYMMDDhhmmss" or a prefix of it
+// followed by an optional "+hhmm" offset. Dates without an offset are UTC.
+func ParseTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	stamp, offset, _ := strings.Cut(s, " ")
+	layouts := map[int]string{14: "20060102150405", 12: "200601021504", 10: "2006010215", 8: "20060102"}
+	layout, ok := layouts[len(stamp)]
+	if !ok {
+		return time.Time{}, fmt.Errorf("xmltv: bad date %q", s)
+	}
+	if offset == "" {
+		return time.Parse(layout, stamp)
+	}
+	t, err := time.Parse(layout+" -0700", stamp+" "+strings.TrimSpace(offset))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("xmltv: bad date %q", s)
+	}
+	return t, nil
+}
+
+// FormatTime formats t as an XMLTV date with its offset from UTC.
+func FormatTime(t time.Time) string {
+	return t.Format("20060102150405 -0700")
+}
+
+// First returns the text in lang, or else the first text, or empty.
+func First(texts []Text, lang string) string {
+	for _, t := range texts {
+		if lang != "" && strings.EqualFold(t.Lang, lang) {
+			return strings.TrimSpace(t.Value)
+		}
+	}
+	if len(texts) > 0 {
+		return strings.TrimSpace(texts[0].Value)
+	}
+	return ""
+}